@@ -1,54 +1,409 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
-var (
-	// Токен пользовательского бота, можно переопределить через переменную окружения BOT_TOKEN.
-	BotToken string = "7785304405:AAFWCYVT_s8axaV8P4exA54TrkvB_Q5rRco"
-	// Список ID администраторов (определяется через переменную окружения ADMIN_IDS).
-	AdminIDs []int64
-	// Идентификатор чата для рассылки событий.
-	// Здесь он установлен равным 7785304405.
-	BroadcastChatID int64 = 7785304405
+// DefaultConfigPath – файл конфигурации, который читается, если путь не задан явно.
+const DefaultConfigPath = "config.yaml"
+
+// Config описывает конфигурацию обоих ботов, базы данных и администрирования.
+type Config struct {
+	PrimaryBot   BotConfig          `yaml:"primary_bot"`
+	AdminBot     BotConfig          `yaml:"admin_bot"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Admin        AdminConfig        `yaml:"admin"`
+	Transport    TransportConfig    `yaml:"transport"`
+	Dispatcher   DispatcherConfig   `yaml:"dispatcher"`
+	Registration RegistrationConfig `yaml:"registration"`
+	Scheduler    SchedulerConfig    `yaml:"scheduler"`
+	Events       EventsConfig       `yaml:"events"`
+	// Часовой пояс игровых суток и расписаний, например "Europe/Moscow".
+	// Пустое значение – часовой пояс сервера.
+	Timezone string `yaml:"timezone"`
+}
+
+// BotConfig – параметры одного Telegram-бота.
+type BotConfig struct {
+	Token string `yaml:"token"`
+	Debug bool   `yaml:"debug"`
+}
+
+// DatabaseConfig – параметры подключения к SQLite.
+// Если DSN не задан, он строится из Path.
+type DatabaseConfig struct {
+	Path string `yaml:"path"`
+	DSN  string `yaml:"dsn"`
+}
+
+// AdminConfig – параметры доступа к админскому боту.
+type AdminConfig struct {
+	// Пароль для команды /auth админского бота.
+	Password string `yaml:"password"`
+	// Telegram ID администраторов.
+	IDs []int64 `yaml:"ids"`
+	// Чаты, в которые админский бот отправляет уведомления (новые анкеты и т.д.).
+	ChatIDs []int64 `yaml:"chat_ids"`
+}
+
+// Режимы получения обновлений.
+const (
+	TransportPolling = "polling"
+	TransportWebhook = "webhook"
 )
 
-// LoadConfig загружает конфигурацию из переменных окружения.
-// Если переменные окружения заданы, они могут переопределить значения по умолчанию.
-func LoadConfig() string {
-	envToken := os.Getenv("BOT_TOKEN")
-	if envToken != "" {
-		BotToken = envToken
+// TransportConfig – способ получения обновлений от Telegram.
+type TransportConfig struct {
+	// Mode – "polling" (по умолчанию) или "webhook".
+	Mode    string        `yaml:"mode"`
+	Webhook WebhookConfig `yaml:"webhook"`
+}
+
+// WebhookConfig – параметры встроенного HTTP-сервера для режима webhook.
+type WebhookConfig struct {
+	// Адрес, на котором слушает HTTP-сервер, например ":8443".
+	Listen string `yaml:"listen"`
+	// Внешний адрес за reverse proxy, например "https://bot.example.com".
+	PublicURL string `yaml:"public_url"`
+	// Секрет, который Telegram передаёт в заголовке X-Telegram-Bot-Api-Secret-Token.
+	SecretToken string `yaml:"secret_token"`
+	// Пути для каждого бота относительно PublicURL.
+	PrimaryPath string `yaml:"primary_path"`
+	AdminPath   string `yaml:"admin_path"`
+}
+
+// DispatcherConfig – параметры пула обработчиков обновлений.
+type DispatcherConfig struct {
+	// Количество воркеров; обновления одного чата всегда обрабатывает один воркер.
+	Workers int `yaml:"workers"`
+	// Размер очереди каждого воркера.
+	QueueSize int `yaml:"queue_size"`
+	// Сколько ждать завершения обработчиков при остановке.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// RegistrationConfig – параметры диалога регистрации анкеты.
+type RegistrationConfig struct {
+	// Через сколько после последнего ответа незавершённый черновик удаляется.
+	TTL time.Duration `yaml:"ttl"`
+	// За сколько до удаления отправлять напоминание.
+	RemindBefore time.Duration `yaml:"remind_before"`
+}
+
+// SchedulerConfig – параметры планировщика отложенных задач.
+type SchedulerConfig struct {
+	// Как часто проверять наступившие задачи.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// EventsConfig – параметры событий.
+type EventsConfig struct {
+	// За сколько до начала запланированного события рассылать напоминание,
+	// если при создании события не указано другое.
+	RemindBefore time.Duration `yaml:"remind_before"`
+}
+
+// Current – конфигурация, загруженная при старте через LoadConfig.
+var Current = &Config{}
+
+// LoadConfig читает файл конфигурации (YAML), применяет переопределения
+// из переменных окружения и проверяет результат.
+// Если path пустой, используется CONFIG_PATH или DefaultConfigPath;
+// отсутствие файла по умолчанию не считается ошибкой.
+func LoadConfig(path string) (*Config, error) {
+	explicit := path != ""
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+		explicit = path != ""
+	}
+	if path == "" {
+		path = DefaultConfigPath
+	}
+
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("разбор файла конфигурации %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		// Файл по умолчанию необязателен: всё можно задать через окружение.
+	default:
+		return nil, fmt.Errorf("чтение файла конфигурации %s: %w", path, err)
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Database.DSN == "" {
+		if cfg.Database.Path == "" {
+			cfg.Database.Path = "bot.db"
+		}
+		// Транзакции сразу берут блокировку на запись, конкурирующие запросы
+		// ждут её до 5 секунд, а не падают с SQLITE_BUSY.
+		cfg.Database.DSN = "file:" + cfg.Database.Path + "?mode=rwc&_txlock=immediate&_pragma=busy_timeout(5000)"
+	}
+	if cfg.Transport.Mode == "" {
+		cfg.Transport.Mode = TransportPolling
+	}
+	if cfg.Transport.Webhook.PrimaryPath == "" {
+		cfg.Transport.Webhook.PrimaryPath = "/webhook/primary"
+	}
+	if cfg.Transport.Webhook.AdminPath == "" {
+		cfg.Transport.Webhook.AdminPath = "/webhook/admin"
+	}
+	if cfg.Dispatcher.Workers == 0 {
+		cfg.Dispatcher.Workers = 8
+	}
+	if cfg.Dispatcher.QueueSize == 0 {
+		cfg.Dispatcher.QueueSize = 100
+	}
+	if cfg.Dispatcher.ShutdownTimeout == 0 {
+		cfg.Dispatcher.ShutdownTimeout = 30 * time.Second
+	}
+	if cfg.Registration.TTL == 0 {
+		cfg.Registration.TTL = 24 * time.Hour
+	}
+	if cfg.Registration.RemindBefore == 0 {
+		cfg.Registration.RemindBefore = 2 * time.Hour
+	}
+	if cfg.Scheduler.Interval == 0 {
+		cfg.Scheduler.Interval = 10 * time.Second
+	}
+	if cfg.Events.RemindBefore == 0 {
+		cfg.Events.RemindBefore = 30 * time.Minute
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	Current = cfg
+	return cfg, nil
+}
+
+// applyEnv переопределяет значения конфигурации переменными окружения.
+func applyEnv(cfg *Config) error {
+	var errs []error
+
+	setString := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = strings.TrimSpace(v)
+		}
+	}
+	setBool := func(name string, dst *bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: ожидается true/false, получено %q", name, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	setInt := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: ожидается число, получено %q", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	setDuration := func(name string, dst *time.Duration) {
+		if v, ok := os.LookupEnv(name); ok {
+			d, err := time.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: ожидается длительность (например, 30s), получено %q", name, v))
+				return
+			}
+			*dst = d
+		}
+	}
+	setIDs := func(name string, dst *[]int64) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		ids, err := parseIDList(v) // Пример: "12345678,98765432"
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		*dst = ids
 	}
 
-	// Загружаем список администраторских ID.
-	adminIDsEnv := os.Getenv("ADMIN_IDS") // Пример: "12345678,98765432"
-	for _, idStr := range strings.Split(adminIDsEnv, ",") {
-		id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
-		if err == nil {
-			AdminIDs = append(AdminIDs, id)
+	setString("BOT_TOKEN", &cfg.PrimaryBot.Token)
+	setBool("BOT_DEBUG", &cfg.PrimaryBot.Debug)
+	setString("ADMIN_BOT_TOKEN", &cfg.AdminBot.Token)
+	setBool("ADMIN_BOT_DEBUG", &cfg.AdminBot.Debug)
+	setString("DB_PATH", &cfg.Database.Path)
+	setString("DB_DSN", &cfg.Database.DSN)
+	setString("ADMIN_PASSWORD", &cfg.Admin.Password)
+	setIDs("ADMIN_IDS", &cfg.Admin.IDs)
+	setIDs("ADMIN_CHAT_IDS", &cfg.Admin.ChatIDs)
+	setString("TRANSPORT_MODE", &cfg.Transport.Mode)
+	setString("WEBHOOK_LISTEN", &cfg.Transport.Webhook.Listen)
+	setString("WEBHOOK_PUBLIC_URL", &cfg.Transport.Webhook.PublicURL)
+	setString("WEBHOOK_SECRET_TOKEN", &cfg.Transport.Webhook.SecretToken)
+	setInt("DISPATCHER_WORKERS", &cfg.Dispatcher.Workers)
+	setInt("DISPATCHER_QUEUE_SIZE", &cfg.Dispatcher.QueueSize)
+	setDuration("SHUTDOWN_TIMEOUT", &cfg.Dispatcher.ShutdownTimeout)
+	setDuration("REGISTRATION_TTL", &cfg.Registration.TTL)
+	setDuration("REGISTRATION_REMIND_BEFORE", &cfg.Registration.RemindBefore)
+	setDuration("SCHEDULER_INTERVAL", &cfg.Scheduler.Interval)
+	setString("TIMEZONE", &cfg.Timezone)
+	setDuration("EVENT_REMIND_BEFORE", &cfg.Events.RemindBefore)
+
+	return errors.Join(errs...)
+}
+
+// parseIDList разбирает список ID, разделённых запятыми.
+func parseIDList(s string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("неверный ID %q", part)
 		}
+		ids = append(ids, id)
 	}
+	return ids, nil
+}
 
-	// Загружаем идентификатор чата для рассылки событий.
-	broadcastChatIDStr := os.Getenv("BROADCAST_CHAT_ID")
-	if broadcastChatIDStr != "" {
-		id, err := strconv.ParseInt(broadcastChatIDStr, 10, 64)
-		if err == nil {
-			BroadcastChatID = id
+// Validate проверяет, что заданы все обязательные параметры.
+// Возвращает все найденные ошибки сразу.
+func (c *Config) Validate() error {
+	var errs []error
+	if err := validateToken(c.PrimaryBot.Token); err != nil {
+		errs = append(errs, fmt.Errorf("primary_bot.token (BOT_TOKEN): %w", err))
+	}
+	if err := validateToken(c.AdminBot.Token); err != nil {
+		errs = append(errs, fmt.Errorf("admin_bot.token (ADMIN_BOT_TOKEN): %w", err))
+	}
+	if c.PrimaryBot.Token != "" && c.PrimaryBot.Token == c.AdminBot.Token {
+		errs = append(errs, errors.New("primary_bot.token и admin_bot.token не должны совпадать"))
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn (DB_DSN) или database.path (DB_PATH) не задан"))
+	}
+	if c.Admin.Password == "" {
+		errs = append(errs, errors.New("admin.password (ADMIN_PASSWORD) не задан"))
+	}
+	if len(c.Admin.ChatIDs) == 0 {
+		errs = append(errs, errors.New("admin.chat_ids (ADMIN_CHAT_IDS) не задан: некуда отправлять уведомления"))
+	}
+	if c.Dispatcher.Workers < 1 || c.Dispatcher.QueueSize < 1 {
+		errs = append(errs, errors.New("dispatcher.workers и dispatcher.queue_size должны быть положительными"))
+	}
+	if c.Dispatcher.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("dispatcher.shutdown_timeout не может быть отрицательным"))
+	}
+	if c.Registration.TTL <= 0 || c.Registration.RemindBefore <= 0 || c.Registration.RemindBefore >= c.Registration.TTL {
+		errs = append(errs, errors.New("registration: ttl и remind_before должны быть положительными, remind_before меньше ttl"))
+	}
+	if c.Scheduler.Interval <= 0 {
+		errs = append(errs, errors.New("scheduler.interval (SCHEDULER_INTERVAL) должен быть положительным"))
+	}
+	if c.Events.RemindBefore < 0 {
+		errs = append(errs, errors.New("events.remind_before (EVENT_REMIND_BEFORE) не может быть отрицательным"))
+	}
+	if _, err := time.LoadLocation(c.Timezone); c.Timezone != "" && err != nil {
+		errs = append(errs, fmt.Errorf("timezone (TIMEZONE): неизвестный часовой пояс %q", c.Timezone))
+	}
+	switch c.Transport.Mode {
+	case TransportPolling:
+	case TransportWebhook:
+		errs = append(errs, c.Transport.Webhook.validate()...)
+	default:
+		errs = append(errs, fmt.Errorf("transport.mode (TRANSPORT_MODE): ожидается %q или %q, получено %q",
+			TransportPolling, TransportWebhook, c.Transport.Mode))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("неверная конфигурация:\n%w", err)
+	}
+	return nil
+}
+
+// validate проверяет параметры режима webhook.
+func (w WebhookConfig) validate() []error {
+	var errs []error
+	if w.Listen == "" {
+		errs = append(errs, errors.New("transport.webhook.listen (WEBHOOK_LISTEN) не задан"))
+	}
+	if u, err := url.Parse(w.PublicURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		errs = append(errs, errors.New("transport.webhook.public_url (WEBHOOK_PUBLIC_URL): ожидается https://<host>"))
+	}
+	if !validSecretToken(w.SecretToken) {
+		errs = append(errs, errors.New("transport.webhook.secret_token (WEBHOOK_SECRET_TOKEN): 1-256 символов A-Z, a-z, 0-9, _ и -"))
+	}
+	if !strings.HasPrefix(w.PrimaryPath, "/") || !strings.HasPrefix(w.AdminPath, "/") {
+		errs = append(errs, errors.New("transport.webhook.primary_path и admin_path должны начинаться с /"))
+	}
+	if w.PrimaryPath == w.AdminPath {
+		errs = append(errs, errors.New("transport.webhook.primary_path и admin_path не должны совпадать"))
+	}
+	return errs
+}
+
+// validSecretToken проверяет секрет по правилам Telegram для setWebhook.
+func validSecretToken(s string) bool {
+	if len(s) == 0 || len(s) > 256 {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
 		}
-		// Если произошла ошибка, сохраняем значение по умолчанию (7785304405).
 	}
+	return true
+}
 
-	return BotToken
+// validateToken проверяет формат токена бота "<id>:<secret>".
+func validateToken(token string) error {
+	if token == "" {
+		return errors.New("не задан")
+	}
+	id, secret, ok := strings.Cut(token, ":")
+	if !ok || secret == "" {
+		return errors.New("ожидается формат <id>:<secret>")
+	}
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return errors.New("ожидается формат <id>:<secret>")
+	}
+	return nil
+}
+
+// Location возвращает часовой пояс из параметра timezone или часовой пояс сервера.
+func (c *Config) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
 }
 
 // IsAdmin возвращает true, если userID входит в список администраторов.
 func IsAdmin(userID int64) bool {
-	for _, id := range AdminIDs {
+	for _, id := range Current.Admin.IDs {
 		if id == userID {
 			return true
 		}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"telegram-bot/models"
+)
+
+const auctionColumns = `a.id, a.seller_id, a.item_id, COALESCE(i.name, ''), a.quantity, a.currency,
+a.start_price, a.current_bid, a.leader_id, a.status, a.ends_at, a.created_at, a.closed_at`
+
+// CreateAuction сохраняет новый аукцион и заполняет a.ID и a.CreatedAt.
+func (s *SQLStore) CreateAuction(ctx context.Context, a *models.Auction) error {
+	a.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO auctions (seller_id, item_id, quantity, currency, start_price, status, ends_at, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.SellerID, a.ItemID, a.Quantity, a.Currency, a.StartPrice, a.Status,
+		a.EndsAt.UTC().Format(time.RFC3339), a.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	a.ID = int(id)
+	return nil
+}
+
+// GetAuction возвращает аукцион по ID или sql.ErrNoRows.
+func (s *SQLStore) GetAuction(ctx context.Context, id int) (*models.Auction, error) {
+	query := "SELECT " + auctionColumns + " FROM auctions a LEFT JOIN items i ON i.id = a.item_id WHERE a.id = ?"
+	return scanAuction(s.q.QueryRowContext(ctx, query, id))
+}
+
+// UpdateAuction сохраняет ставку, лидера, статус и время окончания аукциона.
+func (s *SQLStore) UpdateAuction(ctx context.Context, a *models.Auction) error {
+	var closedAt any
+	if !a.ClosedAt.IsZero() {
+		closedAt = a.ClosedAt.UTC().Format(time.RFC3339)
+	}
+	_, err := s.q.ExecContext(ctx, `
+UPDATE auctions SET current_bid = ?, leader_id = ?, status = ?, ends_at = ?, closed_at = ?
+WHERE id = ?`,
+		a.CurrentBid, a.LeaderID, a.Status, a.EndsAt.UTC().Format(time.RFC3339), closedAt, a.ID)
+	return err
+}
+
+// ListAuctions возвращает до limit аукционов: открытые – по времени окончания,
+// иначе все – новые первыми.
+func (s *SQLStore) ListAuctions(ctx context.Context, openOnly bool, limit int) ([]*models.Auction, error) {
+	query := "SELECT " + auctionColumns + " FROM auctions a LEFT JOIN items i ON i.id = a.item_id"
+	var args []any
+	if openOnly {
+		query += " WHERE a.status = ? ORDER BY a.ends_at, a.id"
+		args = append(args, models.AuctionOpen)
+	} else {
+		query += " ORDER BY a.id DESC"
+	}
+	query += " LIMIT ?"
+	rows, err := s.q.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var auctions []*models.Auction
+	for rows.Next() {
+		a, err := scanAuction(rows)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, a)
+	}
+	return auctions, rows.Err()
+}
+
+// AddBid сохраняет ставку в истории аукциона и заполняет b.ID и b.CreatedAt.
+func (s *SQLStore) AddBid(ctx context.Context, b *models.Bid) error {
+	b.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, "INSERT INTO auction_bids (auction_id, profile_id, amount, created_at) VALUES (?, ?, ?, ?)",
+		b.AuctionID, b.ProfileID, b.Amount, b.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	b.ID = int(id)
+	return nil
+}
+
+// ListBids возвращает историю ставок аукциона по порядку.
+func (s *SQLStore) ListBids(ctx context.Context, auctionID int) ([]*models.Bid, error) {
+	rows, err := s.q.QueryContext(ctx, `
+SELECT id, auction_id, profile_id, amount, created_at FROM auction_bids
+WHERE auction_id = ? ORDER BY id`, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var bids []*models.Bid
+	for rows.Next() {
+		var b models.Bid
+		var createdAtStr string
+		if err := rows.Scan(&b.ID, &b.AuctionID, &b.ProfileID, &b.Amount, &createdAtStr); err != nil {
+			return nil, err
+		}
+		if b.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+			return nil, err
+		}
+		bids = append(bids, &b)
+	}
+	return bids, rows.Err()
+}
+
+// scanAuction читает аукцион из строки результата.
+func scanAuction(row scanner) (*models.Auction, error) {
+	var a models.Auction
+	var endsAtStr, createdAtStr string
+	var closedAtStr sql.NullString
+	err := row.Scan(&a.ID, &a.SellerID, &a.ItemID, &a.ItemName, &a.Quantity, &a.Currency,
+		&a.StartPrice, &a.CurrentBid, &a.LeaderID, &a.Status, &endsAtStr, &createdAtStr, &closedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	if a.EndsAt, err = time.Parse(time.RFC3339, endsAtStr); err != nil {
+		return nil, err
+	}
+	if a.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	if closedAtStr.Valid {
+		if a.ClosedAt, err = time.Parse(time.RFC3339, closedAtStr.String); err != nil {
+			return nil, err
+		}
+	}
+	return &a, nil
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// GetCheckinAttempts возвращает неверные коды отметки пользователя на событии;
+// если ошибок не было – пустую запись.
+func (s *SQLStore) GetCheckinAttempts(ctx context.Context, eventID int, telegramID int64) (*models.CheckinAttempts, error) {
+	a := models.CheckinAttempts{EventID: eventID, TelegramID: telegramID}
+	var lockedUntilStr sql.NullString
+	err := s.q.QueryRowContext(ctx, "SELECT failures, locked_until FROM event_checkin_attempts WHERE event_id = ? AND telegram_id = ?",
+		eventID, telegramID).Scan(&a.Failures, &lockedUntilStr)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if lockedUntilStr.Valid {
+		if a.LockedUntil, err = time.Parse(time.RFC3339, lockedUntilStr.String); err != nil {
+			return nil, err
+		}
+	}
+	return &a, nil
+}
+
+// AddCheckinFailure атомарно засчитывает неверный код отметки и возвращает
+// число неверных кодов с последней блокировки.
+func (s *SQLStore) AddCheckinFailure(ctx context.Context, eventID int, telegramID int64) (int, error) {
+	var failures int
+	err := s.q.QueryRowContext(ctx, `
+INSERT INTO event_checkin_attempts (event_id, telegram_id, failures) VALUES (?, ?, 1)
+ON CONFLICT (event_id, telegram_id) DO UPDATE SET failures = failures + 1
+RETURNING failures`, eventID, telegramID).Scan(&failures)
+	return failures, err
+}
+
+// LockCheckinAttempts блокирует отметку пользователя на событии до until
+// и обнуляет счётчик неверных кодов.
+func (s *SQLStore) LockCheckinAttempts(ctx context.Context, eventID int, telegramID int64, until time.Time) error {
+	_, err := s.q.ExecContext(ctx, "UPDATE event_checkin_attempts SET failures = 0, locked_until = ? WHERE event_id = ? AND telegram_id = ?",
+		nullTime(until), eventID, telegramID)
+	return err
+}
+
+// ResetCheckinAttempts забывает неверные коды после успешной отметки.
+func (s *SQLStore) ResetCheckinAttempts(ctx context.Context, eventID int, telegramID int64) error {
+	_, err := s.q.ExecContext(ctx, "DELETE FROM event_checkin_attempts WHERE event_id = ? AND telegram_id = ?", eventID, telegramID)
+	return err
+}
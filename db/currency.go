@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// ErrUnknownCurrency возвращается, если валюты нет в справочнике.
+var ErrUnknownCurrency = errors.New("неизвестная валюта")
+
+// ErrCurrencyConflict возвращается, если код, название или алиас валюты
+// уже заняты другой валютой.
+var ErrCurrencyConflict = errors.New("название валюты уже занято")
+
+// normalizeCurrencyName приводит название валюты к виду для сравнения.
+func normalizeCurrencyName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// currencyNames возвращает все названия, по которым находится валюта.
+func currencyNames(c *models.Currency) []string {
+	names := []string{normalizeCurrencyName(c.Code), normalizeCurrencyName(c.Name)}
+	for _, a := range c.Aliases {
+		names = append(names, normalizeCurrencyName(a))
+	}
+	return names
+}
+
+// ListCurrencies возвращает валюты в порядке добавления.
+// Выведенные из оборота валюты включаются, только если includeRetired.
+func (s *SQLStore) ListCurrencies(ctx context.Context, includeRetired bool) ([]*models.Currency, error) {
+	query := `
+SELECT code, name, aliases, icon, retired, created_at
+FROM currencies WHERE retired = 0 OR ?
+ORDER BY rowid`
+	rows, err := s.q.QueryContext(ctx, query, boolToInt(includeRetired))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var currencies []*models.Currency
+	for rows.Next() {
+		c, err := scanCurrency(rows)
+		if err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, c)
+	}
+	return currencies, rows.Err()
+}
+
+// GetCurrency возвращает валюту по коду или ErrUnknownCurrency.
+func (s *SQLStore) GetCurrency(ctx context.Context, code string) (*models.Currency, error) {
+	query := `
+SELECT code, name, aliases, icon, retired, created_at
+FROM currencies WHERE code = ?`
+	c, err := scanCurrency(s.q.QueryRowContext(ctx, query, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, err
+}
+
+// ResolveCurrency находит валюту по коду, названию или алиасу без учёта регистра.
+// Выведенные из оборота валюты тоже находятся – вызывающий решает, допустимы ли они.
+// Если валюта не найдена, возвращает ErrUnknownCurrency.
+func (s *SQLStore) ResolveCurrency(ctx context.Context, name string) (*models.Currency, error) {
+	name = normalizeCurrencyName(name)
+	if name == "" {
+		return nil, ErrUnknownCurrency
+	}
+	currencies, err := s.ListCurrencies(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range currencies {
+		for _, n := range currencyNames(c) {
+			if n == name {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, name)
+}
+
+// CreateCurrency добавляет валюту в справочник.
+// Возвращает ErrCurrencyConflict, если код, название или алиас уже заняты.
+func (s *SQLStore) CreateCurrency(ctx context.Context, c *models.Currency) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		if err := t.checkCurrencyNames(ctx, c, ""); err != nil {
+			return err
+		}
+		c.CreatedAt = time.Now()
+		_, err := t.q.ExecContext(ctx, `
+INSERT INTO currencies (code, name, aliases, icon, retired, created_at)
+VALUES (?, ?, ?, ?, ?, ?)`,
+			c.Code, c.Name, strings.Join(c.Aliases, ","), c.Icon, boolToInt(c.Retired),
+			c.CreatedAt.UTC().Format(time.RFC3339))
+		return err
+	})
+}
+
+// UpdateCurrency сохраняет название, алиасы, иконку и признак вывода из оборота.
+// Код валюты не меняется.
+func (s *SQLStore) UpdateCurrency(ctx context.Context, c *models.Currency) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		if err := t.checkCurrencyNames(ctx, c, c.Code); err != nil {
+			return err
+		}
+		res, err := t.q.ExecContext(ctx, `
+UPDATE currencies SET name = ?, aliases = ?, icon = ?, retired = ?
+WHERE code = ?`,
+			c.Name, strings.Join(c.Aliases, ","), c.Icon, boolToInt(c.Retired), c.Code)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: %q", ErrUnknownCurrency, c.Code)
+		}
+		return nil
+	})
+}
+
+// checkCurrencyNames проверяет, что названия валюты c не пересекаются
+// с названиями других валют (кроме валюты с кодом except).
+func (s *SQLStore) checkCurrencyNames(ctx context.Context, c *models.Currency, except string) error {
+	currencies, err := s.ListCurrencies(ctx, true)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]string)
+	for _, other := range currencies {
+		if other.Code == except {
+			continue
+		}
+		for _, n := range currencyNames(other) {
+			taken[n] = other.Code
+		}
+	}
+	for _, n := range currencyNames(c) {
+		if code, ok := taken[n]; ok {
+			return fmt.Errorf("%w: %q используется валютой %s", ErrCurrencyConflict, n, code)
+		}
+	}
+	return nil
+}
+
+// scanCurrency читает валюту из строки результата.
+func scanCurrency(row scanner) (*models.Currency, error) {
+	var c models.Currency
+	var aliases, createdAtStr string
+	var retiredInt int
+	if err := row.Scan(&c.Code, &c.Name, &aliases, &c.Icon, &retiredInt, &createdAtStr); err != nil {
+		return nil, err
+	}
+	for _, a := range strings.Split(aliases, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			c.Aliases = append(c.Aliases, a)
+		}
+	}
+	c.Retired = retiredInt == 1
+	var err error
+	c.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// loadBalances заполняет Balances у профилей: все действующие валюты
+// и выведенные из оборота, если по ним остался ненулевой баланс.
+func (s *SQLStore) loadBalances(ctx context.Context, profiles ...*models.Profile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Profile, len(profiles))
+	for _, p := range profiles {
+		p.Balances = nil
+		byID[p.ID] = p
+	}
+	// Для одного профиля фильтруем в запросе, для списка – читаем все балансы.
+	profileID := 0
+	if len(profiles) == 1 {
+		profileID = profiles[0].ID
+	}
+	query := `
+SELECT p.id, c.code, c.name, c.icon, COALESCE(b.amount, 0)
+FROM profiles p
+CROSS JOIN currencies c
+LEFT JOIN balances b ON b.profile_id = p.id AND b.currency = c.code
+WHERE (? = 0 OR p.id = ?) AND (c.retired = 0 OR COALESCE(b.amount, 0) != 0)
+ORDER BY p.id, c.rowid`
+	rows, err := s.q.QueryContext(ctx, query, profileID, profileID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var b models.Balance
+		if err := rows.Scan(&id, &b.Currency, &b.Name, &b.Icon, &b.Amount); err != nil {
+			return err
+		}
+		if p, ok := byID[id]; ok {
+			p.Balances = append(p.Balances, b)
+		}
+	}
+	return rows.Err()
+}
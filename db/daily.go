@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"telegram-bot/models"
+)
+
+// ListDailyRewards возвращает таблицу ежедневных наград по возрастанию дня серии.
+func (s *SQLStore) ListDailyRewards(ctx context.Context) ([]models.DailyReward, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT streak_day, currency, amount FROM daily_rewards ORDER BY streak_day, currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rewards []models.DailyReward
+	for rows.Next() {
+		var r models.DailyReward
+		if err := rows.Scan(&r.Day, &r.Currency, &r.Amount); err != nil {
+			return nil, err
+		}
+		rewards = append(rewards, r)
+	}
+	return rewards, rows.Err()
+}
+
+// SetDailyReward задаёт награду ступени: r.Amount <= 0 удаляет валюту из ступени.
+func (s *SQLStore) SetDailyReward(ctx context.Context, r models.DailyReward) error {
+	if r.Amount <= 0 {
+		_, err := s.q.ExecContext(ctx, "DELETE FROM daily_rewards WHERE streak_day = ? AND currency = ?", r.Day, r.Currency)
+		return err
+	}
+	_, err := s.q.ExecContext(ctx, `
+INSERT INTO daily_rewards (streak_day, currency, amount) VALUES (?, ?, ?)
+ON CONFLICT (streak_day, currency) DO UPDATE SET amount = excluded.amount`, r.Day, r.Currency, r.Amount)
+	return err
+}
+
+// GetDailyStreak возвращает серию ежедневных наград персонажа; если наград
+// ещё не было – пустую серию.
+func (s *SQLStore) GetDailyStreak(ctx context.Context, profileID int) (*models.DailyStreak, error) {
+	ds := models.DailyStreak{ProfileID: profileID}
+	err := s.q.QueryRowContext(ctx, "SELECT streak, best_streak, last_day FROM daily_streaks WHERE profile_id = ?", profileID).
+		Scan(&ds.Streak, &ds.Best, &ds.LastDay)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return &ds, nil
+}
+
+// SaveDailyStreak сохраняет серию ежедневных наград.
+func (s *SQLStore) SaveDailyStreak(ctx context.Context, ds *models.DailyStreak) error {
+	_, err := s.q.ExecContext(ctx, `
+INSERT INTO daily_streaks (profile_id, streak, best_streak, last_day) VALUES (?, ?, ?, ?)
+ON CONFLICT (profile_id) DO UPDATE SET
+    streak = excluded.streak, best_streak = excluded.best_streak, last_day = excluded.last_day`,
+		ds.ProfileID, ds.Streak, ds.Best, ds.LastDay)
+	return err
+}
@@ -1,9 +1,11 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"telegram-bot/models"
@@ -11,151 +13,186 @@ import (
 	_ "modernc.org/sqlite" // чисто-Go драйвер SQLite
 )
 
-var DB *sql.DB
-
-// InitDB открывает (или создаёт) базу данных и создает таблицы.
-func InitDB() {
-	var err error
-	// Используйте DSN с нужными параметрами, чтобы база создавалась в режиме чтения/записи.
-	DB, err = sql.Open("sqlite", "file:bot.db?cache=shared&mode=rwc")
+// Open открывает (или создаёт) базу данных по DSN, не изменяя схему.
+func Open(dsn string) (*sql.DB, error) {
+	// DSN должен включать нужные параметры, чтобы база создавалась в режиме чтения/записи.
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
-		log.Fatalf("Ошибка открытия базы данных: %v", err)
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
 	}
+	return db, nil
+}
 
-	// Таблица для профилей пользователей.
-	queryProfiles := `
-CREATE TABLE IF NOT EXISTS profiles (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    telegram_id INTEGER UNIQUE,
-    username TEXT,
-    name TEXT,
-    age INTEGER,
-    height REAL,
-    weight REAL,
-    inventory TEXT,
-    photo TEXT,
-    rank TEXT,
-    team TEXT,
-    race TEXT,
-    piastres INTEGER,
-    oblomki INTEGER
-);`
-	_, err = DB.Exec(queryProfiles)
-	if err != nil {
-		log.Fatalf("Ошибка создания таблицы profiles: %v", err)
-	}
-
-	// Таблица для событий.
-	queryEvents := `
-CREATE TABLE IF NOT EXISTS events (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT,
-    currency_type TEXT,
-    amount INTEGER,
-    active INTEGER,
-    created_at DATETIME
-);`
-	_, err = DB.Exec(queryEvents)
+// OpenReadOnly открывает существующую базу данных только для чтения: параметр
+// mode в DSN заменяется на ro, поэтому отсутствующий файл не создаётся, а запись
+// в базу завершается ошибкой.
+func OpenReadOnly(dsn string) (*sql.DB, error) {
+	return Open(readOnlyDSN(dsn))
+}
+
+// readOnlyDSN возвращает DSN с mode=ro вместо исходного режима открытия.
+func readOnlyDSN(dsn string) string {
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	name, query, _ := strings.Cut(dsn, "?")
+	params := []string{"mode=ro"}
+	for _, p := range strings.Split(query, "&") {
+		if p != "" && !strings.HasPrefix(p, "mode=") {
+			params = append(params, p)
+		}
+	}
+	return name + "?" + strings.Join(params, "&")
+}
+
+// InitDB открывает базу данных, применяет ожидающие миграции схемы
+// (см. db/migrations) и возвращает Store. Завершает программу, если база новее бинарника.
+func InitDB(dsn string) *SQLStore {
+	db, err := Open(dsn)
 	if err != nil {
-		log.Fatalf("Ошибка создания таблицы events: %v", err)
-	}
-
-	// Таблица для участия в событиях.
-	queryParticipation := `
-CREATE TABLE IF NOT EXISTS event_participation (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    event_id INTEGER,
-    telegram_id INTEGER,
-    UNIQUE(event_id, telegram_id)
-);`
-	_, err = DB.Exec(queryParticipation)
+		log.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	store := NewStore(db)
+	applied, err := store.Migrate(context.Background())
+	for _, m := range applied {
+		log.Printf("Применена миграция %04d_%s", m.Version, m.Name)
+	}
 	if err != nil {
-		log.Fatalf("Ошибка создания таблицы event_participation: %v", err)
+		log.Fatalf("Ошибка миграции базы данных: %v", err)
 	}
+	return store
 }
 
 // -------------------- Функции для работы с профилями --------------------------
 
 // GetProfile извлекает профиль по telegram_id.
-func GetProfile(telegramID int64) (*models.Profile, error) {
+func (s *SQLStore) GetProfile(ctx context.Context, telegramID int64) (*models.Profile, error) {
 	query := `
-    SELECT id, telegram_id, username, name, age, height, weight, inventory, photo, rank, team, race, piastres, oblomki
+    SELECT id, telegram_id, username, name, age, height, weight, photo, rank, team, race
     FROM profiles WHERE telegram_id = ?`
-	row := DB.QueryRow(query, telegramID)
+	row := s.q.QueryRowContext(ctx, query, telegramID)
 
 	var p models.Profile
 	err := row.Scan(&p.ID, &p.TelegramID, &p.Username, &p.Name, &p.Age, &p.Height, &p.Weight,
-		&p.Inventory, &p.Photo, &p.Rank, &p.Team, &p.Race, &p.Piastres, &p.Oblomki)
+		&p.Photo, &p.Rank, &p.Team, &p.Race)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.loadDetails(ctx, &p); err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
 // GetProfileByID извлекает профиль по уникальному номеру (ID).
-func GetProfileByID(id int) (*models.Profile, error) {
+func (s *SQLStore) GetProfileByID(ctx context.Context, id int) (*models.Profile, error) {
 	query := `
-    SELECT id, telegram_id, username, name, age, height, weight, inventory, photo, rank, team, race, piastres, oblomki
+    SELECT id, telegram_id, username, name, age, height, weight, photo, rank, team, race
     FROM profiles WHERE id = ?`
-	row := DB.QueryRow(query, id)
+	row := s.q.QueryRowContext(ctx, query, id)
 
 	var p models.Profile
 	err := row.Scan(&p.ID, &p.TelegramID, &p.Username, &p.Name, &p.Age, &p.Height, &p.Weight,
-		&p.Inventory, &p.Photo, &p.Rank, &p.Team, &p.Race, &p.Piastres, &p.Oblomki)
+		&p.Photo, &p.Rank, &p.Team, &p.Race)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.loadDetails(ctx, &p); err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
-// CreateProfile вставляет новый профиль в базу.
-func CreateProfile(p *models.Profile) error {
+// GetProfileByUsername извлекает профиль по Telegram username (без @, без учёта регистра).
+func (s *SQLStore) GetProfileByUsername(ctx context.Context, username string) (*models.Profile, error) {
 	query := `
-    INSERT INTO profiles (telegram_id, username, name, age, height, weight, inventory, photo, rank, team, race, piastres, oblomki)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	res, err := DB.Exec(query, p.TelegramID, p.Username, p.Name, p.Age, p.Height, p.Weight,
-		p.Inventory, p.Photo, p.Rank, p.Team, p.Race, p.Piastres, p.Oblomki)
+    SELECT id, telegram_id, username, name, age, height, weight, photo, rank, team, race
+    FROM profiles WHERE lower(username) = lower(?)`
+	row := s.q.QueryRowContext(ctx, query, username)
+
+	var p models.Profile
+	err := row.Scan(&p.ID, &p.TelegramID, &p.Username, &p.Name, &p.Age, &p.Height, &p.Weight,
+		&p.Photo, &p.Rank, &p.Team, &p.Race)
 	if err != nil {
-		log.Printf("Ошибка вставки профиля: %v", err)
-		return err
+		return nil, err
 	}
-	id, err := res.LastInsertId()
-	if err != nil {
+	if err := s.loadDetails(ctx, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// loadDetails заполняет у профилей балансы и инвентарь.
+func (s *SQLStore) loadDetails(ctx context.Context, profiles ...*models.Profile) error {
+	if err := s.loadBalances(ctx, profiles...); err != nil {
 		return err
 	}
-	p.ID = int(id)
-	return nil
+	return s.loadInventory(ctx, profiles...)
 }
 
-// UpdateProfile обновляет данные профиля.
-func UpdateProfile(p *models.Profile) error {
+// CreateProfile вставляет новый профиль в базу.
+// Баланс нового профиля нулевой; начисления выполняются через ChangeBalance.
+// Инвентарь из анкеты (StartingInventory) сохраняется заметкой в инвентаре.
+func (s *SQLStore) CreateProfile(ctx context.Context, p *models.Profile) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		query := `
+    INSERT INTO profiles (telegram_id, username, name, age, height, weight, photo, rank, team, race)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		res, err := t.q.ExecContext(ctx, query, p.TelegramID, p.Username, p.Name, p.Age, p.Height, p.Weight,
+			p.Photo, p.Rank, p.Team, p.Race)
+		if err != nil {
+			log.Printf("Ошибка вставки профиля: %v", err)
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		p.ID = int(id)
+		if p.StartingInventory != "" {
+			if err := t.AddInventoryNote(ctx, p.ID, p.StartingInventory); err != nil {
+				return err
+			}
+		}
+		return t.loadDetails(ctx, p)
+	})
+}
+
+// UpdateProfile обновляет данные профиля, кроме баланса и инвентаря:
+// баланс меняется только через ChangeBalance, чтобы каждая операция попала в журнал,
+// инвентарь – через AddInventoryItem и RemoveInventoryItem.
+func (s *SQLStore) UpdateProfile(ctx context.Context, p *models.Profile) error {
 	query := `
     UPDATE profiles SET username = ?, name = ?, age = ?, height = ?, weight = ?,
-    inventory = ?, photo = ?, rank = ?, team = ?, race = ?, piastres = ?, oblomki = ?
+    photo = ?, rank = ?, team = ?, race = ?
     WHERE telegram_id = ?`
-	_, err := DB.Exec(query, p.Username, p.Name, p.Age, p.Height, p.Weight, p.Inventory,
-		p.Photo, p.Rank, p.Team, p.Race, p.Piastres, p.Oblomki, p.TelegramID)
+	_, err := s.q.ExecContext(ctx, query, p.Username, p.Name, p.Age, p.Height, p.Weight,
+		p.Photo, p.Rank, p.Team, p.Race, p.TelegramID)
 	return err
 }
 
 // SaveProfile сохраняет профиль: создает новый, если профиль не найден, или обновляет существующий.
-func SaveProfile(p *models.Profile) error {
-	_, err := GetProfile(p.TelegramID)
+func (s *SQLStore) SaveProfile(ctx context.Context, p *models.Profile) error {
+	_, err := s.GetProfile(ctx, p.TelegramID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return CreateProfile(p)
+			return s.CreateProfile(ctx, p)
 		}
 		return err
 	}
-	return UpdateProfile(p)
+	return s.UpdateProfile(ctx, p)
 }
 
 // GetAllProfiles возвращает все профили.
-func GetAllProfiles() ([]*models.Profile, error) {
+func (s *SQLStore) GetAllProfiles(ctx context.Context) ([]*models.Profile, error) {
 	query := `
-    SELECT id, telegram_id, username, name, age, height, weight, inventory, photo, rank, team, race, piastres, oblomki
+    SELECT id, telegram_id, username, name, age, height, weight, photo, rank, team, race
     FROM profiles`
-	rows, err := DB.Query(query)
+	rows, err := s.q.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -165,45 +202,79 @@ func GetAllProfiles() ([]*models.Profile, error) {
 	for rows.Next() {
 		var p models.Profile
 		err = rows.Scan(&p.ID, &p.TelegramID, &p.Username, &p.Name, &p.Age, &p.Height, &p.Weight,
-			&p.Inventory, &p.Photo, &p.Rank, &p.Team, &p.Race, &p.Piastres, &p.Oblomki)
+			&p.Photo, &p.Rank, &p.Team, &p.Race)
 		if err != nil {
 			log.Printf("Ошибка Scan: %v", err)
 			continue
 		}
 		profiles = append(profiles, &p)
 	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := s.loadDetails(ctx, profiles...); err != nil {
+		return nil, err
+	}
 	return profiles, nil
 }
 
-// DeleteProfile удаляет профиль по telegram_id.
-func DeleteProfile(telegramID int64) error {
-	query := "DELETE FROM profiles WHERE telegram_id = ?"
-	res, err := DB.Exec(query, telegramID)
-	if err != nil {
-		return err
-	}
-	rows, err := res.RowsAffected()
-	if err != nil || rows == 0 {
-		return errors.New("профиль не найден")
-	}
-	return nil
+// DeleteProfile удаляет профиль по telegram_id вместе с его балансами, инвентарём, серией наград и участием в событиях.
+func (s *SQLStore) DeleteProfile(ctx context.Context, telegramID int64) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		for _, table := range []string{"balances", "inventory", "daily_streaks"} {
+			_, err := t.q.ExecContext(ctx,
+				"DELETE FROM "+table+" WHERE profile_id IN (SELECT id FROM profiles WHERE telegram_id = ?)", telegramID)
+			if err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"event_participation", "event_waitlist"} {
+			if _, err := t.q.ExecContext(ctx, "DELETE FROM "+table+" WHERE telegram_id = ?", telegramID); err != nil {
+				return err
+			}
+		}
+		query := "DELETE FROM profiles WHERE telegram_id = ?"
+		res, err := t.q.ExecContext(ctx, query, telegramID)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil || rows == 0 {
+			return errors.New("профиль не найден")
+		}
+		return nil
+	})
 }
 
-// DeleteProfileByID удаляет профиль по уникальному номеру (id).
-func DeleteProfileByID(id int) error {
-	query := "DELETE FROM profiles WHERE id = ?"
-	res, err := DB.Exec(query, id)
-	if err != nil {
-		return err
-	}
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return errors.New("профиль не найден")
-	}
-	return nil
+// DeleteProfileByID удаляет профиль по уникальному номеру (id) вместе с его балансами, инвентарём, серией наград и участием в событиях.
+func (s *SQLStore) DeleteProfileByID(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		for _, table := range []string{"balances", "inventory", "daily_streaks"} {
+			if _, err := t.q.ExecContext(ctx, "DELETE FROM "+table+" WHERE profile_id = ?", id); err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"event_participation", "event_waitlist"} {
+			_, err := t.q.ExecContext(ctx,
+				"DELETE FROM "+table+" WHERE telegram_id IN (SELECT telegram_id FROM profiles WHERE id = ?)", id)
+			if err != nil {
+				return err
+			}
+		}
+		query := "DELETE FROM profiles WHERE id = ?"
+		res, err := t.q.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errors.New("профиль не найден")
+		}
+		return nil
+	})
 }
 
 // -------------------- Функции для работы с событиями --------------------------
@@ -216,12 +287,17 @@ func boolToInt(b bool) int {
 	return 0
 }
 
+const eventColumns = "id, name, currency_type, amount, active, created_at, starts_at, ends_at, closed_at, remind_minutes, checkin_secret, checkin_rotate_minutes, capacity, template_id"
+
 // CreateEvent вставляет новое событие в базу.
-func CreateEvent(e *models.Event) error {
+func (s *SQLStore) CreateEvent(ctx context.Context, e *models.Event) error {
 	query := `
-INSERT INTO events (name, currency_type, amount, active, created_at)
-VALUES (?, ?, ?, ?, ?)`
-	res, err := DB.Exec(query, e.Name, e.CurrencyType, e.Amount, boolToInt(e.Active), e.CreatedAt.Format(time.RFC3339))
+INSERT INTO events (name, currency_type, amount, active, created_at, starts_at, ends_at, closed_at, remind_minutes,
+	checkin_secret, checkin_rotate_minutes, capacity, template_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.q.ExecContext(ctx, query, e.Name, e.CurrencyType, e.Amount, boolToInt(e.Active),
+		e.CreatedAt.Format(time.RFC3339), nullTime(e.StartsAt), nullTime(e.EndsAt), nullTime(e.ClosedAt), int(e.RemindBefore.Minutes()),
+		e.CheckinSecret, int(e.CheckinRotate.Minutes()), e.Capacity, e.TemplateID)
 	if err != nil {
 		return err
 	}
@@ -234,72 +310,111 @@ VALUES (?, ?, ?, ?, ?)`
 }
 
 // GetEventByID извлекает событие из базы по его ID.
-func GetEventByID(id int) (*models.Event, error) {
-	query := `
-SELECT id, name, currency_type, amount, active, created_at
-FROM events WHERE id = ?`
-	row := DB.QueryRow(query, id)
-	var e models.Event
-	var activeInt int
-	var createdAtStr string
-	err := row.Scan(&e.ID, &e.Name, &e.CurrencyType, &e.Amount, &activeInt, &createdAtStr)
-	if err != nil {
-		return nil, err
-	}
-	e.Active = activeInt == 1
-	e.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
-	if err != nil {
-		return nil, err
-	}
-	return &e, nil
+func (s *SQLStore) GetEventByID(ctx context.Context, id int) (*models.Event, error) {
+	row := s.q.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events WHERE id = ?", id)
+	return scanEvent(row)
 }
 
 // UpdateEvent обновляет событие (например, завершает его).
-func UpdateEvent(e *models.Event) error {
+func (s *SQLStore) UpdateEvent(ctx context.Context, e *models.Event) error {
 	query := `
-UPDATE events SET name = ?, currency_type = ?, amount = ?, active = ?, created_at = ?
+UPDATE events SET name = ?, currency_type = ?, amount = ?, active = ?, created_at = ?,
+	starts_at = ?, ends_at = ?, closed_at = ?, remind_minutes = ?, checkin_secret = ?, checkin_rotate_minutes = ?,
+	capacity = ?
 WHERE id = ?`
-	_, err := DB.Exec(query, e.Name, e.CurrencyType, e.Amount, boolToInt(e.Active), e.CreatedAt.Format(time.RFC3339), e.ID)
+	_, err := s.q.ExecContext(ctx, query, e.Name, e.CurrencyType, e.Amount, boolToInt(e.Active),
+		e.CreatedAt.Format(time.RFC3339), nullTime(e.StartsAt), nullTime(e.EndsAt), nullTime(e.ClosedAt),
+		int(e.RemindBefore.Minutes()), e.CheckinSecret, int(e.CheckinRotate.Minutes()), e.Capacity, e.ID)
 	return err
 }
 
-// GetActiveEvents возвращает список активных событий.
-func GetActiveEvents() ([]*models.Event, error) {
-	query := `
-SELECT id, name, currency_type, amount, active, created_at
-FROM events WHERE active = 1`
-	rows, err := DB.Query(query)
+// GetActiveEvents возвращает список активных событий по порядку создания.
+func (s *SQLStore) GetActiveEvents(ctx context.Context) ([]*models.Event, error) {
+	return s.queryEvents(ctx, "SELECT "+eventColumns+" FROM events WHERE active = 1 ORDER BY id")
+}
+
+// ListEvents возвращает последние limit событий, новые первыми.
+func (s *SQLStore) ListEvents(ctx context.Context, limit int) ([]*models.Event, error) {
+	return s.queryEvents(ctx, "SELECT "+eventColumns+" FROM events ORDER BY id DESC LIMIT ?", limit)
+}
+
+// EventStats возвращает число участников события и сумму, начисленную за участие
+// (за вычетом списаний при отмене участия).
+func (s *SQLStore) EventStats(ctx context.Context, eventID int) (participants, paid int, err error) {
+	err = s.q.QueryRowContext(ctx, "SELECT COUNT(*) FROM event_participation WHERE event_id = ?", eventID).Scan(&participants)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = s.q.QueryRowContext(ctx, "SELECT COALESCE(SUM(delta), 0) FROM currency_ledger WHERE source_type = ? AND source_id = ?",
+		models.LedgerSourceEvent, eventID).Scan(&paid)
+	return participants, paid, err
+}
+
+func (s *SQLStore) queryEvents(ctx context.Context, query string, args ...any) ([]*models.Event, error) {
+	rows, err := s.q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var events []*models.Event
 	for rows.Next() {
-		var e models.Event
-		var activeInt int
-		var createdAtStr string
-		err = rows.Scan(&e.ID, &e.Name, &e.CurrencyType, &e.Amount, &activeInt, &createdAtStr)
+		e, err := scanEvent(rows)
 		if err != nil {
-			log.Printf("Ошибка сканирования события: %v", err)
-			continue
+			return nil, err
 		}
-		e.Active = activeInt == 1
-		e.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
-		if err != nil {
-			log.Printf("Ошибка парсинга даты: %v", err)
-			continue
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func scanEvent(row scanner) (*models.Event, error) {
+	var e models.Event
+	var activeInt, remindMinutes, rotateMinutes int
+	var createdAtStr string
+	var startsAtStr, endsAtStr, closedAtStr sql.NullString
+	err := row.Scan(&e.ID, &e.Name, &e.CurrencyType, &e.Amount, &activeInt, &createdAtStr,
+		&startsAtStr, &endsAtStr, &closedAtStr, &remindMinutes, &e.CheckinSecret, &rotateMinutes, &e.Capacity, &e.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	e.Active = activeInt == 1
+	e.RemindBefore = time.Duration(remindMinutes) * time.Minute
+	e.CheckinRotate = time.Duration(rotateMinutes) * time.Minute
+	if e.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	if startsAtStr.Valid {
+		if e.StartsAt, err = time.Parse(time.RFC3339, startsAtStr.String); err != nil {
+			return nil, err
+		}
+	}
+	if endsAtStr.Valid {
+		if e.EndsAt, err = time.Parse(time.RFC3339, endsAtStr.String); err != nil {
+			return nil, err
+		}
+	}
+	if closedAtStr.Valid {
+		if e.ClosedAt, err = time.Parse(time.RFC3339, closedAtStr.String); err != nil {
+			return nil, err
 		}
-		events = append(events, &e)
 	}
-	return events, nil
+	return &e, nil
+}
+
+// nullTime – значение для необязательного столбца времени: NULL для нулевого времени.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
 }
 
 // UserParticipatedInEvent проверяет, отмечался ли пользователь (telegram_id) на событие (event_id).
-func UserParticipatedInEvent(eventID int, telegramID int64) (bool, error) {
+func (s *SQLStore) UserParticipatedInEvent(ctx context.Context, eventID int, telegramID int64) (bool, error) {
 	query := `
 SELECT COUNT(*) FROM event_participation 
 WHERE event_id = ? AND telegram_id = ?`
-	row := DB.QueryRow(query, eventID, telegramID)
+	row := s.q.QueryRowContext(ctx, query, eventID, telegramID)
 	var count int
 	err := row.Scan(&count)
 	if err != nil {
@@ -309,20 +424,20 @@ WHERE event_id = ? AND telegram_id = ?`
 }
 
 // AddEventParticipation регистрирует участие пользователя в событии.
-func AddEventParticipation(eventID int, telegramID int64) error {
+func (s *SQLStore) AddEventParticipation(ctx context.Context, eventID int, telegramID int64) error {
 	query := `
-INSERT INTO event_participation (event_id, telegram_id)
-VALUES (?, ?)`
-	_, err := DB.Exec(query, eventID, telegramID)
+INSERT INTO event_participation (event_id, telegram_id, profile_id)
+VALUES (?, ?, (SELECT id FROM profiles WHERE telegram_id = ?))`
+	_, err := s.q.ExecContext(ctx, query, eventID, telegramID, telegramID)
 	return err
 }
 
 // RemoveEventParticipation удаляет запись о участии пользователя в событии.
-func RemoveEventParticipation(eventID int, telegramID int64) error {
+func (s *SQLStore) RemoveEventParticipation(ctx context.Context, eventID int, telegramID int64) error {
 	query := `
 DELETE FROM event_participation 
 WHERE event_id = ? AND telegram_id = ?`
-	res, err := DB.Exec(query, eventID, telegramID)
+	res, err := s.q.ExecContext(ctx, query, eventID, telegramID)
 	if err != nil {
 		return err
 	}
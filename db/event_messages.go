@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+
+	"telegram-bot/models"
+)
+
+// SaveEventMessage запоминает разосланное объявление о событии.
+func (s *SQLStore) SaveEventMessage(ctx context.Context, m *models.EventMessage) error {
+	_, err := s.q.ExecContext(ctx, "INSERT OR REPLACE INTO event_messages (event_id, chat_id, message_id, title) VALUES (?, ?, ?, ?)",
+		m.EventID, m.ChatID, m.MessageID, m.Title)
+	return err
+}
+
+// GetEventMessage возвращает объявление о событии по чату и сообщению или sql.ErrNoRows.
+func (s *SQLStore) GetEventMessage(ctx context.Context, chatID int64, messageID int) (*models.EventMessage, error) {
+	var m models.EventMessage
+	err := s.q.QueryRowContext(ctx, "SELECT event_id, chat_id, message_id, title FROM event_messages WHERE chat_id = ? AND message_id = ?",
+		chatID, messageID).Scan(&m.EventID, &m.ChatID, &m.MessageID, &m.Title)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ListEventMessages возвращает разосланные объявления о событии.
+func (s *SQLStore) ListEventMessages(ctx context.Context, eventID int) ([]*models.EventMessage, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT event_id, chat_id, message_id, title FROM event_messages WHERE event_id = ?", eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var msgs []*models.EventMessage
+	for rows.Next() {
+		var m models.EventMessage
+		if err := rows.Scan(&m.EventID, &m.ChatID, &m.MessageID, &m.Title); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &m)
+	}
+	return msgs, rows.Err()
+}
+
+// DeleteEventMessages забывает объявления о событии.
+func (s *SQLStore) DeleteEventMessages(ctx context.Context, eventID int) error {
+	_, err := s.q.ExecContext(ctx, "DELETE FROM event_messages WHERE event_id = ?", eventID)
+	return err
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"telegram-bot/models"
+)
+
+const eventTemplateColumns = "id, name, currency_type, amount, schedule, duration_minutes, remind_minutes, checkin, " +
+	"checkin_rotate_minutes, capacity, paused, next_run_at, last_event_id, created_at"
+
+// CreateEventTemplate сохраняет новый шаблон события и заполняет t.ID и t.CreatedAt.
+func (s *SQLStore) CreateEventTemplate(ctx context.Context, t *models.EventTemplate) error {
+	t.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO event_templates (name, currency_type, amount, schedule, duration_minutes, remind_minutes, checkin,
+	checkin_rotate_minutes, capacity, paused, next_run_at, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Name, t.CurrencyType, t.Amount, t.Schedule, int(t.Duration.Minutes()), int(t.RemindBefore.Minutes()),
+		boolToInt(t.Checkin), int(t.CheckinRotate.Minutes()), t.Capacity, boolToInt(t.Paused),
+		t.NextRunAt.UTC().Format(time.RFC3339), t.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	t.ID = int(id)
+	return nil
+}
+
+// GetEventTemplate возвращает шаблон события по ID или sql.ErrNoRows.
+func (s *SQLStore) GetEventTemplate(ctx context.Context, id int) (*models.EventTemplate, error) {
+	return scanEventTemplate(s.q.QueryRowContext(ctx, "SELECT "+eventTemplateColumns+" FROM event_templates WHERE id = ?", id))
+}
+
+// UpdateEventTemplate сохраняет паузу, ближайший повтор и последнее созданное событие шаблона.
+func (s *SQLStore) UpdateEventTemplate(ctx context.Context, t *models.EventTemplate) error {
+	_, err := s.q.ExecContext(ctx, "UPDATE event_templates SET paused = ?, next_run_at = ?, last_event_id = ? WHERE id = ?",
+		boolToInt(t.Paused), t.NextRunAt.UTC().Format(time.RFC3339), t.LastEventID, t.ID)
+	return err
+}
+
+// DeleteEventTemplate удаляет шаблон и его пропуски; sql.ErrNoRows, если шаблона нет.
+// Созданные по шаблону события остаются.
+func (s *SQLStore) DeleteEventTemplate(ctx context.Context, id int) error {
+	res, err := s.q.ExecContext(ctx, "DELETE FROM event_templates WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = s.q.ExecContext(ctx, "DELETE FROM event_template_skips WHERE template_id = ?", id)
+	return err
+}
+
+// ListEventTemplates возвращает все шаблоны событий по порядку добавления.
+func (s *SQLStore) ListEventTemplates(ctx context.Context) ([]*models.EventTemplate, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT "+eventTemplateColumns+" FROM event_templates ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var templates []*models.EventTemplate
+	for rows.Next() {
+		t, err := scanEventTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// SkipOccurrence отмечает повтор шаблона, который начинается в startsAt, как пропущенный.
+func (s *SQLStore) SkipOccurrence(ctx context.Context, templateID int, startsAt time.Time) error {
+	_, err := s.q.ExecContext(ctx, "INSERT OR IGNORE INTO event_template_skips (template_id, starts_at) VALUES (?, ?)",
+		templateID, startsAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// UnskipOccurrence снимает пропуск повтора; sql.ErrNoRows, если повтор не пропускался.
+func (s *SQLStore) UnskipOccurrence(ctx context.Context, templateID int, startsAt time.Time) error {
+	res, err := s.q.ExecContext(ctx, "DELETE FROM event_template_skips WHERE template_id = ? AND starts_at = ?",
+		templateID, startsAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListSkippedOccurrences возвращает пропускаемые повторы шаблона по времени начала.
+func (s *SQLStore) ListSkippedOccurrences(ctx context.Context, templateID int) ([]time.Time, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT starts_at FROM event_template_skips WHERE template_id = ? ORDER BY starts_at", templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var skipped []time.Time
+	for rows.Next() {
+		var startsAtStr string
+		if err := rows.Scan(&startsAtStr); err != nil {
+			return nil, err
+		}
+		startsAt, err := time.Parse(time.RFC3339, startsAtStr)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, startsAt)
+	}
+	return skipped, rows.Err()
+}
+
+func scanEventTemplate(row scanner) (*models.EventTemplate, error) {
+	var t models.EventTemplate
+	var durationMinutes, remindMinutes, checkin, rotateMinutes, paused int
+	var nextRunAtStr, createdAtStr string
+	err := row.Scan(&t.ID, &t.Name, &t.CurrencyType, &t.Amount, &t.Schedule, &durationMinutes, &remindMinutes, &checkin,
+		&rotateMinutes, &t.Capacity, &paused, &nextRunAtStr, &t.LastEventID, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	t.Duration = time.Duration(durationMinutes) * time.Minute
+	t.RemindBefore = time.Duration(remindMinutes) * time.Minute
+	t.Checkin = checkin != 0
+	t.CheckinRotate = time.Duration(rotateMinutes) * time.Minute
+	t.Paused = paused != 0
+	if t.NextRunAt, err = time.Parse(time.RFC3339, nextRunAtStr); err != nil {
+		return nil, err
+	}
+	if t.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
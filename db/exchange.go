@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// SetExchangeRate сохраняет новый курс направления обмена и заполняет r.ID и r.CreatedAt.
+// Прежние курсы остаются в истории.
+func (s *SQLStore) SetExchangeRate(ctx context.Context, r *models.ExchangeRate) error {
+	r.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO exchange_rates (from_currency, to_currency, from_amount, to_amount, set_by, created_at)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		r.FromCurrency, r.ToCurrency, r.FromAmount, r.ToAmount, r.SetBy, r.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	r.ID = int(id)
+	return nil
+}
+
+// GetExchangeRate возвращает текущий курс направления from → to или sql.ErrNoRows,
+// если курс ни разу не задавался.
+func (s *SQLStore) GetExchangeRate(ctx context.Context, from, to string) (*models.ExchangeRate, error) {
+	row := s.q.QueryRowContext(ctx, `
+SELECT id, from_currency, to_currency, from_amount, to_amount, set_by, created_at
+FROM exchange_rates WHERE from_currency = ? AND to_currency = ?
+ORDER BY id DESC LIMIT 1`, from, to)
+	return scanExchangeRate(row)
+}
+
+// ListExchangeRates возвращает текущие курсы всех направлений, включая закрытые.
+func (s *SQLStore) ListExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	return s.queryExchangeRates(ctx, `
+SELECT r.id, r.from_currency, r.to_currency, r.from_amount, r.to_amount, r.set_by, r.created_at
+FROM exchange_rates r
+JOIN (SELECT MAX(id) AS id FROM exchange_rates GROUP BY from_currency, to_currency) latest ON latest.id = r.id
+LEFT JOIN currencies f ON f.code = r.from_currency
+LEFT JOIN currencies t ON t.code = r.to_currency
+ORDER BY f.rowid, t.rowid`)
+}
+
+// ExchangeRateHistory возвращает последние limit изменений курса (новые первыми).
+// Если from и to заданы – только по направлению from → to.
+func (s *SQLStore) ExchangeRateHistory(ctx context.Context, from, to string, limit int) ([]*models.ExchangeRate, error) {
+	return s.queryExchangeRates(ctx, `
+SELECT id, from_currency, to_currency, from_amount, to_amount, set_by, created_at
+FROM exchange_rates
+WHERE ? = '' OR (from_currency = ? AND to_currency = ?)
+ORDER BY id DESC LIMIT ?`, from, from, to, limit)
+}
+
+// queryExchangeRates выполняет выборку курсов.
+func (s *SQLStore) queryExchangeRates(ctx context.Context, query string, args ...any) ([]*models.ExchangeRate, error) {
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rates []*models.ExchangeRate
+	for rows.Next() {
+		r, err := scanExchangeRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// scanExchangeRate читает курс из строки результата.
+func scanExchangeRate(row scanner) (*models.ExchangeRate, error) {
+	var r models.ExchangeRate
+	var createdAtStr string
+	err := row.Scan(&r.ID, &r.FromCurrency, &r.ToCurrency, &r.FromAmount, &r.ToAmount, &r.SetBy, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	if r.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateExchange сохраняет котировку обмена и заполняет e.ID и e.CreatedAt.
+func (s *SQLStore) CreateExchange(ctx context.Context, e *models.Exchange) error {
+	e.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO exchanges (profile_id, rate_id, from_currency, to_currency, amount, received, fee, status, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ProfileID, e.RateID, e.FromCurrency, e.ToCurrency, e.Amount, e.Received, e.Fee, e.Status,
+		e.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	e.ID = int(id)
+	return nil
+}
+
+// GetExchange возвращает обмен валют по ID или sql.ErrNoRows.
+func (s *SQLStore) GetExchange(ctx context.Context, id int) (*models.Exchange, error) {
+	query := `
+SELECT id, profile_id, rate_id, from_currency, to_currency, amount, received, fee, status, created_at, completed_at
+FROM exchanges WHERE id = ?`
+	var e models.Exchange
+	var createdAtStr string
+	var completedAtStr sql.NullString
+	err := s.q.QueryRowContext(ctx, query, id).Scan(&e.ID, &e.ProfileID, &e.RateID, &e.FromCurrency, &e.ToCurrency,
+		&e.Amount, &e.Received, &e.Fee, &e.Status, &createdAtStr, &completedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	if e.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	if completedAtStr.Valid {
+		if e.CompletedAt, err = time.Parse(time.RFC3339, completedAtStr.String); err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+// UpdateExchange сохраняет статус и время выполнения обмена.
+func (s *SQLStore) UpdateExchange(ctx context.Context, e *models.Exchange) error {
+	var completedAt any
+	if !e.CompletedAt.IsZero() {
+		completedAt = e.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	_, err := s.q.ExecContext(ctx, "UPDATE exchanges SET status = ?, completed_at = ? WHERE id = ?",
+		e.Status, completedAt, e.ID)
+	return err
+}
+
+// ExchangedSince возвращает, сколько валюты from профиль обменял начиная с момента since.
+func (s *SQLStore) ExchangedSince(ctx context.Context, profileID int, from string, since time.Time) (int, error) {
+	query := `
+SELECT COALESCE(SUM(amount), 0) FROM exchanges
+WHERE profile_id = ? AND from_currency = ? AND status = ? AND completed_at >= ?`
+	var total int
+	err := s.q.QueryRowContext(ctx, query, profileID, from, models.ExchangeCompleted,
+		since.UTC().Format(time.RFC3339)).Scan(&total)
+	return total, err
+}
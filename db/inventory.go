@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// ErrItemExists возвращается при добавлении предмета с уже занятым названием.
+var ErrItemExists = errors.New("предмет с таким названием уже есть")
+
+// itemNameKey – ключ названия предмета (items.name_key): названия с одинаковым
+// ключом считаются одним предметом. В отличие от COLLATE NOCASE, регистр
+// приводится и у кириллицы.
+func itemNameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// fillItemNameKeys – шаг миграции 0020: заполняет items.name_key через
+// itemNameKey и создаёт уникальный индекс по ключу. Ключ считается в Go, потому
+// что lower() в SQLite не приводит кириллицу к нижнему регистру.
+func fillItemNameKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, name FROM items")
+	if err != nil {
+		return err
+	}
+	keys := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		keys[id] = itemNameKey(name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, key := range keys {
+		if _, err := tx.ExecContext(ctx, "UPDATE items SET name_key = ? WHERE id = ?", key, id); err != nil {
+			return err
+		}
+	}
+	// Предметы, названия которых различаются только регистром, могли появиться при
+	// переносе товаров магазина (0007). Первый сохраняет название, остальные
+	// получают ключ с ID и находятся только по ID.
+	_, err = tx.ExecContext(ctx, `
+UPDATE items SET name_key = name_key || '#' || id
+WHERE id NOT IN (SELECT MIN(id) FROM items GROUP BY name_key);
+
+CREATE UNIQUE INDEX idx_items_name_key ON items (name_key);`)
+	return err
+}
+
+// CreateItem добавляет предмет в справочник и заполняет item.ID.
+// Названия предметов уникальны без учёта регистра.
+func (s *SQLStore) CreateItem(ctx context.Context, item *models.Item) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		if _, err := t.GetItemByName(ctx, item.Name); err == nil {
+			return fmt.Errorf("%w: %q", ErrItemExists, item.Name)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		item.CreatedAt = time.Now()
+		res, err := t.q.ExecContext(ctx, "INSERT INTO items (name, name_key, description, created_at) VALUES (?, ?, ?, ?)",
+			item.Name, itemNameKey(item.Name), item.Description, item.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		item.ID = int(id)
+		return nil
+	})
+}
+
+// GetItem возвращает предмет по ID или sql.ErrNoRows.
+func (s *SQLStore) GetItem(ctx context.Context, id int) (*models.Item, error) {
+	row := s.q.QueryRowContext(ctx, "SELECT id, name, description, created_at FROM items WHERE id = ?", id)
+	return scanItem(row)
+}
+
+// GetItemByName возвращает предмет по названию без учёта регистра или sql.ErrNoRows.
+func (s *SQLStore) GetItemByName(ctx context.Context, name string) (*models.Item, error) {
+	row := s.q.QueryRowContext(ctx, "SELECT id, name, description, created_at FROM items WHERE name_key = ?", itemNameKey(name))
+	return scanItem(row)
+}
+
+// ListItems возвращает справочник предметов, отсортированный по названию.
+func (s *SQLStore) ListItems(ctx context.Context) ([]*models.Item, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT id, name, description, created_at FROM items ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*models.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// scanItem читает предмет из строки результата.
+func scanItem(row scanner) (*models.Item, error) {
+	var item models.Item
+	var createdAtStr string
+	if err := row.Scan(&item.ID, &item.Name, &item.Description, &createdAtStr); err != nil {
+		return nil, err
+	}
+	var err error
+	item.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// AddInventoryItem добавляет персонажу quantity штук предмета.
+// Непустая заметка заменяет прежнюю заметку к этому предмету.
+func (s *SQLStore) AddInventoryItem(ctx context.Context, profileID, itemID, quantity int, note string) error {
+	_, err := s.q.ExecContext(ctx, `
+INSERT INTO inventory (profile_id, item_id, quantity, note) VALUES (?, ?, ?, ?)
+ON CONFLICT (profile_id, item_id) DO UPDATE SET
+    quantity = quantity + excluded.quantity,
+    note = CASE WHEN excluded.note != '' THEN excluded.note ELSE note END`,
+		profileID, itemID, quantity, note)
+	return err
+}
+
+// AddInventoryNote добавляет в инвентарь запись без предмета – только текст.
+func (s *SQLStore) AddInventoryNote(ctx context.Context, profileID int, note string) error {
+	_, err := s.q.ExecContext(ctx, "INSERT INTO inventory (profile_id, item_id, quantity, note) VALUES (?, NULL, 1, ?)",
+		profileID, note)
+	return err
+}
+
+// RemoveInventoryItem забирает у персонажа до quantity штук предмета и возвращает,
+// сколько забрано. Если предмета в инвентаре нет, возвращает sql.ErrNoRows.
+func (s *SQLStore) RemoveInventoryItem(ctx context.Context, profileID, itemID, quantity int) (int, error) {
+	var removed int
+	err := s.inTx(ctx, func(t *SQLStore) error {
+		var have int
+		err := t.q.QueryRowContext(ctx, "SELECT quantity FROM inventory WHERE profile_id = ? AND item_id = ?",
+			profileID, itemID).Scan(&have)
+		if err != nil {
+			return err
+		}
+		removed = min(have, quantity)
+		if removed == have {
+			_, err = t.q.ExecContext(ctx, "DELETE FROM inventory WHERE profile_id = ? AND item_id = ?", profileID, itemID)
+		} else {
+			_, err = t.q.ExecContext(ctx, "UPDATE inventory SET quantity = quantity - ? WHERE profile_id = ? AND item_id = ?",
+				removed, profileID, itemID)
+		}
+		return err
+	})
+	return removed, err
+}
+
+// DeleteInventoryEntry удаляет запись инвентаря персонажа по её номеру.
+// Возвращает sql.ErrNoRows, если такой записи у персонажа нет.
+func (s *SQLStore) DeleteInventoryEntry(ctx context.Context, profileID, entryID int) error {
+	res, err := s.q.ExecContext(ctx, "DELETE FROM inventory WHERE id = ? AND profile_id = ?", entryID, profileID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// loadInventory заполняет Items у профилей: сначала предметы по названию, затем заметки.
+func (s *SQLStore) loadInventory(ctx context.Context, profiles ...*models.Profile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Profile, len(profiles))
+	for _, p := range profiles {
+		p.Items = nil
+		byID[p.ID] = p
+	}
+	profileID := 0
+	if len(profiles) == 1 {
+		profileID = profiles[0].ID
+	}
+	query := `
+SELECT inv.profile_id, inv.id, COALESCE(inv.item_id, 0), COALESCE(i.name, ''), inv.quantity, inv.note
+FROM inventory inv LEFT JOIN items i ON i.id = inv.item_id
+WHERE ? = 0 OR inv.profile_id = ?
+ORDER BY inv.profile_id, inv.item_id IS NULL, i.name, inv.id`
+	rows, err := s.q.QueryContext(ctx, query, profileID, profileID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var it models.InventoryItem
+		if err := rows.Scan(&id, &it.ID, &it.ItemID, &it.Name, &it.Quantity, &it.Note); err != nil {
+			return err
+		}
+		if p, ok := byID[id]; ok {
+			p.Items = append(p.Items, it)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"telegram-bot/models"
+)
+
+func TestGetItemByNameIgnoresCase(t *testing.T) {
+	store := InitDB("file:" + filepath.Join(t.TempDir(), "bot.db"))
+	defer store.Close()
+	ctx := context.Background()
+
+	sword := &models.Item{Name: "Меч Ёрмунганда"}
+	if err := store.CreateItem(ctx, sword); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Меч Ёрмунганда", "меч ёрмунганда", "  МЕЧ ЁРМУНГАНДА "} {
+		item, err := store.GetItemByName(ctx, name)
+		if err != nil {
+			t.Errorf("GetItemByName(%q): %v", name, err)
+			continue
+		}
+		if item.ID != sword.ID {
+			t.Errorf("GetItemByName(%q) = предмет %d, want %d", name, item.ID, sword.ID)
+		}
+	}
+	if _, err := store.GetItemByName(ctx, "Меч"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetItemByName(\"Меч\") = %v, want sql.ErrNoRows", err)
+	}
+	if err := store.CreateItem(ctx, &models.Item{Name: "МЕЧ ЁРМУНГАНДА"}); !errors.Is(err, ErrItemExists) {
+		t.Errorf("CreateItem с тем же названием в другом регистре = %v, want ErrItemExists", err)
+	}
+}
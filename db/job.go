@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// ScheduleJob планирует задачу kind для объекта refID на момент runAt.
+// Если такая задача уже есть (в том числе выполненная), она переносится и
+// снова ждёт выполнения с обнулённым счётчиком попыток.
+func (s *SQLStore) ScheduleJob(ctx context.Context, kind string, refID int64, runAt time.Time) error {
+	_, err := s.q.ExecContext(ctx, `
+INSERT INTO jobs (kind, ref_id, run_at, status, created_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (kind, ref_id) DO UPDATE SET
+    run_at = excluded.run_at, status = excluded.status, attempts = 0, last_error = ''`,
+		kind, refID, runAt.UTC().Format(time.RFC3339), models.JobPending, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// ScheduleJobOnce планирует задачу kind для объекта refID на момент runAt, если она
+// ещё не ждёт выполнения; ожидающая задача не переносится. Так частые вызовы
+// объединяются в одно выполнение и не откладывают его бесконечно.
+func (s *SQLStore) ScheduleJobOnce(ctx context.Context, kind string, refID int64, runAt time.Time) error {
+	_, err := s.q.ExecContext(ctx, `
+INSERT INTO jobs (kind, ref_id, run_at, status, created_at) VALUES (?1, ?2, ?3, ?4, ?5)
+ON CONFLICT (kind, ref_id) DO UPDATE SET
+    run_at = excluded.run_at, status = excluded.status, attempts = 0, last_error = ''
+WHERE jobs.status <> ?4`,
+		kind, refID, runAt.UTC().Format(time.RFC3339), models.JobPending, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// CancelJob удаляет задачу kind для объекта refID, если она есть.
+func (s *SQLStore) CancelJob(ctx context.Context, kind string, refID int64) error {
+	_, err := s.q.ExecContext(ctx, "DELETE FROM jobs WHERE kind = ? AND ref_id = ?", kind, refID)
+	return err
+}
+
+// DueJobs возвращает до limit ожидающих задач, время которых наступило к now.
+func (s *SQLStore) DueJobs(ctx context.Context, now time.Time, limit int) ([]*models.Job, error) {
+	rows, err := s.q.QueryContext(ctx, `
+SELECT id, kind, ref_id, run_at, status, attempts, last_error, created_at
+FROM jobs WHERE status = ? AND run_at <= ?
+ORDER BY run_at, id LIMIT ?`,
+		models.JobPending, now.UTC().Format(time.RFC3339), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []*models.Job
+	for rows.Next() {
+		var j models.Job
+		var runAtStr, createdAtStr string
+		err := rows.Scan(&j.ID, &j.Kind, &j.RefID, &runAtStr, &j.Status, &j.Attempts, &j.LastError, &createdAtStr)
+		if err != nil {
+			return nil, err
+		}
+		if j.RunAt, err = time.Parse(time.RFC3339, runAtStr); err != nil {
+			return nil, err
+		}
+		if j.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &j)
+	}
+	return jobs, rows.Err()
+}
+
+// CompleteJob отмечает задачу выполненной. Если пока задача выполнялась, её
+// перенесли через ScheduleJob, отметка не ставится – задача выполнится в новое время.
+func (s *SQLStore) CompleteJob(ctx context.Context, job *models.Job) error {
+	_, err := s.q.ExecContext(ctx, "UPDATE jobs SET status = ? WHERE id = ? AND status = ? AND run_at = ?",
+		models.JobDone, job.ID, models.JobPending, job.RunAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// RetryJob записывает неудачную попытку выполнения задачи: следующая попытка
+// будет в nextRunAt, а нулевое nextRunAt отмечает задачу проваленной.
+// Как и CompleteJob, не трогает задачу, которую успели перенести.
+func (s *SQLStore) RetryJob(ctx context.Context, job *models.Job, nextRunAt time.Time, lastError string) error {
+	status, runAt := models.JobPending, nextRunAt
+	if nextRunAt.IsZero() {
+		status, runAt = models.JobFailed, job.RunAt
+	}
+	_, err := s.q.ExecContext(ctx, `
+UPDATE jobs SET status = ?, attempts = attempts + 1, last_error = ?, run_at = ?
+WHERE id = ? AND status = ? AND run_at = ?`,
+		status, lastError, runAt.UTC().Format(time.RFC3339),
+		job.ID, models.JobPending, job.RunAt.UTC().Format(time.RFC3339))
+	return err
+}
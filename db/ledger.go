@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// ChangeBalance изменяет баланс профиля на e.Delta и записывает операцию в журнал
+// в одной транзакции. Заполняет e.ID, e.BalanceAfter и e.CreatedAt.
+// Возвращает sql.ErrNoRows, если профиль не найден, и ErrUnknownCurrency,
+// если валюты нет в справочнике.
+func (s *SQLStore) ChangeBalance(ctx context.Context, e *models.LedgerEntry) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		if _, err := t.GetCurrency(ctx, e.Currency); err != nil {
+			return err
+		}
+		var exists int
+		if err := t.q.QueryRowContext(ctx, "SELECT 1 FROM profiles WHERE id = ?", e.ProfileID).Scan(&exists); err != nil {
+			return err
+		}
+		err := t.q.QueryRowContext(ctx, `
+INSERT INTO balances (profile_id, currency, amount) VALUES (?, ?, ?)
+ON CONFLICT (profile_id, currency) DO UPDATE SET amount = amount + excluded.amount
+RETURNING amount`, e.ProfileID, e.Currency, e.Delta).Scan(&e.BalanceAfter)
+		if err != nil {
+			return err
+		}
+		return t.insertLedgerEntry(ctx, e)
+	})
+}
+
+// CorrectBalance записывает исправление, найденное сверкой журнала с источниками:
+// баланс меняется на e.Delta, а в журнал попадает операция с тем же источником,
+// что и расхождение, чтобы после исправления сумма по источнику сошлась.
+// Нулевое исправление – ошибка.
+func (s *SQLStore) CorrectBalance(ctx context.Context, e *models.LedgerEntry) error {
+	if e.Delta == 0 {
+		return fmt.Errorf("нулевое исправление баланса анкеты %d", e.ProfileID)
+	}
+	return s.ChangeBalance(ctx, e)
+}
+
+// CreateAdminGrant записывает начисление администратора и заполняет g.ID и g.CreatedAt.
+// Саму операцию с балансом вызывающий записывает через ChangeBalance с SourceID = g.ID.
+func (s *SQLStore) CreateAdminGrant(ctx context.Context, g *models.AdminGrant) error {
+	g.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO admin_grants (profile_id, currency, amount, admin_id, reason, created_at)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		g.ProfileID, g.Currency, g.Amount, g.AdminID, g.Reason, g.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	g.ID = int(id)
+	return nil
+}
+
+// LedgerDrifts сверяет операции журнала с их источниками и возвращает расхождения
+// по порядку анкет и источников:
+//   - событие: за каждое участие начислена ровно одна награда в валюте события,
+//     а после отмены участия награда списана;
+//   - начисление администратора: в журнале ровно его сумма.
+//
+// События, прошедшие до появления журнала (pre_ledger), не сверяются.
+func (s *SQLStore) LedgerDrifts(ctx context.Context) ([]models.LedgerDrift, error) {
+	rows, err := s.q.QueryContext(ctx, `
+SELECT profile_id, currency, source_type, source_id, SUM(recorded), SUM(expected) FROM (
+    SELECT p.id AS profile_id, e.currency_type AS currency, 'event' AS source_type, e.id AS source_id,
+           0 AS recorded, e.amount AS expected
+    FROM event_participation ep
+    JOIN events e ON e.id = ep.event_id
+    JOIN profiles p ON p.id = ep.profile_id
+    WHERE e.pre_ledger = 0
+    UNION ALL
+    SELECT l.profile_id, l.currency, l.source_type, l.source_id, l.delta, 0
+    FROM currency_ledger l
+    WHERE l.source_type = 'event'
+      AND l.source_id NOT IN (SELECT id FROM events WHERE pre_ledger = 1)
+    UNION ALL
+    SELECT profile_id, currency, 'admin', id, 0, amount FROM admin_grants
+    UNION ALL
+    SELECT profile_id, currency, source_type, source_id, delta, 0
+    FROM currency_ledger WHERE source_type = 'admin'
+)
+WHERE profile_id IN (SELECT id FROM profiles)
+GROUP BY profile_id, currency, source_type, source_id
+HAVING SUM(recorded) != SUM(expected)
+ORDER BY profile_id, source_type, source_id, currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var drifts []models.LedgerDrift
+	for rows.Next() {
+		var d models.LedgerDrift
+		if err := rows.Scan(&d.ProfileID, &d.Currency, &d.SourceType, &d.SourceID, &d.Recorded, &d.Expected); err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, d)
+	}
+	return drifts, rows.Err()
+}
+
+// insertLedgerEntry записывает операцию в журнал и заполняет e.ID и e.CreatedAt.
+func (s *SQLStore) insertLedgerEntry(ctx context.Context, e *models.LedgerEntry) error {
+	e.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO currency_ledger (profile_id, currency, delta, balance_after, reason, source_type, source_id, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ProfileID, e.Currency, e.Delta, e.BalanceAfter, e.Reason, e.SourceType, e.SourceID,
+		e.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	e.ID = int(id)
+	return nil
+}
+
+// GetLedger возвращает последние limit операций профиля, от новых к старым.
+func (s *SQLStore) GetLedger(ctx context.Context, profileID, limit int) ([]*models.LedgerEntry, error) {
+	query := `
+SELECT l.id, l.profile_id, l.currency, COALESCE(c.name, l.currency), l.delta, l.balance_after,
+       l.reason, l.source_type, l.source_id, l.created_at
+FROM currency_ledger l LEFT JOIN currencies c ON c.code = l.currency
+WHERE l.profile_id = ?
+ORDER BY l.id DESC LIMIT ?`
+	rows, err := s.q.QueryContext(ctx, query, profileID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []*models.LedgerEntry
+	for rows.Next() {
+		e, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// scanLedgerEntry читает запись журнала из строки результата.
+func scanLedgerEntry(row scanner) (*models.LedgerEntry, error) {
+	var e models.LedgerEntry
+	var createdAtStr string
+	err := row.Scan(&e.ID, &e.ProfileID, &e.Currency, &e.CurrencyName, &e.Delta, &e.BalanceAfter, &e.Reason,
+		&e.SourceType, &e.SourceID, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	e.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationsFS содержит SQL-файлы миграций вида NNNN_описание.sql.
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Migration – одна версия схемы базы данных.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	// Step – шаг миграции на Go, выполняется после SQL в той же транзакции.
+	// Нужен, когда данные нельзя посчитать средствами самого SQLite.
+	Step func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrationSteps – шаги на Go по номеру миграции.
+var migrationSteps = map[int]func(ctx context.Context, tx *sql.Tx) error{
+	20: fillItemNameKeys,
+}
+
+// createMigrationsTable создаёт таблицу учёта применённых миграций.
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT,
+    applied_at DATETIME
+);`
+
+// loadMigrations читает встроенные миграции и сортирует их по номеру.
+func loadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("неверное имя файла миграции %s: ожидается NNNN_описание.sql", file)
+		}
+		if prev, dup := seen[version]; dup {
+			return nil, fmt.Errorf("миграции %s и %s имеют одинаковый номер %d", prev, file, version)
+		}
+		seen[version] = file
+		data, err := migrationsFS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(data), Step: migrationSteps[version]})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedVersions возвращает номера уже применённых миграций. Базу не изменяет:
+// если таблицы schema_migrations ещё нет, ни одна миграция не применена.
+func (s *SQLStore) appliedVersions(ctx context.Context) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	var tables int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&tables)
+	if err != nil || tables == 0 {
+		return applied, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// PendingMigrations возвращает миграции, которые ещё не применены к базе.
+// Возвращает ошибку, если база содержит миграции новее, чем знает этот бинарник
+// (то есть её уже обновила более новая версия бота).
+func (s *SQLStore) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for v := range applied {
+		if v > latest {
+			return nil, fmt.Errorf("схема базы данных (версия %d) новее, чем поддерживает эта версия бота (%d); "+
+				"обновите бота или используйте резервную копию базы", v, latest)
+		}
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate применяет все ожидающие миграции по порядку, каждую в своей транзакции.
+// Возвращает список применённых миграций.
+func (s *SQLStore) Migrate(ctx context.Context) ([]Migration, error) {
+	if _, err := s.db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, err
+	}
+	pending, err := s.PendingMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range pending {
+		if err := s.applyMigration(ctx, m); err != nil {
+			return pending[:i], fmt.Errorf("миграция %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// applyMigration выполняет миграцию и записывает её версию в одной транзакции.
+func (s *SQLStore) applyMigration(ctx context.Context, m Migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+	if m.Step != nil {
+		if err := m.Step(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
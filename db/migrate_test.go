@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPendingMigrationsReadOnly(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bot.db")
+	dsn := "file:" + path + "?mode=rwc&_txlock=immediate&_pragma=busy_timeout(5000)"
+
+	if conn, err := OpenReadOnly(dsn); err == nil {
+		conn.Close()
+		t.Error("OpenReadOnly открыл несуществующую базу")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("OpenReadOnly создал файл базы: %v", err)
+	}
+
+	conn, err := Open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	conn, err = OpenReadOnly(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(conn)
+	pending, err := store.PendingMigrations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(all) {
+		t.Errorf("ожидающих миграций %d, want %d", len(pending), len(all))
+	}
+	var tables int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("проверка миграций создала %d таблиц", tables)
+	}
+	store.Close()
+
+	InitDB(dsn).Close()
+	conn, err = OpenReadOnly(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store = NewStore(conn)
+	defer store.Close()
+	if pending, err := store.PendingMigrations(ctx); err != nil || len(pending) != 0 {
+		t.Errorf("после миграции: ожидающих %d, ошибка %v", len(pending), err)
+	}
+}
+
+func TestItemNameKeyMigration(t *testing.T) {
+	ctx := context.Background()
+	conn, err := Open("file:" + filepath.Join(t.TempDir(), "bot.db") + "?mode=rwc&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(conn)
+	defer store.Close()
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rest []Migration
+	for _, m := range migrations {
+		if m.Version >= 20 {
+			rest = append(rest, m)
+			continue
+		}
+		if err := store.applyMigration(ctx, m); err != nil {
+			t.Fatalf("миграция %04d: %v", m.Version, err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, `INSERT INTO items (name, created_at) VALUES
+		(' Меч ', '2024-01-01T00:00:00Z'), ('МЕЧ', '2024-01-01T00:00:00Z')`); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range rest {
+		if err := store.applyMigration(ctx, m); err != nil {
+			t.Fatalf("миграция %04d: %v", m.Version, err)
+		}
+	}
+
+	item, err := store.GetItemByName(ctx, "меч")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != " Меч " {
+		t.Errorf("по ключу найден %q, want первый предмет", item.Name)
+	}
+	var key string
+	if err := conn.QueryRowContext(ctx, "SELECT name_key FROM items WHERE name = 'МЕЧ'").Scan(&key); err != nil {
+		t.Fatal(err)
+	}
+	if key != "меч#2" {
+		t.Errorf("ключ второго предмета %q, want меч#2", key)
+	}
+}
@@ -0,0 +1,35 @@
+-- Исходная схема: профили, события и участие в событиях.
+-- IF NOT EXISTS – базы, созданные до появления миграций, уже содержат эти таблицы.
+
+CREATE TABLE IF NOT EXISTS profiles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER UNIQUE,
+    username TEXT,
+    name TEXT,
+    age INTEGER,
+    height REAL,
+    weight REAL,
+    inventory TEXT,
+    photo TEXT,
+    rank TEXT,
+    team TEXT,
+    race TEXT,
+    piastres INTEGER,
+    oblomki INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT,
+    currency_type TEXT,
+    amount INTEGER,
+    active INTEGER,
+    created_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS event_participation (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER,
+    telegram_id INTEGER,
+    UNIQUE(event_id, telegram_id)
+);
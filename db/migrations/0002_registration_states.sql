@@ -0,0 +1,10 @@
+-- Черновики регистрации (диалог /start), переживают перезапуск бота.
+
+CREATE TABLE IF NOT EXISTS registration_states (
+    telegram_id INTEGER PRIMARY KEY,
+    chat_id INTEGER,
+    step INTEGER,
+    profile TEXT,
+    updated_at DATETIME,
+    reminded INTEGER DEFAULT 0
+);
@@ -0,0 +1,27 @@
+-- Журнал изменений баланса: каждая операция с валютой – отдельная запись.
+-- Баланс в profiles меняется только вместе с записью в журнале.
+
+CREATE TABLE currency_ledger (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    profile_id INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    delta INTEGER NOT NULL,
+    balance_after INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    source_type TEXT NOT NULL,
+    source_id INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_currency_ledger_profile ON currency_ledger (profile_id, id);
+
+-- Существующие балансы записываются как начальные, чтобы сумма журнала совпадала с балансом.
+INSERT INTO currency_ledger (profile_id, currency, delta, balance_after, reason, source_type, source_id, created_at)
+SELECT id, 'piastres', piastres, piastres, 'Начальный баланс', 'system', 0, strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+FROM profiles WHERE COALESCE(piastres, 0) != 0;
+
+INSERT INTO currency_ledger (profile_id, currency, delta, balance_after, reason, source_type, source_id, created_at)
+SELECT id, 'oblomki', oblomki, oblomki, 'Начальный баланс', 'system', 0, strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+FROM profiles WHERE COALESCE(oblomki, 0) != 0;
+
+UPDATE profiles SET piastres = COALESCE(piastres, 0), oblomki = COALESCE(oblomki, 0);
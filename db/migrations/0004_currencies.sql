@@ -0,0 +1,35 @@
+-- Справочник валют и балансы по валютам. Балансы переносятся из столбцов
+-- profiles.piastres и profiles.oblomki, чтобы новая валюта не требовала изменения схемы.
+
+CREATE TABLE currencies (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    aliases TEXT NOT NULL DEFAULT '',
+    icon TEXT NOT NULL DEFAULT '',
+    retired INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL
+);
+
+INSERT INTO currencies (code, name, aliases, icon, retired, created_at) VALUES
+    ('piastres', 'Пиастры', 'пиастры,пиастр,пиастров', '🪙', 0, strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    ('oblomki', 'Обломки', 'обломки,обломок,обломков', '💠', 0, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+
+CREATE TABLE balances (
+    profile_id INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    amount INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (profile_id, currency)
+);
+
+INSERT INTO balances (profile_id, currency, amount)
+SELECT id, 'piastres', piastres FROM profiles WHERE COALESCE(piastres, 0) != 0;
+
+INSERT INTO balances (profile_id, currency, amount)
+SELECT id, 'oblomki', oblomki FROM profiles WHERE COALESCE(oblomki, 0) != 0;
+
+ALTER TABLE profiles DROP COLUMN piastres;
+ALTER TABLE profiles DROP COLUMN oblomki;
+
+-- События хранили валюту в том виде, в каком её ввёл администратор.
+UPDATE events SET currency_type = 'piastres' WHERE trim(currency_type) IN ('piastres', 'пиастры', 'пиastres');
+UPDATE events SET currency_type = 'oblomki' WHERE trim(currency_type) IN ('oblomki', 'обломки');
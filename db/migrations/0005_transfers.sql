@@ -0,0 +1,21 @@
+-- Переводы валюты между персонажами и настройки, которые меняет администратор.
+
+CREATE TABLE transfers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_profile_id INTEGER NOT NULL,
+    to_profile_id INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    fee INTEGER NOT NULL DEFAULT 0,
+    comment TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    completed_at DATETIME
+);
+
+CREATE INDEX idx_transfers_from ON transfers (from_profile_id, currency, status, completed_at);
+
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
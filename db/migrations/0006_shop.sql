@@ -0,0 +1,20 @@
+-- Магазин: товары, которые персонажи покупают за валюту.
+
+CREATE TABLE shop_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    stock INTEGER NOT NULL DEFAULT -1, -- -1 – без ограничения
+    rank TEXT NOT NULL DEFAULT '',     -- пустое значение – без ограничения
+    team TEXT NOT NULL DEFAULT '',
+    race TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+-- Цена товара: сумма в каждой из валют, все суммы списываются при покупке.
+CREATE TABLE shop_item_prices (
+    item_id INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    PRIMARY KEY (item_id, currency)
+);
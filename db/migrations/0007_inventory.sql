@@ -0,0 +1,30 @@
+-- Структурированный инвентарь: справочник предметов и предметы персонажей с количеством.
+-- Текстовое поле profiles.inventory сохраняется как заметка без предмета.
+
+CREATE TABLE items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE inventory (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    profile_id INTEGER NOT NULL,
+    item_id INTEGER,                 -- NULL – запись без предмета (только заметка)
+    quantity INTEGER NOT NULL DEFAULT 1,
+    note TEXT NOT NULL DEFAULT '',
+    UNIQUE (profile_id, item_id)
+);
+
+INSERT INTO inventory (profile_id, item_id, quantity, note)
+SELECT id, NULL, 1, trim(inventory) FROM profiles WHERE trim(COALESCE(inventory, '')) != '';
+
+ALTER TABLE profiles DROP COLUMN inventory;
+
+-- Товары магазина ссылаются на предметы справочника, которые получает покупатель.
+INSERT INTO items (name, description, created_at)
+SELECT name, MIN(description), MIN(created_at) FROM shop_items GROUP BY name;
+
+ALTER TABLE shop_items ADD COLUMN item_id INTEGER;
+UPDATE shop_items SET item_id = (SELECT id FROM items WHERE items.name = shop_items.name);
@@ -0,0 +1,29 @@
+-- Обмены между персонажами: сессия обмена и предложения каждой из сторон.
+-- Завершённые и отменённые обмены остаются в базе как журнал для администраторов.
+
+CREATE TABLE trades (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    initiator_id INTEGER NOT NULL,
+    partner_id INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    revision INTEGER NOT NULL DEFAULT 0,
+    initiator_confirmed INTEGER NOT NULL DEFAULT 0,
+    partner_confirmed INTEGER NOT NULL DEFAULT 0,
+    cancel_reason TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    closed_at DATETIME
+);
+
+CREATE INDEX idx_trades_initiator ON trades (initiator_id, status);
+CREATE INDEX idx_trades_partner ON trades (partner_id, status);
+
+-- Строка предложения: предмет (item_id) или валюта (currency) от одного из участников.
+CREATE TABLE trade_lines (
+    trade_id INTEGER NOT NULL,
+    profile_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    amount INTEGER NOT NULL,
+    PRIMARY KEY (trade_id, profile_id, item_id, currency)
+);
@@ -0,0 +1,45 @@
+-- Отложенные задачи планировщика. Задача определяется видом и ID объекта,
+-- к которому относится (например, закрытие аукциона), поэтому повторное
+-- планирование переносит существующую задачу, а не создаёт новую.
+CREATE TABLE jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    ref_id INTEGER NOT NULL,
+    run_at DATETIME NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    UNIQUE (kind, ref_id)
+);
+
+CREATE INDEX idx_jobs_due ON jobs (status, run_at);
+
+-- Аукционы. Пока аукцион открыт, лот (предмет продавца) и сумма лидирующей
+-- ставки (current_bid, списанная с leader_id) находятся на хранении у бота.
+CREATE TABLE auctions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    seller_id INTEGER NOT NULL DEFAULT 0, -- 0 – лот выставлен администратором
+    item_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    start_price INTEGER NOT NULL,
+    current_bid INTEGER NOT NULL DEFAULT 0,
+    leader_id INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    ends_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    closed_at DATETIME
+);
+
+CREATE INDEX idx_auctions_status ON auctions (status, ends_at);
+
+CREATE TABLE auction_bids (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    auction_id INTEGER NOT NULL,
+    profile_id INTEGER NOT NULL,
+    amount INTEGER NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_auction_bids_auction ON auction_bids (auction_id, id);
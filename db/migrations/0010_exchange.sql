@@ -0,0 +1,33 @@
+-- Обмен валют по курсу. Каждое изменение курса – новая строка, поэтому
+-- таблица одновременно хранит текущий курс (последняя строка пары) и историю.
+-- Курс: за from_amount единиц from_currency выдаётся to_amount единиц to_currency;
+-- to_amount = 0 означает, что обмен по этому направлению закрыт.
+CREATE TABLE exchange_rates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_currency TEXT NOT NULL,
+    to_currency TEXT NOT NULL,
+    from_amount INTEGER NOT NULL,
+    to_amount INTEGER NOT NULL,
+    set_by INTEGER NOT NULL, -- Telegram ID администратора
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_exchange_rates_pair ON exchange_rates (from_currency, to_currency, id);
+
+-- Обмены игроков: котировка сохраняется до подтверждения и выполняется
+-- только по тому курсу, который был показан.
+CREATE TABLE exchanges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    profile_id INTEGER NOT NULL,
+    rate_id INTEGER NOT NULL,
+    from_currency TEXT NOT NULL,
+    to_currency TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    received INTEGER NOT NULL,
+    fee INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    completed_at DATETIME
+);
+
+CREATE INDEX idx_exchanges_profile ON exchanges (profile_id, from_currency, status, completed_at);
@@ -0,0 +1,15 @@
+-- Стипендии: периодические начисления персонажам по рангу и/или команде.
+-- Пустые rank и team – без ограничения. Выплату выполняет планировщик
+-- (задача stipend.pay), next_run_at дублирует время задачи для вывода админам.
+CREATE TABLE stipends (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rank TEXT NOT NULL DEFAULT '',
+    team TEXT NOT NULL DEFAULT '',
+    currency TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    schedule TEXT NOT NULL, -- правило повторения, см. scheduler.ParseRecurrence
+    paused INTEGER NOT NULL DEFAULT 0,
+    next_run_at DATETIME NOT NULL,
+    last_run_at DATETIME,
+    created_at DATETIME NOT NULL
+);
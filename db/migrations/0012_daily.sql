@@ -0,0 +1,23 @@
+-- Ежедневная награда (/daily). Таблица наград: начиная с дня серии streak_day
+-- выдаётся amount валюты currency, пока не начнётся следующая ступень.
+-- У одной ступени может быть несколько валют.
+CREATE TABLE daily_rewards (
+    streak_day INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    PRIMARY KEY (streak_day, currency)
+);
+
+INSERT INTO daily_rewards (streak_day, currency, amount) VALUES
+    (1, 'piastres', 10),
+    (3, 'piastres', 15),
+    (7, 'piastres', 25);
+
+-- Серии ежедневных наград. last_day – игровые сутки последнего получения
+-- в формате YYYY-MM-DD (в часовом поясе из параметра timezone).
+CREATE TABLE daily_streaks (
+    profile_id INTEGER PRIMARY KEY,
+    streak INTEGER NOT NULL,
+    best_streak INTEGER NOT NULL,
+    last_day TEXT NOT NULL
+);
@@ -0,0 +1,4 @@
+-- Жизненный цикл событий: необязательное время автоматического закрытия
+-- и время фактического закрытия. NULL – без срока / ещё не закрыто.
+ALTER TABLE events ADD COLUMN ends_at DATETIME;
+ALTER TABLE events ADD COLUMN closed_at DATETIME;
@@ -0,0 +1,4 @@
+-- Запланированные события: время начала (NULL – событие открыто сразу)
+-- и за сколько минут до начала разослать напоминание (0 – без напоминания).
+ALTER TABLE events ADD COLUMN starts_at DATETIME;
+ALTER TABLE events ADD COLUMN remind_minutes INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+-- Коды отметки на событиях: секрет, из которого выводится код (пусто – код не нужен),
+-- и период смены кода в минутах (0 – код постоянный).
+ALTER TABLE events ADD COLUMN checkin_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN checkin_rotate_minutes INTEGER NOT NULL DEFAULT 0;
+
+-- Неверные коды: после нескольких ошибок подряд отметка блокируется на время.
+CREATE TABLE event_checkin_attempts (
+    event_id     INTEGER NOT NULL,
+    telegram_id  INTEGER NOT NULL,
+    failures     INTEGER NOT NULL DEFAULT 0,
+    locked_until DATETIME,
+    PRIMARY KEY (event_id, telegram_id)
+);
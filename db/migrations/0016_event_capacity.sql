@@ -0,0 +1,11 @@
+-- Ограничение мест на событии (0 – без ограничения) и лист ожидания:
+-- когда место освобождается, отмечается первый в очереди.
+ALTER TABLE events ADD COLUMN capacity INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE event_waitlist (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id    INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    created_at  DATETIME NOT NULL,
+    UNIQUE (event_id, telegram_id)
+);
@@ -0,0 +1,11 @@
+-- Разосланные объявления о событиях: бот редактирует их, показывая число
+-- участников, а при закрытии события убирает кнопки.
+CREATE TABLE event_messages (
+    event_id   INTEGER NOT NULL,
+    chat_id    INTEGER NOT NULL,
+    message_id INTEGER NOT NULL,
+    title      TEXT NOT NULL,
+    PRIMARY KEY (chat_id, message_id)
+);
+
+CREATE INDEX idx_event_messages_event ON event_messages (event_id);
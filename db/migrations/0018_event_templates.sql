@@ -0,0 +1,29 @@
+-- Шаблоны повторяющихся событий: по расписанию планировщик (задача
+-- eventtemplate.create) создаёт из шаблона обычное событие. next_run_at –
+-- время начала ближайшего ещё не созданного события.
+CREATE TABLE event_templates (
+    id                     INTEGER PRIMARY KEY AUTOINCREMENT,
+    name                   TEXT NOT NULL,
+    currency_type          TEXT NOT NULL,
+    amount                 INTEGER NOT NULL,
+    schedule               TEXT NOT NULL, -- правило повторения, см. scheduler.ParseRecurrence
+    duration_minutes       INTEGER NOT NULL,
+    remind_minutes         INTEGER NOT NULL DEFAULT 0,
+    checkin                INTEGER NOT NULL DEFAULT 0,
+    checkin_rotate_minutes INTEGER NOT NULL DEFAULT 0,
+    capacity               INTEGER NOT NULL DEFAULT 0,
+    paused                 INTEGER NOT NULL DEFAULT 0,
+    next_run_at            DATETIME NOT NULL,
+    last_event_id          INTEGER NOT NULL DEFAULT 0,
+    created_at             DATETIME NOT NULL
+);
+
+-- Пропускаемые повторы шаблона: событие на это время не создаётся.
+CREATE TABLE event_template_skips (
+    template_id INTEGER NOT NULL,
+    starts_at   DATETIME NOT NULL,
+    PRIMARY KEY (template_id, starts_at)
+);
+
+-- Шаблон, по которому создано событие; 0 – событие создано вручную.
+ALTER TABLE events ADD COLUMN template_id INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,27 @@
+-- Источники для сверки журнала: начисления администраторов записываются отдельно,
+-- а операции журнала ссылаются на них через source_id.
+CREATE TABLE admin_grants (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    profile_id INTEGER NOT NULL,
+    currency   TEXT NOT NULL,
+    amount     INTEGER NOT NULL,
+    admin_id   INTEGER NOT NULL,
+    reason     TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+-- Прежние начисления администраторов переносятся как есть: ID начисления совпадает
+-- с ID операции, а source_id операции вместо ID администратора – ID начисления.
+INSERT INTO admin_grants (id, profile_id, currency, amount, admin_id, reason, created_at)
+SELECT id, profile_id, currency, delta, source_id, reason, created_at
+FROM currency_ledger WHERE source_type = 'admin';
+
+UPDATE currency_ledger SET source_id = id WHERE source_type = 'admin';
+
+-- Награды за события, прошедшие до появления журнала, вошли в начальные балансы,
+-- поэтому такие события не сверяются с журналом.
+ALTER TABLE events ADD COLUMN pre_ledger INTEGER NOT NULL DEFAULT 0;
+
+UPDATE events SET pre_ledger = 1
+WHERE id IN (SELECT event_id FROM event_participation)
+  AND id NOT IN (SELECT source_id FROM currency_ledger WHERE source_type = 'event');
@@ -0,0 +1,6 @@
+-- Ключ названия предмета для поиска без учёта регистра: COLLATE NOCASE и lower()
+-- в SQLite приводят к нижнему регистру только латиницу, поэтому ключи заполняет
+-- и уникальный индекс idx_items_name_key создаёт шаг на Go fillItemNameKeys
+-- (db/inventory.go). Без него, например через sqlite3, миграция неполная:
+-- применяйте её только запуском бота.
+ALTER TABLE items ADD COLUMN name_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+-- Покупки в магазине: заказ создаётся при нажатии «Купить» или /buy и выполняется
+-- один раз после подтверждения по той цене, которая была показана.
+CREATE TABLE shop_orders (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    profile_id   INTEGER NOT NULL,
+    shop_item_id INTEGER NOT NULL,
+    price        TEXT NOT NULL, -- показанная цена, например "oblomki:2,piastres:10"
+    status       TEXT NOT NULL,
+    created_at   DATETIME NOT NULL,
+    completed_at DATETIME
+);
@@ -0,0 +1,21 @@
+-- Участие в событии привязывается к анкете, а не только к Telegram ID: после удаления
+-- анкеты и повторной регистрации прежние награды не должны сверяться с новой анкетой.
+ALTER TABLE event_participation ADD COLUMN profile_id INTEGER;
+
+UPDATE event_participation
+SET profile_id = (SELECT id FROM profiles p WHERE p.telegram_id = event_participation.telegram_id);
+
+-- Участие удалённой анкеты: нынешней анкете награда за событие не начислялась,
+-- а удалённой – начислялась.
+UPDATE event_participation SET profile_id = NULL
+WHERE profile_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM currency_ledger l
+                  WHERE l.source_type = 'event' AND l.source_id = event_participation.event_id
+                    AND l.profile_id = event_participation.profile_id)
+  AND EXISTS (SELECT 1 FROM currency_ledger l
+              WHERE l.source_type = 'event' AND l.source_id = event_participation.event_id
+                AND l.profile_id NOT IN (SELECT id FROM profiles));
+
+-- Участие и лист ожидания удалённых анкет удаляются, как теперь при удалении анкеты.
+DELETE FROM event_participation WHERE profile_id IS NULL;
+DELETE FROM event_waitlist WHERE telegram_id NOT IN (SELECT telegram_id FROM profiles);
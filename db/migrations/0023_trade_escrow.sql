@@ -0,0 +1,6 @@
+-- Предложения в обмене теперь забираются в залог при /offer. У обменов, открытых
+-- раньше, залога нет, поэтому они отменяются: предметы и валюта остались у участников.
+UPDATE trades
+SET status = 'cancelled', cancel_reason = 'обмен закрыт при обновлении бота',
+    updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'), closed_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE status = 'open';
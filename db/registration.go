@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// registrationMu сериализует чтение-изменение-запись черновиков регистрации
+// между обработчиками обновлений и фоновой очисткой.
+var registrationMu sync.Mutex
+
+// GetRegistrationState возвращает черновик регистрации пользователя
+// или sql.ErrNoRows, если регистрация не начата.
+func (s *SQLStore) GetRegistrationState(ctx context.Context, telegramID int64) (*models.RegistrationState, error) {
+	query := `
+SELECT telegram_id, chat_id, step, profile, updated_at, reminded
+FROM registration_states WHERE telegram_id = ?`
+	return scanRegistrationState(s.q.QueryRowContext(ctx, query, telegramID))
+}
+
+// SaveRegistrationState создаёт или обновляет черновик регистрации.
+// Время последнего ответа обновляется, напоминание сбрасывается.
+func (s *SQLStore) SaveRegistrationState(ctx context.Context, rs *models.RegistrationState) error {
+	profileJSON, err := json.Marshal(rs.Profile)
+	if err != nil {
+		return err
+	}
+	rs.UpdatedAt = time.Now()
+	rs.Reminded = false
+	query := `
+INSERT INTO registration_states (telegram_id, chat_id, step, profile, updated_at, reminded)
+VALUES (?, ?, ?, ?, ?, 0)
+ON CONFLICT(telegram_id) DO UPDATE SET
+    chat_id = excluded.chat_id, step = excluded.step, profile = excluded.profile,
+    updated_at = excluded.updated_at, reminded = 0`
+	registrationMu.Lock()
+	defer registrationMu.Unlock()
+	_, err = s.q.ExecContext(ctx, query, rs.TelegramID, rs.ChatID, rs.CurrentStep, string(profileJSON), rs.UpdatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// DeleteRegistrationState удаляет черновик регистрации.
+// Возвращает false, если черновика не было.
+func (s *SQLStore) DeleteRegistrationState(ctx context.Context, telegramID int64) (bool, error) {
+	registrationMu.Lock()
+	defer registrationMu.Unlock()
+	res, err := s.q.ExecContext(ctx, "DELETE FROM registration_states WHERE telegram_id = ?", telegramID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ExpireRegistrationStates в одной транзакции удаляет черновики, не обновлявшиеся
+// с момента expireBefore, и помечает напомненными те, что не обновлялись с remindBefore.
+// Возвращает удалённые черновики и черновики, по которым нужно отправить напоминание.
+func (s *SQLStore) ExpireRegistrationStates(ctx context.Context, expireBefore, remindBefore time.Time) (expired, remind []*models.RegistrationState, err error) {
+	registrationMu.Lock()
+	defer registrationMu.Unlock()
+
+	expireAt := expireBefore.UTC().Format(time.RFC3339)
+	remindAt := remindBefore.UTC().Format(time.RFC3339)
+	err = s.inTx(ctx, func(t *SQLStore) error {
+		q := t.q
+		var err error
+		expired, err = queryRegistrationStates(ctx, q, `
+SELECT telegram_id, chat_id, step, profile, updated_at, reminded
+FROM registration_states WHERE updated_at < ?`, expireAt)
+		if err != nil {
+			return err
+		}
+		if _, err = q.ExecContext(ctx, "DELETE FROM registration_states WHERE updated_at < ?", expireAt); err != nil {
+			return err
+		}
+
+		remind, err = queryRegistrationStates(ctx, q, `
+SELECT telegram_id, chat_id, step, profile, updated_at, reminded
+FROM registration_states WHERE updated_at < ? AND reminded = 0`, remindAt)
+		if err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, "UPDATE registration_states SET reminded = 1 WHERE updated_at < ? AND reminded = 0", remindAt)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return expired, remind, nil
+}
+
+// queryRegistrationStates выполняет выборку черновиков.
+func queryRegistrationStates(ctx context.Context, q querier, query string, args ...any) ([]*models.RegistrationState, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var states []*models.RegistrationState
+	for rows.Next() {
+		s, err := scanRegistrationState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+	}
+	return states, rows.Err()
+}
+
+// scanRegistrationState читает черновик из строки результата.
+func scanRegistrationState(row scanner) (*models.RegistrationState, error) {
+	var s models.RegistrationState
+	var profileJSON, updatedAtStr string
+	var remindedInt int
+	err := row.Scan(&s.TelegramID, &s.ChatID, &s.CurrentStep, &profileJSON, &updatedAtStr, &remindedInt)
+	if err != nil {
+		return nil, err
+	}
+	s.Profile = &models.Profile{}
+	if err := json.Unmarshal([]byte(profileJSON), s.Profile); err != nil {
+		return nil, errors.Join(errors.New("повреждённый черновик регистрации"), err)
+	}
+	s.UpdatedAt, err = time.Parse(time.RFC3339, updatedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	s.Reminded = remindedInt == 1
+	return &s, nil
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// GetSetting возвращает значение настройки или "", если она не задана.
+func (s *SQLStore) GetSetting(ctx context.Context, key string) (string, error) {
+	var value string
+	err := s.q.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetSetting сохраняет значение настройки.
+func (s *SQLStore) SetSetting(ctx context.Context, key, value string) error {
+	_, err := s.q.ExecContext(ctx, `
+INSERT INTO settings (key, value) VALUES (?, ?)
+ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// CreateShopItem добавляет товар вместе с ценами и заполняет item.ID.
+func (s *SQLStore) CreateShopItem(ctx context.Context, item *models.ShopItem) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		item.CreatedAt = time.Now()
+		res, err := t.q.ExecContext(ctx, `
+INSERT INTO shop_items (item_id, name, description, stock, rank, team, race, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.ItemID, item.Name, item.Description, item.Stock, item.Rank, item.Team, item.Race,
+			item.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		item.ID = int(id)
+		return t.savePrices(ctx, item)
+	})
+}
+
+// UpdateShopItem сохраняет все поля товара и заменяет его цены.
+func (s *SQLStore) UpdateShopItem(ctx context.Context, item *models.ShopItem) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		res, err := t.q.ExecContext(ctx, `
+UPDATE shop_items SET item_id = ?, name = ?, description = ?, stock = ?, rank = ?, team = ?, race = ?
+WHERE id = ?`,
+			item.ItemID, item.Name, item.Description, item.Stock, item.Rank, item.Team, item.Race, item.ID)
+		if err != nil {
+			return err
+		}
+		if rows, err := res.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return errors.New("товар не найден")
+		}
+		return t.savePrices(ctx, item)
+	})
+}
+
+// savePrices заменяет цены товара.
+func (s *SQLStore) savePrices(ctx context.Context, item *models.ShopItem) error {
+	if _, err := s.q.ExecContext(ctx, "DELETE FROM shop_item_prices WHERE item_id = ?", item.ID); err != nil {
+		return err
+	}
+	for _, p := range item.Prices {
+		_, err := s.q.ExecContext(ctx, "INSERT INTO shop_item_prices (item_id, currency, amount) VALUES (?, ?, ?)",
+			item.ID, p.Currency, p.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteShopItem удаляет товар из каталога.
+func (s *SQLStore) DeleteShopItem(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(t *SQLStore) error {
+		if _, err := t.q.ExecContext(ctx, "DELETE FROM shop_item_prices WHERE item_id = ?", id); err != nil {
+			return err
+		}
+		res, err := t.q.ExecContext(ctx, "DELETE FROM shop_items WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errors.New("товар не найден")
+		}
+		return nil
+	})
+}
+
+// GetShopItem возвращает товар по ID или sql.ErrNoRows.
+func (s *SQLStore) GetShopItem(ctx context.Context, id int) (*models.ShopItem, error) {
+	query := `
+SELECT id, COALESCE(item_id, 0), name, description, stock, rank, team, race, created_at
+FROM shop_items WHERE id = ?`
+	item, err := scanShopItem(s.q.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadPrices(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ListShopItems возвращает все товары по порядку добавления.
+func (s *SQLStore) ListShopItems(ctx context.Context) ([]*models.ShopItem, error) {
+	query := `
+SELECT id, COALESCE(item_id, 0), name, description, stock, rank, team, race, created_at
+FROM shop_items ORDER BY id`
+	rows, err := s.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*models.ShopItem
+	for rows.Next() {
+		item, err := scanShopItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := s.loadPrices(ctx, items...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// loadPrices заполняет цены товаров.
+func (s *SQLStore) loadPrices(ctx context.Context, items ...*models.ShopItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.ShopItem, len(items))
+	for _, item := range items {
+		item.Prices = nil
+		byID[item.ID] = item
+	}
+	itemID := 0
+	if len(items) == 1 {
+		itemID = items[0].ID
+	}
+	query := `
+SELECT p.item_id, p.currency, p.amount
+FROM shop_item_prices p LEFT JOIN currencies c ON c.code = p.currency
+WHERE ? = 0 OR p.item_id = ?
+ORDER BY p.item_id, c.rowid`
+	rows, err := s.q.QueryContext(ctx, query, itemID, itemID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var p models.Price
+		if err := rows.Scan(&id, &p.Currency, &p.Amount); err != nil {
+			return err
+		}
+		if item, ok := byID[id]; ok {
+			item.Prices = append(item.Prices, p)
+		}
+	}
+	return rows.Err()
+}
+
+// scanShopItem читает товар (без цен) из строки результата.
+func scanShopItem(row scanner) (*models.ShopItem, error) {
+	var item models.ShopItem
+	var createdAtStr string
+	err := row.Scan(&item.ID, &item.ItemID, &item.Name, &item.Description, &item.Stock,
+		&item.Rank, &item.Team, &item.Race, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	item.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateShopOrder сохраняет заказ в магазине и заполняет o.ID и o.CreatedAt.
+func (s *SQLStore) CreateShopOrder(ctx context.Context, o *models.ShopOrder) error {
+	o.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO shop_orders (profile_id, shop_item_id, price, status, created_at) VALUES (?, ?, ?, ?, ?)`,
+		o.ProfileID, o.ShopItemID, o.Price, o.Status, o.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	o.ID = int(id)
+	return nil
+}
+
+// GetShopOrder возвращает заказ в магазине по ID или sql.ErrNoRows.
+func (s *SQLStore) GetShopOrder(ctx context.Context, id int) (*models.ShopOrder, error) {
+	query := `
+SELECT id, profile_id, shop_item_id, price, status, created_at, completed_at
+FROM shop_orders WHERE id = ?`
+	var o models.ShopOrder
+	var createdAtStr string
+	var completedAtStr sql.NullString
+	err := s.q.QueryRowContext(ctx, query, id).Scan(&o.ID, &o.ProfileID, &o.ShopItemID, &o.Price, &o.Status,
+		&createdAtStr, &completedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	if o.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	if completedAtStr.Valid {
+		if o.CompletedAt, err = time.Parse(time.RFC3339, completedAtStr.String); err != nil {
+			return nil, err
+		}
+	}
+	return &o, nil
+}
+
+// UpdateShopOrder сохраняет статус и время выполнения заказа.
+func (s *SQLStore) UpdateShopOrder(ctx context.Context, o *models.ShopOrder) error {
+	var completedAt any
+	if !o.CompletedAt.IsZero() {
+		completedAt = o.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	_, err := s.q.ExecContext(ctx, "UPDATE shop_orders SET status = ?, completed_at = ? WHERE id = ?",
+		o.Status, completedAt, o.ID)
+	return err
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"telegram-bot/models"
+)
+
+const stipendColumns = "id, rank, team, currency, amount, schedule, paused, next_run_at, last_run_at, created_at"
+
+// CreateStipend сохраняет новую стипендию и заполняет s.ID и s.CreatedAt.
+func (s *SQLStore) CreateStipend(ctx context.Context, st *models.Stipend) error {
+	st.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO stipends (rank, team, currency, amount, schedule, paused, next_run_at, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		st.Rank, st.Team, st.Currency, st.Amount, st.Schedule, boolToInt(st.Paused),
+		st.NextRunAt.UTC().Format(time.RFC3339), st.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	st.ID = int(id)
+	return nil
+}
+
+// GetStipend возвращает стипендию по ID или sql.ErrNoRows.
+func (s *SQLStore) GetStipend(ctx context.Context, id int) (*models.Stipend, error) {
+	return scanStipend(s.q.QueryRowContext(ctx, "SELECT "+stipendColumns+" FROM stipends WHERE id = ?", id))
+}
+
+// UpdateStipend сохраняет паузу и время выплат стипендии.
+func (s *SQLStore) UpdateStipend(ctx context.Context, st *models.Stipend) error {
+	var lastRunAt any
+	if !st.LastRunAt.IsZero() {
+		lastRunAt = st.LastRunAt.UTC().Format(time.RFC3339)
+	}
+	_, err := s.q.ExecContext(ctx, "UPDATE stipends SET paused = ?, next_run_at = ?, last_run_at = ? WHERE id = ?",
+		boolToInt(st.Paused), st.NextRunAt.UTC().Format(time.RFC3339), lastRunAt, st.ID)
+	return err
+}
+
+// DeleteStipend удаляет стипендию; sql.ErrNoRows, если её нет.
+func (s *SQLStore) DeleteStipend(ctx context.Context, id int) error {
+	res, err := s.q.ExecContext(ctx, "DELETE FROM stipends WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListStipends возвращает все стипендии по порядку добавления.
+func (s *SQLStore) ListStipends(ctx context.Context) ([]*models.Stipend, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT "+stipendColumns+" FROM stipends ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var stipends []*models.Stipend
+	for rows.Next() {
+		st, err := scanStipend(rows)
+		if err != nil {
+			return nil, err
+		}
+		stipends = append(stipends, st)
+	}
+	return stipends, rows.Err()
+}
+
+func scanStipend(row scanner) (*models.Stipend, error) {
+	var st models.Stipend
+	var paused int
+	var nextRunAtStr, createdAtStr string
+	var lastRunAtStr sql.NullString
+	err := row.Scan(&st.ID, &st.Rank, &st.Team, &st.Currency, &st.Amount, &st.Schedule, &paused,
+		&nextRunAtStr, &lastRunAtStr, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	st.Paused = paused != 0
+	if st.NextRunAt, err = time.Parse(time.RFC3339, nextRunAtStr); err != nil {
+		return nil, err
+	}
+	if st.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	if lastRunAtStr.Valid {
+		if st.LastRunAt, err = time.Parse(time.RFC3339, lastRunAtStr.String); err != nil {
+			return nil, err
+		}
+	}
+	return &st, nil
+}
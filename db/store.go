@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// Store – доступ к данным бота. Все методы принимают контекст; несколько
+// операций можно объединить в одну транзакцию через WithTx.
+type Store interface {
+	// Профили
+	GetProfile(ctx context.Context, telegramID int64) (*models.Profile, error)
+	GetProfileByID(ctx context.Context, id int) (*models.Profile, error)
+	GetProfileByUsername(ctx context.Context, username string) (*models.Profile, error)
+	CreateProfile(ctx context.Context, p *models.Profile) error
+	UpdateProfile(ctx context.Context, p *models.Profile) error
+	SaveProfile(ctx context.Context, p *models.Profile) error
+	GetAllProfiles(ctx context.Context) ([]*models.Profile, error)
+	DeleteProfile(ctx context.Context, telegramID int64) error
+	DeleteProfileByID(ctx context.Context, id int) error
+
+	// Справочник валют
+	ListCurrencies(ctx context.Context, includeRetired bool) ([]*models.Currency, error)
+	GetCurrency(ctx context.Context, code string) (*models.Currency, error)
+	ResolveCurrency(ctx context.Context, name string) (*models.Currency, error)
+	CreateCurrency(ctx context.Context, c *models.Currency) error
+	UpdateCurrency(ctx context.Context, c *models.Currency) error
+
+	// Баланс и журнал операций с валютой
+	ChangeBalance(ctx context.Context, e *models.LedgerEntry) error
+	GetLedger(ctx context.Context, profileID, limit int) ([]*models.LedgerEntry, error)
+	CreateAdminGrant(ctx context.Context, g *models.AdminGrant) error
+	LedgerDrifts(ctx context.Context) ([]models.LedgerDrift, error)
+	CorrectBalance(ctx context.Context, e *models.LedgerEntry) error
+
+	// Переводы между персонажами
+	CreateTransfer(ctx context.Context, t *models.Transfer) error
+	GetTransfer(ctx context.Context, id int) (*models.Transfer, error)
+	UpdateTransfer(ctx context.Context, t *models.Transfer) error
+	TransferredSince(ctx context.Context, fromProfileID int, currency string, since time.Time) (int, error)
+
+	// Справочник предметов и инвентарь
+	CreateItem(ctx context.Context, item *models.Item) error
+	GetItem(ctx context.Context, id int) (*models.Item, error)
+	GetItemByName(ctx context.Context, name string) (*models.Item, error)
+	ListItems(ctx context.Context) ([]*models.Item, error)
+	AddInventoryItem(ctx context.Context, profileID, itemID, quantity int, note string) error
+	AddInventoryNote(ctx context.Context, profileID int, note string) error
+	RemoveInventoryItem(ctx context.Context, profileID, itemID, quantity int) (int, error)
+	DeleteInventoryEntry(ctx context.Context, profileID, entryID int) error
+
+	// Магазин
+	CreateShopItem(ctx context.Context, item *models.ShopItem) error
+	GetShopItem(ctx context.Context, id int) (*models.ShopItem, error)
+	UpdateShopItem(ctx context.Context, item *models.ShopItem) error
+	DeleteShopItem(ctx context.Context, id int) error
+	ListShopItems(ctx context.Context) ([]*models.ShopItem, error)
+	CreateShopOrder(ctx context.Context, o *models.ShopOrder) error
+	GetShopOrder(ctx context.Context, id int) (*models.ShopOrder, error)
+	UpdateShopOrder(ctx context.Context, o *models.ShopOrder) error
+
+	// Обмены между персонажами
+	CreateTrade(ctx context.Context, t *models.Trade) error
+	GetTrade(ctx context.Context, id int) (*models.Trade, error)
+	GetOpenTrade(ctx context.Context, profileID int) (*models.Trade, error)
+	UpdateTrade(ctx context.Context, t *models.Trade) error
+	SetTradeLine(ctx context.Context, tradeID int, line models.TradeLine) error
+	ListTrades(ctx context.Context, profileID, limit int) ([]*models.Trade, error)
+
+	// Обмен валют по курсу
+	SetExchangeRate(ctx context.Context, r *models.ExchangeRate) error
+	GetExchangeRate(ctx context.Context, from, to string) (*models.ExchangeRate, error)
+	ListExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error)
+	ExchangeRateHistory(ctx context.Context, from, to string, limit int) ([]*models.ExchangeRate, error)
+	CreateExchange(ctx context.Context, e *models.Exchange) error
+	GetExchange(ctx context.Context, id int) (*models.Exchange, error)
+	UpdateExchange(ctx context.Context, e *models.Exchange) error
+	ExchangedSince(ctx context.Context, profileID int, from string, since time.Time) (int, error)
+
+	// Аукционы
+	CreateAuction(ctx context.Context, a *models.Auction) error
+	GetAuction(ctx context.Context, id int) (*models.Auction, error)
+	UpdateAuction(ctx context.Context, a *models.Auction) error
+	ListAuctions(ctx context.Context, openOnly bool, limit int) ([]*models.Auction, error)
+	AddBid(ctx context.Context, b *models.Bid) error
+	ListBids(ctx context.Context, auctionID int) ([]*models.Bid, error)
+
+	// Ежедневные награды
+	ListDailyRewards(ctx context.Context) ([]models.DailyReward, error)
+	SetDailyReward(ctx context.Context, r models.DailyReward) error
+	GetDailyStreak(ctx context.Context, profileID int) (*models.DailyStreak, error)
+	SaveDailyStreak(ctx context.Context, ds *models.DailyStreak) error
+
+	// Стипендии
+	CreateStipend(ctx context.Context, st *models.Stipend) error
+	GetStipend(ctx context.Context, id int) (*models.Stipend, error)
+	UpdateStipend(ctx context.Context, st *models.Stipend) error
+	DeleteStipend(ctx context.Context, id int) error
+	ListStipends(ctx context.Context) ([]*models.Stipend, error)
+
+	// Отложенные задачи планировщика
+	ScheduleJob(ctx context.Context, kind string, refID int64, runAt time.Time) error
+	ScheduleJobOnce(ctx context.Context, kind string, refID int64, runAt time.Time) error
+	CancelJob(ctx context.Context, kind string, refID int64) error
+	DueJobs(ctx context.Context, now time.Time, limit int) ([]*models.Job, error)
+	CompleteJob(ctx context.Context, job *models.Job) error
+	RetryJob(ctx context.Context, job *models.Job, nextRunAt time.Time, lastError string) error
+
+	// Настройки, которые меняет администратор
+	GetSetting(ctx context.Context, key string) (string, error)
+	SetSetting(ctx context.Context, key, value string) error
+
+	// События и участие
+	CreateEvent(ctx context.Context, e *models.Event) error
+	GetEventByID(ctx context.Context, id int) (*models.Event, error)
+	UpdateEvent(ctx context.Context, e *models.Event) error
+	GetActiveEvents(ctx context.Context) ([]*models.Event, error)
+	ListEvents(ctx context.Context, limit int) ([]*models.Event, error)
+	EventStats(ctx context.Context, eventID int) (participants, paid int, err error)
+	UserParticipatedInEvent(ctx context.Context, eventID int, telegramID int64) (bool, error)
+	AddEventParticipation(ctx context.Context, eventID int, telegramID int64) error
+	RemoveEventParticipation(ctx context.Context, eventID int, telegramID int64) error
+	GetCheckinAttempts(ctx context.Context, eventID int, telegramID int64) (*models.CheckinAttempts, error)
+	AddCheckinFailure(ctx context.Context, eventID int, telegramID int64) (int, error)
+	LockCheckinAttempts(ctx context.Context, eventID int, telegramID int64, until time.Time) error
+	ResetCheckinAttempts(ctx context.Context, eventID int, telegramID int64) error
+	CountEventParticipants(ctx context.Context, eventID int) (int, error)
+	AddToWaitlist(ctx context.Context, eventID int, telegramID int64) (int, error)
+	WaitlistPosition(ctx context.Context, eventID int, telegramID int64) (int, error)
+	WaitlistLength(ctx context.Context, eventID int) (int, error)
+	PopWaitlist(ctx context.Context, eventID int) (int64, error)
+	RemoveFromWaitlist(ctx context.Context, eventID int, telegramID int64) error
+	SaveEventMessage(ctx context.Context, m *models.EventMessage) error
+	GetEventMessage(ctx context.Context, chatID int64, messageID int) (*models.EventMessage, error)
+	ListEventMessages(ctx context.Context, eventID int) ([]*models.EventMessage, error)
+	DeleteEventMessages(ctx context.Context, eventID int) error
+
+	// Шаблоны повторяющихся событий
+	CreateEventTemplate(ctx context.Context, t *models.EventTemplate) error
+	GetEventTemplate(ctx context.Context, id int) (*models.EventTemplate, error)
+	UpdateEventTemplate(ctx context.Context, t *models.EventTemplate) error
+	DeleteEventTemplate(ctx context.Context, id int) error
+	ListEventTemplates(ctx context.Context) ([]*models.EventTemplate, error)
+	SkipOccurrence(ctx context.Context, templateID int, startsAt time.Time) error
+	UnskipOccurrence(ctx context.Context, templateID int, startsAt time.Time) error
+	ListSkippedOccurrences(ctx context.Context, templateID int) ([]time.Time, error)
+
+	// Черновики регистрации
+	GetRegistrationState(ctx context.Context, telegramID int64) (*models.RegistrationState, error)
+	SaveRegistrationState(ctx context.Context, s *models.RegistrationState) error
+	DeleteRegistrationState(ctx context.Context, telegramID int64) (bool, error)
+	ExpireRegistrationStates(ctx context.Context, expireBefore, remindBefore time.Time) (expired, remind []*models.RegistrationState, err error)
+
+	// WithTx выполняет fn в транзакции: если fn вернула ошибку, все изменения
+	// откатываются. Внутри fn нужно использовать переданный tx, а не исходный Store.
+	// Вложенный WithTx выполняется в рамках внешней транзакции.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+// querier – общий интерфейс *sql.DB и *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanner – общий интерфейс *sql.Row и *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// SQLStore – реализация Store поверх SQLite.
+type SQLStore struct {
+	db *sql.DB
+	q  querier // db или открытая транзакция
+	tx *sql.Tx
+}
+
+// NewStore создаёт Store поверх открытой базы.
+func NewStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, q: db}
+}
+
+// WithTx выполняет fn в транзакции.
+func (s *SQLStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return s.inTx(ctx, func(t *SQLStore) error { return fn(t) })
+}
+
+// inTx – WithTx для внутренних методов, которым нужен доступ к querier.
+func (s *SQLStore) inTx(ctx context.Context, fn func(t *SQLStore) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&SQLStore{db: s.db, q: tx, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close закрывает соединение с базой данных.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"telegram-bot/models"
+)
+
+const tradeColumns = `id, initiator_id, partner_id, status, revision, initiator_confirmed, partner_confirmed,
+cancel_reason, created_at, updated_at, closed_at`
+
+// CreateTrade сохраняет новый обмен и заполняет t.ID, t.CreatedAt и t.UpdatedAt.
+func (s *SQLStore) CreateTrade(ctx context.Context, t *models.Trade) error {
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+	now := t.CreatedAt.UTC().Format(time.RFC3339)
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO trades (initiator_id, partner_id, status, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?)`,
+		t.InitiatorID, t.PartnerID, t.Status, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	t.ID = int(id)
+	return nil
+}
+
+// GetTrade возвращает обмен вместе с предложениями или sql.ErrNoRows.
+func (s *SQLStore) GetTrade(ctx context.Context, id int) (*models.Trade, error) {
+	t, err := scanTrade(s.q.QueryRowContext(ctx, "SELECT "+tradeColumns+" FROM trades WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadTradeLines(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetOpenTrade возвращает открытый обмен, в котором участвует профиль, или sql.ErrNoRows.
+func (s *SQLStore) GetOpenTrade(ctx context.Context, profileID int) (*models.Trade, error) {
+	query := "SELECT " + tradeColumns + ` FROM trades
+WHERE status = ? AND (initiator_id = ? OR partner_id = ?)
+ORDER BY id DESC LIMIT 1`
+	t, err := scanTrade(s.q.QueryRowContext(ctx, query, models.TradeOpen, profileID, profileID))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadTradeLines(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// UpdateTrade сохраняет статус, ревизию, подтверждения и причину отмены обмена
+// и обновляет t.UpdatedAt.
+func (s *SQLStore) UpdateTrade(ctx context.Context, t *models.Trade) error {
+	t.UpdatedAt = time.Now()
+	var closedAt any
+	if !t.ClosedAt.IsZero() {
+		closedAt = t.ClosedAt.UTC().Format(time.RFC3339)
+	}
+	_, err := s.q.ExecContext(ctx, `
+UPDATE trades SET status = ?, revision = ?, initiator_confirmed = ?, partner_confirmed = ?, cancel_reason = ?,
+    updated_at = ?, closed_at = ?
+WHERE id = ?`,
+		t.Status, t.Revision, boolToInt(t.InitiatorConfirmed), boolToInt(t.PartnerConfirmed), t.CancelReason,
+		t.UpdatedAt.UTC().Format(time.RFC3339), closedAt, t.ID)
+	return err
+}
+
+// SetTradeLine задаёт количество предмета или валюты, которое участник отдаёт в обмене.
+// Нулевое количество убирает строку из предложения.
+func (s *SQLStore) SetTradeLine(ctx context.Context, tradeID int, line models.TradeLine) error {
+	if line.Amount <= 0 {
+		_, err := s.q.ExecContext(ctx, `
+DELETE FROM trade_lines WHERE trade_id = ? AND profile_id = ? AND item_id = ? AND currency = ?`,
+			tradeID, line.ProfileID, line.ItemID, line.Currency)
+		return err
+	}
+	_, err := s.q.ExecContext(ctx, `
+INSERT INTO trade_lines (trade_id, profile_id, item_id, currency, amount) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (trade_id, profile_id, item_id, currency) DO UPDATE SET amount = excluded.amount`,
+		tradeID, line.ProfileID, line.ItemID, line.Currency, line.Amount)
+	return err
+}
+
+// ListTrades возвращает последние limit обменов (новые первыми).
+// Если profileID не равен 0 – только обмены с участием этого профиля.
+func (s *SQLStore) ListTrades(ctx context.Context, profileID, limit int) ([]*models.Trade, error) {
+	query := "SELECT " + tradeColumns + ` FROM trades
+WHERE ? = 0 OR initiator_id = ? OR partner_id = ?
+ORDER BY id DESC LIMIT ?`
+	rows, err := s.q.QueryContext(ctx, query, profileID, profileID, profileID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var trades []*models.Trade
+	for rows.Next() {
+		t, err := scanTrade(rows)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, t)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := s.loadTradeLines(ctx, trades...); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// loadTradeLines заполняет предложения обменов: сначала валюты, затем предметы по названию.
+func (s *SQLStore) loadTradeLines(ctx context.Context, trades ...*models.Trade) error {
+	for _, t := range trades {
+		t.Lines = nil
+		rows, err := s.q.QueryContext(ctx, `
+SELECT l.profile_id, l.item_id, COALESCE(i.name, ''), l.currency, l.amount
+FROM trade_lines l
+LEFT JOIN items i ON i.id = l.item_id
+LEFT JOIN currencies c ON c.code = l.currency
+WHERE l.trade_id = ?
+ORDER BY l.profile_id, l.item_id != 0, c.rowid, i.name`, t.ID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var line models.TradeLine
+			if err := rows.Scan(&line.ProfileID, &line.ItemID, &line.ItemName, &line.Currency, &line.Amount); err != nil {
+				rows.Close()
+				return err
+			}
+			t.Lines = append(t.Lines, line)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanTrade читает обмен (без предложений) из строки результата.
+func scanTrade(row scanner) (*models.Trade, error) {
+	var t models.Trade
+	var initiatorConfirmed, partnerConfirmed int
+	var createdAtStr, updatedAtStr string
+	var closedAtStr sql.NullString
+	err := row.Scan(&t.ID, &t.InitiatorID, &t.PartnerID, &t.Status, &t.Revision, &initiatorConfirmed, &partnerConfirmed,
+		&t.CancelReason, &createdAtStr, &updatedAtStr, &closedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	t.InitiatorConfirmed = initiatorConfirmed != 0
+	t.PartnerConfirmed = partnerConfirmed != 0
+	if t.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr); err != nil {
+		return nil, err
+	}
+	if t.UpdatedAt, err = time.Parse(time.RFC3339, updatedAtStr); err != nil {
+		return nil, err
+	}
+	if closedAtStr.Valid {
+		if t.ClosedAt, err = time.Parse(time.RFC3339, closedAtStr.String); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
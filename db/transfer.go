@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// CreateTransfer сохраняет новый перевод и заполняет t.ID и t.CreatedAt.
+func (s *SQLStore) CreateTransfer(ctx context.Context, t *models.Transfer) error {
+	t.CreatedAt = time.Now()
+	res, err := s.q.ExecContext(ctx, `
+INSERT INTO transfers (from_profile_id, to_profile_id, currency, amount, fee, comment, status, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.FromProfileID, t.ToProfileID, t.Currency, t.Amount, t.Fee, t.Comment, t.Status,
+		t.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	t.ID = int(id)
+	return nil
+}
+
+// GetTransfer возвращает перевод по ID или sql.ErrNoRows.
+func (s *SQLStore) GetTransfer(ctx context.Context, id int) (*models.Transfer, error) {
+	query := `
+SELECT id, from_profile_id, to_profile_id, currency, amount, fee, comment, status, created_at, completed_at
+FROM transfers WHERE id = ?`
+	var t models.Transfer
+	var createdAtStr string
+	var completedAtStr sql.NullString
+	err := s.q.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.FromProfileID, &t.ToProfileID, &t.Currency,
+		&t.Amount, &t.Fee, &t.Comment, &t.Status, &createdAtStr, &completedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	t.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	if completedAtStr.Valid {
+		t.CompletedAt, err = time.Parse(time.RFC3339, completedAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// UpdateTransfer сохраняет статус, комиссию и время выполнения перевода.
+func (s *SQLStore) UpdateTransfer(ctx context.Context, t *models.Transfer) error {
+	var completedAt any
+	if !t.CompletedAt.IsZero() {
+		completedAt = t.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	_, err := s.q.ExecContext(ctx, "UPDATE transfers SET status = ?, fee = ?, completed_at = ? WHERE id = ?",
+		t.Status, t.Fee, completedAt, t.ID)
+	return err
+}
+
+// TransferredSince возвращает сумму выполненных переводов профиля в валюте
+// начиная с момента since (без комиссии).
+func (s *SQLStore) TransferredSince(ctx context.Context, fromProfileID int, currency string, since time.Time) (int, error) {
+	query := `
+SELECT COALESCE(SUM(amount), 0) FROM transfers
+WHERE from_profile_id = ? AND currency = ? AND status = ? AND completed_at >= ?`
+	var total int
+	err := s.q.QueryRowContext(ctx, query, fromProfileID, currency, models.TransferCompleted,
+		since.UTC().Format(time.RFC3339)).Scan(&total)
+	return total, err
+}
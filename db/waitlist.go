@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CountEventParticipants возвращает число участников события.
+func (s *SQLStore) CountEventParticipants(ctx context.Context, eventID int) (int, error) {
+	var n int
+	err := s.q.QueryRowContext(ctx, "SELECT COUNT(*) FROM event_participation WHERE event_id = ?", eventID).Scan(&n)
+	return n, err
+}
+
+// AddToWaitlist ставит пользователя в конец листа ожидания события и возвращает его место в очереди.
+func (s *SQLStore) AddToWaitlist(ctx context.Context, eventID int, telegramID int64) (int, error) {
+	_, err := s.q.ExecContext(ctx, "INSERT INTO event_waitlist (event_id, telegram_id, created_at) VALUES (?, ?, ?)",
+		eventID, telegramID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return s.WaitlistPosition(ctx, eventID, telegramID)
+}
+
+// WaitlistPosition возвращает место пользователя в листе ожидания (с 1) или 0, если его там нет.
+func (s *SQLStore) WaitlistPosition(ctx context.Context, eventID int, telegramID int64) (int, error) {
+	var pos int
+	err := s.q.QueryRowContext(ctx, `
+SELECT CASE WHEN EXISTS (SELECT 1 FROM event_waitlist WHERE event_id = ?1 AND telegram_id = ?2)
+    THEN (SELECT COUNT(*) FROM event_waitlist WHERE event_id = ?1
+          AND id <= (SELECT id FROM event_waitlist WHERE event_id = ?1 AND telegram_id = ?2))
+    ELSE 0 END`, eventID, telegramID).Scan(&pos)
+	return pos, err
+}
+
+// WaitlistLength возвращает число пользователей в листе ожидания события.
+func (s *SQLStore) WaitlistLength(ctx context.Context, eventID int) (int, error) {
+	var n int
+	err := s.q.QueryRowContext(ctx, "SELECT COUNT(*) FROM event_waitlist WHERE event_id = ?", eventID).Scan(&n)
+	return n, err
+}
+
+// PopWaitlist убирает из листа ожидания первого в очереди и возвращает его Telegram ID;
+// sql.ErrNoRows, если лист пуст.
+func (s *SQLStore) PopWaitlist(ctx context.Context, eventID int) (int64, error) {
+	var telegramID int64
+	err := s.q.QueryRowContext(ctx, `
+DELETE FROM event_waitlist
+WHERE id = (SELECT MIN(id) FROM event_waitlist WHERE event_id = ?)
+RETURNING telegram_id`, eventID).Scan(&telegramID)
+	return telegramID, err
+}
+
+// RemoveFromWaitlist убирает пользователя из листа ожидания; sql.ErrNoRows, если его там нет.
+func (s *SQLStore) RemoveFromWaitlist(ctx context.Context, eventID int, telegramID int64) error {
+	res, err := s.q.ExecContext(ctx, "DELETE FROM event_waitlist WHERE event_id = ? AND telegram_id = ?", eventID, telegramID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
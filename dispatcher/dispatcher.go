@@ -0,0 +1,150 @@
+// Package dispatcher распределяет обновления Telegram по ограниченному пулу
+// обработчиков, сохраняя порядок сообщений внутри одного чата.
+package dispatcher
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Handler обрабатывает одно обновление. Контекст отменяется только
+// если обработчик не успел завершиться за время остановки.
+type Handler func(ctx context.Context, update tgbotapi.Update)
+
+// job – обновление вместе с обработчиком бота, от которого оно пришло.
+type job struct {
+	update  tgbotapi.Update
+	handler Handler
+}
+
+// Dispatcher – пул воркеров с очередью на каждого воркера.
+// Обновления одного чата всегда попадают к одному воркеру и
+// обрабатываются строго по порядку.
+type Dispatcher struct {
+	queues []chan job
+
+	workers   sync.WaitGroup
+	consumers sync.WaitGroup
+
+	// handlerCtx передаётся обработчикам и отменяется, если
+	// они не уложились в таймаут остановки.
+	handlerCtx    context.Context
+	cancelHandler context.CancelFunc
+}
+
+// New создаёт диспетчер с workers воркерами и очередью queueSize на каждого.
+func New(workers, queueSize int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		queues:        make([]chan job, workers),
+		handlerCtx:    ctx,
+		cancelHandler: cancel,
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan job, queueSize)
+		d.workers.Add(1)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+// Consume читает обновления из канала и ставит их в очередь, пока канал
+// не закроется. Обновления из канала уже подтверждены источнику, поэтому
+// Consume не бросает их при остановке, а дочитывает до закрытия канала.
+// Если очередь воркера заполнена, чтение приостанавливается (обратное
+// давление на источник обновлений).
+func (d *Dispatcher) Consume(updates tgbotapi.UpdatesChannel, h Handler) {
+	d.consumers.Add(1)
+	go func() {
+		defer d.consumers.Done()
+		for update := range updates {
+			d.queues[shard(chatKey(update), len(d.queues))] <- job{update: update, handler: h}
+		}
+	}()
+}
+
+// Shutdown дожидается, пока все Consume дочитают свои каналы (источник
+// обновлений к этому времени должен быть остановлен и закрыть их), затем
+// даёт воркерам обработать оставшиеся в очередях обновления.
+// Если ctx истекает раньше, обработчикам отменяется контекст и
+// возвращается ctx.Err(): обработчики могут ещё работать.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	consumed := make(chan struct{})
+	go func() {
+		d.consumers.Wait()
+		close(consumed)
+	}()
+	select {
+	case <-consumed:
+	case <-ctx.Done():
+		d.cancelHandler()
+		return ctx.Err()
+	}
+	for _, q := range d.queues {
+		close(q)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancelHandler()
+		return nil
+	case <-ctx.Done():
+		d.cancelHandler()
+		return ctx.Err()
+	}
+}
+
+// work последовательно выполняет задания из своей очереди.
+func (d *Dispatcher) work(q <-chan job) {
+	defer d.workers.Done()
+	for j := range q {
+		d.run(j)
+	}
+}
+
+// run выполняет обработчик, не давая панике остановить воркер.
+func (d *Dispatcher) run(j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Паника при обработке обновления %d: %v\n%s", j.update.UpdateID, r, debug.Stack())
+		}
+	}()
+	j.handler(d.handlerCtx, j.update)
+}
+
+// chatKey возвращает ключ упорядочивания: ID чата, иначе ID отправителя,
+// иначе ID обновления (такие обновления порядок не требуют).
+func chatKey(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return int64(update.UpdateID)
+}
+
+// shard выбирает воркера по ключу.
+func shard(key int64, n int) int {
+	k := key % int64(n)
+	if k < 0 {
+		k = -k
+	}
+	return int(k)
+}
@@ -0,0 +1,44 @@
+package dispatcher
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestShutdownHandlesBufferedUpdates(t *testing.T) {
+	d := New(2, 1)
+	updates := make(chan tgbotapi.Update, 10)
+	var handled atomic.Int32
+	d.Consume(updates, func(ctx context.Context, update tgbotapi.Update) {
+		time.Sleep(10 * time.Millisecond)
+		handled.Add(1)
+	})
+	for i := 1; i <= 10; i++ {
+		updates <- tgbotapi.Update{UpdateID: i}
+	}
+	close(updates)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := handled.Load(); got != 10 {
+		t.Errorf("обработано %d обновлений, want 10", got)
+	}
+}
+
+func TestShutdownTimesOutOnOpenChannel(t *testing.T) {
+	d := New(1, 1)
+	d.Consume(make(chan tgbotapi.Update), func(ctx context.Context, update tgbotapi.Update) {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); err == nil {
+		t.Error("Shutdown вернул nil, хотя канал обновлений не закрыт")
+	}
+}
@@ -4,7 +4,12 @@ go 1.23.5
 
 require github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 
-require github.com/mattn/go-sqlite3 v1.14.24
+require (
+	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	gopkg.in/yaml.v3 v3.0.1
+	modernc.org/sqlite v1.36.1
+)
 
 require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
@@ -17,5 +22,4 @@ require (
 	modernc.org/libc v1.61.13 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.8.2 // indirect
-	modernc.org/sqlite v1.36.1 // indirect
 )
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -8,13 +10,14 @@ import (
 
 	"telegram-bot/config"
 	"telegram-bot/db"
+	"telegram-bot/models"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // HandleModifyCurrency позволяет администратору изменять валюту у пользователя.
 // Формат команды: /modifycurrency <telegram_id> <currency> <amount>
-func HandleModifyCurrency(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func HandleModifyCurrency(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
 	if !config.IsAdmin(msg.From.ID) {
 		sendMessage(bot, msg.Chat.ID, "Нет прав!")
 		return
@@ -29,74 +32,96 @@ func HandleModifyCurrency(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		sendMessage(bot, msg.Chat.ID, "Неверный telegram_id")
 		return
 	}
-	currency := strings.ToLower(args[1])
 	amount, err := strconv.Atoi(args[2])
 	if err != nil {
 		sendMessage(bot, msg.Chat.ID, "Amount должен быть числом")
 		return
 	}
-
-	profile, err := db.GetProfile(userID)
-	if err != nil || profile == nil {
-		sendMessage(bot, msg.Chat.ID, "Профиль не найден")
+	// Баланс выведенной из оборота валюты тоже можно установить (например, обнулить).
+	currency, err := resolveCurrency(ctx, Store, args[1], false)
+	if err != nil {
+		sendMessage(bot, msg.Chat.ID, currencyErrorText(ctx, err))
 		return
 	}
 
-	if currency == "piastres" || currency == "пиастры" {
-		profile.Piastres = amount
-	} else if currency == "oblomki" || currency == "обломки" {
-		profile.Oblomki = amount
-	} else {
-		sendMessage(bot, msg.Chat.ID, "Неизвестный тип валюты")
-		return
-	}
-	err = db.SaveProfile(profile)
-	if err != nil {
+	// Установка баланса записывается в журнал как разница с текущим значением.
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		profile, err := tx.GetProfile(ctx, userID)
+		if err != nil {
+			return errProfileNotFound
+		}
+		current := profile.Balance(currency.Code)
+		if amount == current {
+			return nil
+		}
+		grant := &models.AdminGrant{
+			ProfileID: profile.ID,
+			Currency:  currency.Code,
+			Amount:    amount - current,
+			AdminID:   msg.From.ID,
+			Reason:    fmt.Sprintf("Установка баланса администратором: %d", amount),
+		}
+		if err := tx.CreateAdminGrant(ctx, grant); err != nil {
+			return err
+		}
+		return tx.ChangeBalance(ctx, &models.LedgerEntry{
+			ProfileID:  profile.ID,
+			Currency:   currency.Code,
+			Delta:      grant.Amount,
+			Reason:     grant.Reason,
+			SourceType: models.LedgerSourceAdmin,
+			SourceID:   int64(grant.ID),
+		})
+	})
+	switch {
+	case errors.Is(err, errProfileNotFound):
+		sendMessage(bot, msg.Chat.ID, "Профиль не найден")
+	case err != nil:
 		sendMessage(bot, msg.Chat.ID, "Ошибка сохранения профиля")
-	} else {
+	default:
 		sendMessage(bot, msg.Chat.ID, "Валюта успешно изменена.")
 	}
 }
 
 // HandleCurrencyRanking выводит рейтинг по заданной валюте.
 // Формат команды: /currencyranking <currency>
-// Если параметр не указан, по умолчанию используется "piastres".
-func HandleCurrencyRanking(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+// Если параметр не указан, используется первая действующая валюта из справочника.
+func HandleCurrencyRanking(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
 	if !config.IsAdmin(msg.From.ID) {
 		sendMessage(bot, msg.Chat.ID, "Нет прав!")
 		return
 	}
-	args := strings.Fields(msg.CommandArguments())
-	currency := "piastres"
-	if len(args) > 0 {
-		currency = strings.ToLower(args[0])
+	var currency *models.Currency
+	if arg := strings.TrimSpace(msg.CommandArguments()); arg != "" {
+		c, err := resolveCurrency(ctx, Store, arg, false)
+		if err != nil {
+			sendMessage(bot, msg.Chat.ID, currencyErrorText(ctx, err))
+			return
+		}
+		currency = c
+	} else {
+		currencies, err := Store.ListCurrencies(ctx, false)
+		if err != nil || len(currencies) == 0 {
+			sendMessage(bot, msg.Chat.ID, "Нет действующих валют")
+			return
+		}
+		currency = currencies[0]
 	}
 
-	profiles, err := db.GetAllProfiles()
+	profiles, err := Store.GetAllProfiles(ctx)
 	if err != nil {
 		sendMessage(bot, msg.Chat.ID, "Ошибка получения профилей")
 		return
 	}
 
 	// Сортировка профилей по валюте
-	sort.Slice(profiles, func(i, j int) bool {
-		if currency == "piastres" || currency == "пиастры" {
-			return profiles[i].Piastres > profiles[j].Piastres
-		} else if currency == "oblomki" || currency == "обломки" {
-			return profiles[i].Oblomki > profiles[j].Oblomki
-		}
-		return false
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Balance(currency.Code) > profiles[j].Balance(currency.Code)
 	})
 
-	ranking := fmt.Sprintf("Рейтинг по %s:\n", currency)
+	ranking := fmt.Sprintf("Рейтинг по %s:\n", currency.Label())
 	for i, p := range profiles {
-		var value int
-		if currency == "piastres" || currency == "пиастры" {
-			value = p.Piastres
-		} else {
-			value = p.Oblomki
-		}
-		ranking += fmt.Sprintf("%d. %s – %d\n", i+1, p.Name, value)
+		ranking += fmt.Sprintf("%d. %s – %d\n", i+1, p.Name, p.Balance(currency.Code))
 	}
 	sendMessage(bot, msg.Chat.ID, ranking)
 }
@@ -1,12 +1,14 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"strconv"
 	"strings"
-	"time"
 
+	"telegram-bot/config"
 	"telegram-bot/db"
 	"telegram-bot/models"
 	"telegram-bot/utils"
@@ -14,8 +16,6 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-const AdminPassword = "SuperSecret123"
-
 // Глобальная карта для хранения аутентифицированных админов.
 var authenticatedAdmins = make(map[int64]bool)
 
@@ -24,10 +24,10 @@ func isAuthenticated(chatID int64) bool {
 	return authenticatedAdmins[chatID]
 }
 
-// authenticate выполняет аутентификацию: если предоставленный пароль совпадает с AdminPassword,
-// то chatID сохраняется как аутентифицированный.
+// authenticate выполняет аутентификацию: если предоставленный пароль совпадает с паролем
+// из конфигурации (admin.password), то chatID сохраняется как аутентифицированный.
 func authenticate(chatID int64, providedPassword string) bool {
-	if providedPassword == AdminPassword {
+	if providedPassword != "" && providedPassword == config.Current.Admin.Password {
 		authenticatedAdmins[chatID] = true
 		return true
 	}
@@ -35,7 +35,7 @@ func authenticate(chatID int64, providedPassword string) bool {
 }
 
 // HandleAdminUpdate обрабатывает команды администраторского бота.
-func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+func HandleAdminUpdate(ctx context.Context, bot Sender, update tgbotapi.Update) {
 	if update.Message == nil || !update.Message.IsCommand() {
 		return
 	}
@@ -54,7 +54,58 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 			"/editprofile <ID> <поле> <значение> - редактирование анкеты\n" +
 			"/deleteprofilebyid <ID> - удаление анкеты по ID\n" +
 			"/addcurrency <ID> <тип валюты> <количество> - добавление валюты\n" +
-			"/createevent <название|валюта|сумма> - создание события\n"
+			"/ledger <ID> - журнал операций с валютой анкеты\n" +
+			"/fine <ID> <сумма> <валюта> <причина> - штраф (баланс может уйти в минус)\n" +
+			"/debts - персонажи с долгами\n" +
+			"/reconcile [apply] - сверка журнала с участием в событиях и начислениями (apply - исправить)\n" +
+			"/currencies - справочник валют\n" +
+			"/newcurrency <код|название|алиасы|иконка> - добавление валюты\n" +
+			"/renamecurrency <валюта|название|алиасы|иконка> - переименование валюты\n" +
+			"/retirecurrency <валюта> - вывод валюты из оборота\n" +
+			"/restorecurrency <валюта> - возврат валюты в оборот\n" +
+			"/catalog - справочник предметов\n" +
+			"/newitem <название|описание> - добавление предмета в справочник\n" +
+			"/grantitem <ID> <предмет> [количество] [| заметка] - выдача предмета персонажу\n" +
+			"/revokeitem <ID> <предмет или #запись> [количество] - изъятие предмета\n" +
+			"/items - товары магазина\n" +
+			"/additem <название|описание|цена|запас|ограничения> - добавление товара\n" +
+			"/edititem <ID> <поле> <значение> - изменение товара\n" +
+			"/removeitem <ID> - удаление товара\n" +
+			"/transfersettings - комиссия и лимиты переводов между персонажами\n" +
+			"/settransferfee <процент> - комиссия за перевод\n" +
+			"/settransferlimit <валюта> <сумма> - дневной лимит переводов (0 - без лимита)\n" +
+			"/trades [ID] - журнал обменов между персонажами\n" +
+			"/viewtrade <номер> - подробности обмена\n" +
+			"/auction <предмет> [количество]|<цена> <валюта>|<длительность> - выставить лот на аукцион\n" +
+			"/auctions - последние аукционы\n" +
+			"/viewauction <номер> - аукцион и история ставок\n" +
+			"/cancelauction <номер> - отмена аукциона с возвратом ставки и лота\n" +
+			"/rates - курсы, спред и лимиты обмена валют\n" +
+			"/setrate <из> <в> <курс> - курс обмена (10:1, 8 или off)\n" +
+			"/setspread <процент> - спред обмена валют\n" +
+			"/setexchangelimit <валюта> <сумма> - дневной лимит обмена (0 - без лимита)\n" +
+			"/ratehistory [<из> <в>] - история курсов\n" +
+			"/dailyrewards - таблица ежедневных наград\n" +
+			"/setdailyreward <день серии> <сумма> <валюта> - награда за день серии (0 - убрать)\n" +
+			"/stipends - стипендии по рангам и командам\n" +
+			"/addstipend <получатели>|<сумма> <валюта>|<расписание> - добавление стипендии\n" +
+			"/pausestipend <номер> - приостановка стипендии\n" +
+			"/resumestipend <номер> - возобновление стипендии\n" +
+			"/removestipend <номер> - удаление стипендии\n" +
+			"/createevent <название|валюта|сумма[|срок[|напоминание]][|код [период]][|мест N]> - создание события (срок: 3h или 25.10 18:00 - 21:00)\n" +
+			"/setcapacity <ID> <мест> - число мест события (0 - без ограничения)\n" +
+			"/eventcode <ID> - код отметки события и ссылка для QR-кода\n" +
+			"/events - последние события с участниками и выплатами\n" +
+			"/closeevent <ID> - закрытие события с итогом\n" +
+			"/reopenevent <ID> [длительность] - повторное открытие события\n" +
+			"/eventtemplates - шаблоны повторяющихся событий\n" +
+			"/addeventtemplate <название|валюта|сумма|расписание|длительность[|напоминание][|код [период]][|мест N]> - добавление шаблона события\n" +
+			"/upcomingevents [дней] - ближайшие события по шаблонам\n" +
+			"/skipevent <номер шаблона> [время] - пропуск повтора шаблона\n" +
+			"/unskipevent <номер шаблона> <время> - отмена пропуска повтора\n" +
+			"/pauseeventtemplate <номер> - приостановка шаблона события\n" +
+			"/resumeeventtemplate <номер> - возобновление шаблона события\n" +
+			"/removeeventtemplate <номер> - удаление шаблона события\n"
 		msg := tgbotapi.NewMessage(chatID, helpText)
 		bot.Send(msg)
 		return
@@ -83,7 +134,7 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 	// Обработка других команд
 	switch cmd {
 	case "allprofiles":
-		profiles, err := db.GetAllProfiles()
+		profiles, err := Store.GetAllProfiles(ctx)
 		if err != nil {
 			log.Printf("Ошибка получения профилей: %v", err)
 			msg := tgbotapi.NewMessage(chatID, "Ошибка получения профилей.")
@@ -120,7 +171,7 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 			bot.Send(msg)
 			return
 		}
-		profile, err := db.GetProfileByID(id)
+		profile, err := Store.GetProfileByID(ctx, id)
 		if err != nil {
 			msg := tgbotapi.NewMessage(chatID, "Ошибка получения анкеты: "+err.Error())
 			bot.Send(msg)
@@ -143,7 +194,7 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 			bot.Send(msg)
 			return
 		}
-		err = db.DeleteProfileByID(id)
+		err = Store.DeleteProfileByID(ctx, id)
 		if err != nil {
 			msg := tgbotapi.NewMessage(chatID, "Ошибка удаления анкеты: "+err.Error())
 			bot.Send(msg)
@@ -168,7 +219,7 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 		field := strings.ToLower(parts[1])
 		newValue := strings.Join(parts[2:], " ")
 
-		profile, err := db.GetProfileByID(id)
+		profile, err := Store.GetProfileByID(ctx, id)
 		if err != nil {
 			msg := tgbotapi.NewMessage(chatID, "Ошибка получения анкеты: "+err.Error())
 			bot.Send(msg)
@@ -198,9 +249,6 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 				profile.Weight = weight
 				edited = true
 			}
-		case "inventory":
-			profile.Inventory = newValue
-			edited = true
 		case "photo":
 			profile.Photo = newValue
 			edited = true
@@ -211,12 +259,13 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 			profile.Team = newValue
 			edited = true
 		default:
-			msg := tgbotapi.NewMessage(chatID, "Неизвестное поле. Доступны: name, age, height, weight, inventory, photo, rank, team")
+			msg := tgbotapi.NewMessage(chatID, "Неизвестное поле. Доступны: name, age, height, weight, photo, rank, team\n"+
+				"Инвентарь изменяется командами /grantitem и /revokeitem")
 			bot.Send(msg)
 			return
 		}
 		if edited {
-			err = db.UpdateProfile(profile)
+			err = Store.UpdateProfile(ctx, profile)
 			if err != nil {
 				msg := tgbotapi.NewMessage(chatID, "Ошибка обновления анкеты: "+err.Error())
 				bot.Send(msg)
@@ -249,132 +298,226 @@ func HandleAdminUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 		}
 
 		// Обработка первой валюты
-		currency1 := strings.ToLower(parts[1])
-		amount1, err := strconv.Atoi(parts[2])
+		currency1, err := resolveCurrency(ctx, Store, parts[1], true)
 		if err != nil {
-			msg := tgbotapi.NewMessage(chatID, "Количество должно быть числом для первой валюты.")
+			msg := tgbotapi.NewMessage(chatID, "Первая валюта: "+currencyErrorText(ctx, err))
 			bot.Send(msg)
 			return
 		}
-
-		// Получаем профиль
-		profile, err := db.GetProfileByID(id)
+		amount1, err := strconv.Atoi(parts[2])
 		if err != nil {
-			msg := tgbotapi.NewMessage(chatID, "Ошибка получения анкеты: "+err.Error())
-			bot.Send(msg)
-			return
-		}
-
-		// Начисление первой валюты
-		switch currency1 {
-		case "piastres", "пиастры":
-			profile.Piastres += amount1
-		case "oblomki", "обломки":
-			profile.Oblomki += amount1
-		default:
-			msg := tgbotapi.NewMessage(chatID, "Неизвестный тип валюты для первого обновления. Используйте 'piastres' или 'oblomki'.")
+			msg := tgbotapi.NewMessage(chatID, "Количество должно быть числом для первой валюты.")
 			bot.Send(msg)
 			return
 		}
 
-		// Если передано 5 параметров — начисляем вторую валюту
+		// Если передано 5 параметров — будет начислена и вторая валюта
+		var currency2 *models.Currency
+		var amount2 int
 		if len(parts) == 5 {
-			currency2 := strings.ToLower(parts[3])
-			amount2, err := strconv.Atoi(parts[4])
+			currency2, err = resolveCurrency(ctx, Store, parts[3], true)
 			if err != nil {
-				msg := tgbotapi.NewMessage(chatID, "Количество должно быть числом для второй валюты.")
+				msg := tgbotapi.NewMessage(chatID, "Вторая валюта: "+currencyErrorText(ctx, err))
 				bot.Send(msg)
 				return
 			}
-			switch currency2 {
-			case "piastres", "пиастры":
-				profile.Piastres += amount2
-			case "oblomki", "обломки":
-				profile.Oblomki += amount2
-			default:
-				msg := tgbotapi.NewMessage(chatID, "Неизвестный тип валюты для второго обновления. Используйте 'piastres' или 'oblomki'.")
+			amount2, err = strconv.Atoi(parts[4])
+			if err != nil {
+				msg := tgbotapi.NewMessage(chatID, "Количество должно быть числом для второй валюты.")
 				bot.Send(msg)
 				return
 			}
 		}
 
-		// Обновление профиля в базе данных
-		err = db.UpdateProfile(profile)
-		if err != nil {
-			msg := tgbotapi.NewMessage(chatID, "Ошибка обновления анкеты: "+err.Error())
+		// Все начисления – в одной транзакции, каждое с записью в журнал
+		adminID := update.Message.From.ID
+		var profile *models.Profile
+		err = Store.WithTx(ctx, func(tx db.Store) error {
+			credit := func(currency *models.Currency, amount int) error {
+				grant := &models.AdminGrant{
+					ProfileID: id, Currency: currency.Code, Amount: amount,
+					AdminID: adminID, Reason: "Начисление администратором",
+				}
+				if err := tx.CreateAdminGrant(ctx, grant); err != nil {
+					return err
+				}
+				return tx.ChangeBalance(ctx, &models.LedgerEntry{
+					ProfileID:  id,
+					Currency:   currency.Code,
+					Delta:      amount,
+					Reason:     grant.Reason,
+					SourceType: models.LedgerSourceAdmin,
+					SourceID:   int64(grant.ID),
+				})
+			}
+			if err := credit(currency1, amount1); err != nil {
+				return err
+			}
+			if currency2 != nil {
+				if err := credit(currency2, amount2); err != nil {
+					return err
+				}
+			}
+			var err error
+			profile, err = tx.GetProfileByID(ctx, id)
+			return err
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			msg := tgbotapi.NewMessage(chatID, "Анкета с ID "+strconv.Itoa(id)+" не найдена.")
+			bot.Send(msg)
+			return
+		} else if err != nil {
+			msg := tgbotapi.NewMessage(chatID, "Ошибка начисления валюты: "+err.Error())
 			bot.Send(msg)
 			return
 		}
 
 		// Отправляем сообщение с обновлённым балансом
-		responseText := fmt.Sprintf("Валюта успешно начислена.\nНовая сумма:\nПиастры: %d\nОбломки: %d",
-			profile.Piastres, profile.Oblomki)
-		msg := tgbotapi.NewMessage(chatID, responseText)
+		msg := tgbotapi.NewMessage(chatID, "Валюта успешно начислена.\n"+utils.FormatBalance(profile))
 		bot.Send(msg)
 
-	case "createevent":
-		parts := strings.Split(args, "|")
-		if len(parts) != 3 {
-			msg := tgbotapi.NewMessage(chatID, "Используйте формат команды: /createevent <название|валюта|количество>")
-			bot.Send(msg)
-			return
-		}
+	case "ledger":
+		handleAdminLedger(ctx, bot, chatID, args)
 
-		if PrimaryBot != nil {
-			// Используем PrimaryBot (пользовательский бот) для рассылки уведомления
-			HandleCreateEvent(PrimaryBot, update.Message)
-		} else {
-			log.Println("PrimaryBot не инициализирован")
-		}
+	case "fine":
+		handleAdminFine(ctx, bot, chatID, update.Message.From.ID, args)
 
-		name := strings.TrimSpace(parts[0])
-		currency := strings.ToLower(strings.TrimSpace(parts[1]))
-		amount, err := strconv.Atoi(strings.TrimSpace(parts[2]))
-		if err != nil {
-			msg := tgbotapi.NewMessage(chatID, "Количество должно быть числом.")
-			bot.Send(msg)
-			return
-		}
+	case "debts":
+		handleAdminDebts(ctx, bot, chatID)
 
-		// Создаем объект события
-		event := &models.Event{
-			Name:         name,
-			CurrencyType: currency,
-			Amount:       amount,
-			Active:       true,
-			CreatedAt:    time.Now(),
-		}
+	case "reconcile":
+		handleAdminReconcile(ctx, bot, chatID, update.Message.From.ID, args)
 
-		// Сохраняем событие в базе данных
-		err = db.CreateEvent(event)
-		if err != nil {
-			log.Printf("Ошибка создания события: %v", err)
-			msg := tgbotapi.NewMessage(chatID, "Ошибка создания события: "+err.Error())
-			bot.Send(msg)
-			return
-		}
+	case "currencies":
+		handleAdminCurrencies(ctx, bot, chatID)
 
-		log.Printf("Создано событие: ID=%d, Название=%s, Валюта=%s, Сумма=%d", event.ID, event.Name, event.CurrencyType, event.Amount)
+	case "newcurrency":
+		handleAdminNewCurrency(ctx, bot, chatID, args)
 
-		// Уведомление для админа
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Событие создано: \"%s\" (ID: %d)", event.Name, event.ID))
-		bot.Send(msg)
+	case "renamecurrency":
+		handleAdminRenameCurrency(ctx, bot, chatID, args)
 
-		// Отправляем уведомление всем пользователям
-		notifyText := fmt.Sprintf("Новое событие: \"%s\" (ID: %d)\n"+
-			"Для участия введите: /attend %d\n"+
-			"Для отказа: /unattend %d", event.Name, event.ID, event.ID, event.ID)
+	case "retirecurrency":
+		handleAdminRetireCurrency(ctx, bot, chatID, args, true)
 
-		profiles, err := db.GetAllProfiles()
-		if err != nil {
-			log.Printf("Ошибка получения профилей для рассылки: %v", err)
-			return
-		}
+	case "restorecurrency":
+		handleAdminRetireCurrency(ctx, bot, chatID, args, false)
 
-		for _, profile := range profiles {
-			notification := tgbotapi.NewMessage(profile.TelegramID, notifyText)
-			bot.Send(notification)
-		}
+	case "catalog":
+		handleAdminCatalog(ctx, bot, chatID)
+
+	case "newitem":
+		handleAdminNewItem(ctx, bot, chatID, args)
+
+	case "grantitem":
+		handleAdminGrantItem(ctx, bot, chatID, args)
+
+	case "revokeitem":
+		handleAdminRevokeItem(ctx, bot, chatID, args)
+
+	case "items":
+		handleAdminItems(ctx, bot, chatID)
+
+	case "additem":
+		handleAdminAddItem(ctx, bot, chatID, args)
+
+	case "edititem":
+		handleAdminEditItem(ctx, bot, chatID, args)
+
+	case "removeitem":
+		handleAdminRemoveItem(ctx, bot, chatID, args)
+
+	case "transfersettings":
+		handleAdminTransferSettings(ctx, bot, chatID)
+
+	case "settransferfee":
+		handleAdminSetTransferFee(ctx, bot, chatID, args)
+
+	case "settransferlimit":
+		handleAdminSetTransferLimit(ctx, bot, chatID, args)
+
+	case "trades":
+		handleAdminTrades(ctx, bot, chatID, args)
+
+	case "viewtrade":
+		handleAdminViewTrade(ctx, bot, chatID, args)
+
+	case "auction":
+		handleAdminAuction(ctx, bot, chatID, args)
+
+	case "auctions":
+		handleAdminAuctions(ctx, bot, chatID)
+
+	case "viewauction":
+		handleAdminViewAuction(ctx, bot, chatID, args)
+
+	case "cancelauction":
+		handleAdminCancelAuction(ctx, bot, chatID, args)
+
+	case "rates":
+		handleAdminRates(ctx, bot, chatID)
+
+	case "setrate":
+		handleAdminSetRate(ctx, bot, chatID, update.Message.From.ID, args)
+
+	case "setspread":
+		handleAdminSetSpread(ctx, bot, chatID, args)
+
+	case "setexchangelimit":
+		handleAdminSetExchangeLimit(ctx, bot, chatID, args)
+
+	case "ratehistory":
+		handleAdminRateHistory(ctx, bot, chatID, args)
+
+	case "dailyrewards":
+		handleAdminDailyRewards(ctx, bot, chatID)
+
+	case "setdailyreward":
+		handleAdminSetDailyReward(ctx, bot, chatID, args)
+
+	case "stipends":
+		handleAdminStipends(ctx, bot, chatID)
+
+	case "addstipend":
+		handleAdminAddStipend(ctx, bot, chatID, args)
+
+	case "pausestipend":
+		handleAdminPauseStipend(ctx, bot, chatID, args, true)
+
+	case "resumestipend":
+		handleAdminPauseStipend(ctx, bot, chatID, args, false)
+
+	case "removestipend":
+		handleAdminRemoveStipend(ctx, bot, chatID, args)
+
+	case "createevent":
+		handleAdminCreateEvent(ctx, bot, chatID, args)
+	case "events":
+		handleAdminEvents(ctx, bot, chatID)
+	case "closeevent":
+		handleAdminCloseEvent(ctx, bot, chatID, args)
+	case "reopenevent":
+		handleAdminReopenEvent(ctx, bot, chatID, args)
+	case "eventcode":
+		handleAdminEventCode(ctx, bot, chatID, args)
+	case "setcapacity":
+		handleAdminSetCapacity(ctx, bot, chatID, args)
+	case "eventtemplates":
+		handleAdminEventTemplates(ctx, bot, chatID)
+	case "addeventtemplate":
+		handleAdminAddEventTemplate(ctx, bot, chatID, args)
+	case "upcomingevents":
+		handleAdminUpcomingEvents(ctx, bot, chatID, args)
+	case "skipevent":
+		handleAdminSkipEvent(ctx, bot, chatID, args, true)
+	case "unskipevent":
+		handleAdminSkipEvent(ctx, bot, chatID, args, false)
+	case "pauseeventtemplate":
+		handleAdminPauseEventTemplate(ctx, bot, chatID, args, true)
+	case "resumeeventtemplate":
+		handleAdminPauseEventTemplate(ctx, bot, chatID, args, false)
+	case "removeeventtemplate":
+		handleAdminRemoveEventTemplate(ctx, bot, chatID, args)
 
 	default:
 		msg := tgbotapi.NewMessage(chatID, "Неизвестная команда. Используйте /help для списка доступных команд.")
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+)
+
+// attendance – результат отметки на событии или отмены участия.
+type attendance struct {
+	event    *models.Event // событие, прочитанное в транзакции
+	profile  *models.Profile
+	credit   *models.LedgerEntry // начисление за участие
+	label    string              // валюта начисления для сообщений
+	waitlist int                 // место в листе ожидания: встал в очередь (отметка) или покинул её (отмена)
+	promoted []promotion         // кто отмечен из листа ожидания на освободившиеся места
+}
+
+// promotion – пользователь, отмеченный из листа ожидания.
+type promotion struct {
+	telegramID int64
+	credit     *models.LedgerEntry
+	label      string
+}
+
+// creditAttendance начисляет награду за участие в событии с записью в журнал
+// и регистрирует участие. Если баланс отрицательный, начисление гасит долг.
+func creditAttendance(ctx context.Context, tx db.Store, event *models.Event, profile *models.Profile) (*models.LedgerEntry, string, error) {
+	currency, err := resolveCurrency(ctx, tx, event.CurrencyType, false)
+	if err != nil {
+		return nil, "", err
+	}
+	credit := &models.LedgerEntry{
+		ProfileID:  profile.ID,
+		Currency:   currency.Code,
+		Delta:      event.Amount,
+		Reason:     fmt.Sprintf("Участие в событии «%s»", event.Name),
+		SourceType: models.LedgerSourceEvent,
+		SourceID:   int64(event.ID),
+	}
+	if err := tx.ChangeBalance(ctx, credit); err != nil {
+		return nil, "", fmt.Errorf("ошибка начисления валюты: %w", err)
+	}
+	if err := tx.AddEventParticipation(ctx, event.ID, profile.TelegramID); err != nil {
+		return nil, "", fmt.Errorf("ошибка регистрации участия: %w", err)
+	}
+	return credit, currency.Label(), nil
+}
+
+// eventInTx перечитывает событие в транзакции и проверяет, что оно открыто и
+// идёт: проверки до транзакции могли устареть из-за /closeevent или задачи закрытия.
+func eventInTx(ctx context.Context, tx db.Store, eventID int) (*models.Event, error) {
+	e, err := tx.GetEventByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if !e.Active {
+		return e, errEventClosed
+	}
+	if eventWindowText(e, time.Now()) != "" {
+		return e, errEventWindow
+	}
+	return e, nil
+}
+
+// joinEvent отмечает пользователя на событии. Проверка события, участия и мест,
+// начисление и запись участия выполняются в одной транзакции по перечитанному
+// в ней событию: либо всё сохранится, либо ничего, и два одновременных участника
+// не займут одно последнее место. Если мест нет, пользователь встаёт в лист ожидания (res.waitlist –
+// его место). При ошибках errEventClosed и errEventWindow res.event – событие из транзакции.
+func joinEvent(ctx context.Context, eventID int, telegramID int64) (*attendance, error) {
+	res := &attendance{}
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		event, err := eventInTx(ctx, tx, eventID)
+		res.event = event
+		if err != nil {
+			return err
+		}
+		participated, err := tx.UserParticipatedInEvent(ctx, event.ID, telegramID)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки участия: %w", err)
+		}
+		if participated {
+			return errAlreadyParticipated
+		}
+		profile, err := tx.GetProfile(ctx, telegramID)
+		if err != nil {
+			return errProfileNotFound
+		}
+
+		if event.Capacity > 0 {
+			taken, err := tx.CountEventParticipants(ctx, event.ID)
+			if err != nil {
+				return err
+			}
+			if taken >= event.Capacity {
+				if res.waitlist, err = tx.WaitlistPosition(ctx, event.ID, telegramID); err != nil || res.waitlist > 0 {
+					return err
+				}
+				res.waitlist, err = tx.AddToWaitlist(ctx, event.ID, telegramID)
+				return err
+			}
+		}
+
+		if res.credit, res.label, err = creditAttendance(ctx, tx, event, profile); err != nil {
+			return err
+		}
+		res.profile, err = tx.GetProfile(ctx, telegramID)
+		return err
+	})
+	return res, err
+}
+
+// leaveEvent отменяет участие пользователя в событии и списывает начисленную валюту;
+// освободившееся место получает первый из листа ожидания. Пользователь из листа
+// ожидания просто покидает очередь (res.waitlist – его бывшее место). После окончания
+// события участие не отменяется.
+func leaveEvent(ctx context.Context, eventID int, telegramID int64) (*attendance, error) {
+	res := &attendance{}
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		event, err := eventInTx(ctx, tx, eventID)
+		res.event = event
+		if err != nil {
+			return err
+		}
+		participated, err := tx.UserParticipatedInEvent(ctx, event.ID, telegramID)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки участия: %w", err)
+		}
+		if !participated {
+			if res.waitlist, err = tx.WaitlistPosition(ctx, event.ID, telegramID); err != nil {
+				return err
+			}
+			if res.waitlist == 0 {
+				return errNotParticipated
+			}
+			return tx.RemoveFromWaitlist(ctx, event.ID, telegramID)
+		}
+
+		profile, err := tx.GetProfile(ctx, telegramID)
+		if err != nil {
+			return errProfileNotFound
+		}
+
+		// Списываем начисленную за участие валюту с записью в журнал. Если её уже
+		// потратили, баланс уходит в минус и становится долгом.
+		currency, err := resolveCurrency(ctx, tx, event.CurrencyType, false)
+		if err != nil {
+			return err
+		}
+		err = tx.ChangeBalance(ctx, &models.LedgerEntry{
+			ProfileID:  profile.ID,
+			Currency:   currency.Code,
+			Delta:      -event.Amount,
+			Reason:     fmt.Sprintf("Отмена участия в событии «%s»", event.Name),
+			SourceType: models.LedgerSourceEvent,
+			SourceID:   int64(event.ID),
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка списания валюты: %w", err)
+		}
+		if err := tx.RemoveEventParticipation(ctx, event.ID, telegramID); err != nil {
+			return fmt.Errorf("ошибка отмены участия: %w", err)
+		}
+		if res.profile, err = tx.GetProfile(ctx, telegramID); err != nil {
+			return err
+		}
+		res.promoted, err = promoteWaitlist(ctx, tx, event)
+		return err
+	})
+	return res, err
+}
+
+// promoteWaitlist отмечает пользователей из листа ожидания по очереди, пока есть
+// свободные места, и начисляет им награду. Пользователи без анкеты пропускаются.
+func promoteWaitlist(ctx context.Context, tx db.Store, event *models.Event) ([]promotion, error) {
+	var promoted []promotion
+	for {
+		if event.Capacity > 0 {
+			taken, err := tx.CountEventParticipants(ctx, event.ID)
+			if err != nil {
+				return nil, err
+			}
+			if taken >= event.Capacity {
+				return promoted, nil
+			}
+		}
+		telegramID, err := tx.PopWaitlist(ctx, event.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return promoted, nil
+		} else if err != nil {
+			return nil, err
+		}
+		profile, err := tx.GetProfile(ctx, telegramID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		credit, label, err := creditAttendance(ctx, tx, event, profile)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, promotion{telegramID: telegramID, credit: credit, label: label})
+	}
+}
+
+// notifyPromoted сообщает пользователям, отмеченным из листа ожидания.
+func notifyPromoted(event *models.Event, promoted []promotion) {
+	if PrimaryBot == nil {
+		if len(promoted) > 0 {
+			log.Println("PrimaryBot не инициализирован")
+		}
+		return
+	}
+	for _, p := range promoted {
+		SendMessage(PrimaryBot, p.telegramID, fmt.Sprintf(
+			"Освободилось место на событии «%s» (ID %d): вы отмечены из листа ожидания и получили %d %s.%s",
+			event.Name, event.ID, p.credit.Delta, p.label, debtRepaymentText(p.label, p.credit)))
+	}
+}
+
+// eventSeatsText – места и лист ожидания события, например "мест 20, свободно 3";
+// "" для события без ограничения мест.
+func eventSeatsText(ctx context.Context, e *models.Event) string {
+	if e.Capacity <= 0 {
+		return ""
+	}
+	taken, err := Store.CountEventParticipants(ctx, e.ID)
+	if err != nil {
+		log.Printf("Ошибка подсчёта участников события %d: %v", e.ID, err)
+		return fmt.Sprintf("мест %d", e.Capacity)
+	}
+	text := fmt.Sprintf("мест %d, свободно %d", e.Capacity, max(e.Capacity-taken, 0))
+	waiting, err := Store.WaitlistLength(ctx, e.ID)
+	if err != nil {
+		log.Printf("Ошибка подсчёта листа ожидания события %d: %v", e.ID, err)
+	} else if waiting > 0 {
+		text += fmt.Sprintf(", в листе ожидания %d", waiting)
+	}
+	return text
+}
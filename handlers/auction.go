@@ -0,0 +1,567 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Параметры аукционов.
+const (
+	auctionMinDuration    = 10 * time.Minute
+	auctionMaxDuration    = 7 * 24 * time.Hour
+	auctionExtendWindow   = 5 * time.Minute // ставка в последние минуты продлевает аукцион на это время
+	auctionMinStepPercent = 5               // минимальный шаг ставки в процентах от текущей
+	auctionListLimit      = 20
+)
+
+// jobAuctionClose – задача планировщика, закрывающая аукцион; RefID – ID аукциона.
+const jobAuctionClose = "auction.close"
+
+// Ошибки аукционов.
+var (
+	errAuctionClosed  = errors.New("аукцион уже завершён")
+	errBidTooLow      = errors.New("ставка слишком мала")
+	errOwnAuction     = errors.New("нельзя делать ставки на свой лот")
+	errNotInInventory = errors.New("предмета нет в инвентаре")
+)
+
+// parseDuration разбирает длительность "30m", "2h", "1h30m" или "3d" и проверяет,
+// что она лежит в пределах от minD до maxD.
+func parseDuration(s string, minD, maxD time.Duration) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("неверная длительность %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("неверная длительность %q: используйте, например, 30m, 2h или 3d", s)
+		}
+	}
+	if d < minD || d > maxD {
+		return 0, fmt.Errorf("длительность должна быть от %d минут до %d дней",
+			int(minD.Minutes()), int(maxD.Hours()/24))
+	}
+	return d, nil
+}
+
+// parseAuctionDuration разбирает длительность аукциона: "30m", "2h", "1h30m" или "3d".
+func parseAuctionDuration(s string) (time.Duration, error) {
+	return parseDuration(s, auctionMinDuration, auctionMaxDuration)
+}
+
+// parseAuctionArgs разбирает "<предмет> [количество]|<начальная цена> <валюта>|<длительность>".
+func parseAuctionArgs(ctx context.Context, args string) (itemRef string, quantity int, price models.Price, duration time.Duration, err error) {
+	parts := strings.Split(args, "|")
+	if len(parts) != 3 {
+		return "", 0, price, 0, errors.New("нужно три части через |")
+	}
+	if itemRef, quantity, err = parseItemQuantity(parts[0]); err != nil {
+		return "", 0, price, 0, err
+	}
+	prices, err := parsePrices(ctx, parts[1])
+	if err != nil {
+		return "", 0, price, 0, err
+	}
+	if len(prices) != 1 {
+		return "", 0, price, 0, errors.New("начальная цена указывается в одной валюте")
+	}
+	if duration, err = parseAuctionDuration(parts[2]); err != nil {
+		return "", 0, price, 0, err
+	}
+	return itemRef, quantity, prices[0], duration, nil
+}
+
+// minNextBid – минимальная допустимая ставка: начальная цена, если ставок нет,
+// иначе текущая ставка плюс шаг (не меньше 1).
+func minNextBid(a *models.Auction) int {
+	if a.LeaderID == 0 {
+		return a.StartPrice
+	}
+	return a.CurrentBid + max(1, (a.CurrentBid*auctionMinStepPercent+99)/100)
+}
+
+// formatAuction – строка аукциона для списков.
+func formatAuction(ctx context.Context, labels map[string]string, a *models.Auction) string {
+	label, ok := labels[a.Currency]
+	if !ok {
+		label = a.Currency
+	}
+	text := fmt.Sprintf("#%d %s ×%d", a.ID, a.ItemName, a.Quantity)
+	if a.LeaderID == 0 {
+		text += fmt.Sprintf(" – начальная цена %d %s, ставок нет", a.StartPrice, label)
+	} else {
+		text += fmt.Sprintf(" – ставка %d %s (%s)", a.CurrentBid, label, profileName(ctx, a.LeaderID))
+	}
+	if a.Status == models.AuctionOpen {
+		text += ", до " + eventTime(a.EndsAt)
+	}
+	return text
+}
+
+// auctionStatuses – названия статусов аукциона для администраторов.
+var auctionStatuses = map[string]string{
+	models.AuctionOpen:      "идёт",
+	models.AuctionSold:      "продан",
+	models.AuctionUnsold:    "не продан",
+	models.AuctionCancelled: "отменён",
+}
+
+// createAuction выставляет лот. Предмет продавца сразу изымается из его инвентаря
+// и хранится у бота до закрытия аукциона; лот администрации (sellerID 0) берётся из справочника.
+func createAuction(ctx context.Context, sellerID int, item *models.Item, quantity int, price models.Price, duration time.Duration) (*models.Auction, error) {
+	a := &models.Auction{
+		SellerID:   sellerID,
+		ItemID:     item.ID,
+		ItemName:   item.Name,
+		Quantity:   quantity,
+		Currency:   price.Currency,
+		StartPrice: price.Amount,
+		Status:     models.AuctionOpen,
+		EndsAt:     time.Now().Add(duration),
+	}
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		if sellerID != 0 {
+			removed, err := tx.RemoveInventoryItem(ctx, sellerID, item.ID, quantity)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && removed < quantity) {
+				return fmt.Errorf("%w: %s ×%d", errNotInInventory, item.Name, quantity)
+			} else if err != nil {
+				return err
+			}
+		}
+		if err := tx.CreateAuction(ctx, a); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobAuctionClose, int64(a.ID), a.EndsAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// HandleAuction обрабатывает команду /auction <предмет> [количество]|<начальная цена> <валюта>|<длительность>.
+// Без аргументов показывает открытые аукционы.
+func HandleAuction(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	args := strings.TrimSpace(msg.CommandArguments())
+	if args == "" {
+		HandleAuctions(ctx, bot, msg)
+		return
+	}
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	itemRef, quantity, price, duration, err := parseAuctionArgs(ctx, args)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Используйте: /auction <предмет> [количество]|<начальная цена> <валюта>|<длительность>, "+
+			"например /auction Меч|50 piastres|24h ("+err.Error()+")")
+		return
+	}
+	item, err := findItem(ctx, itemRef)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, msg.Chat.ID, "Такого предмета нет. Посмотреть инвентарь: /inventory")
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка поиска предмета: "+err.Error())
+		return
+	}
+	a, err := createAuction(ctx, profile.ID, item, quantity, price, duration)
+	if errors.Is(err, errNotInInventory) {
+		SendMessage(bot, msg.Chat.ID, fmt.Sprintf("В вашем инвентаре нет %s ×%d.", item.Name, quantity))
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка создания аукциона: "+err.Error())
+		return
+	}
+	SendMessage(bot, msg.Chat.ID, fmt.Sprintf("Лот выставлен: %s\nПредмет хранится у аукциона до его окончания. Ставка: /bid %d <сумма>",
+		formatAuction(ctx, currencyLabels(ctx), a), a.ID))
+}
+
+// HandleAuctions обрабатывает команду /auctions – открытые аукционы.
+func HandleAuctions(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	auctions, err := Store.ListAuctions(ctx, true, auctionListLimit)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения аукционов: "+err.Error())
+		return
+	}
+	if len(auctions) == 0 {
+		SendMessage(bot, msg.Chat.ID, "Открытых аукционов нет.")
+		return
+	}
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("Аукционы:\n")
+	for _, a := range auctions {
+		b.WriteString(formatAuction(ctx, labels, a) + "\n")
+	}
+	b.WriteString("Ставка: /bid <номер> <сумма>")
+	SendMessage(bot, msg.Chat.ID, b.String())
+}
+
+// HandleBid обрабатывает команду /bid <номер аукциона> <сумма>.
+// Сумма ставки сразу списывается и хранится у аукциона; предыдущему лидеру
+// его ставка возвращается в той же транзакции.
+func HandleBid(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 2 {
+		SendMessage(bot, msg.Chat.ID, "Используйте: /bid <номер аукциона> <сумма>")
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Номер аукциона должен быть числом.")
+		return
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil || amount <= 0 {
+		SendMessage(bot, msg.Chat.ID, "Сумма должна быть положительным числом.")
+		return
+	}
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+
+	var a *models.Auction
+	var prevLeader, prevBid int
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		if a, err = tx.GetAuction(ctx, id); err != nil {
+			return err
+		}
+		if a.Status != models.AuctionOpen || !time.Now().Before(a.EndsAt) {
+			return errAuctionClosed
+		}
+		if a.SellerID == profile.ID {
+			return errOwnAuction
+		}
+		if next := minNextBid(a); amount < next {
+			return fmt.Errorf("%w: минимальная ставка %d", errBidTooLow, next)
+		}
+
+		prevLeader, prevBid = a.LeaderID, a.CurrentBid
+		if prevLeader != 0 {
+			err := tx.ChangeBalance(ctx, &models.LedgerEntry{
+				ProfileID: prevLeader, Currency: a.Currency, Delta: prevBid,
+				Reason:     fmt.Sprintf("Возврат ставки: аукцион #%d", a.ID),
+				SourceType: models.LedgerSourceAuction, SourceID: int64(a.ID),
+			})
+			if err != nil && !errors.Is(err, sql.ErrNoRows) { // анкету прежнего лидера могли удалить
+				return fmt.Errorf("ошибка возврата ставки: %w", err)
+			}
+		}
+		bidder, err := tx.GetProfileByID(ctx, profile.ID)
+		if err != nil {
+			return errProfileNotFound
+		}
+		if err := checkNoDebt(bidder); err != nil {
+			return err
+		}
+		if bidder.Balance(a.Currency) < amount {
+			return fmt.Errorf("%w: на балансе %d", errInsufficientFunds, bidder.Balance(a.Currency))
+		}
+		err = tx.ChangeBalance(ctx, &models.LedgerEntry{
+			ProfileID: profile.ID, Currency: a.Currency, Delta: -amount,
+			Reason:     fmt.Sprintf("Ставка на аукционе #%d (%s)", a.ID, a.ItemName),
+			SourceType: models.LedgerSourceAuction, SourceID: int64(a.ID),
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка изменения баланса: %w", err)
+		}
+		if err := tx.AddBid(ctx, &models.Bid{AuctionID: a.ID, ProfileID: profile.ID, Amount: amount}); err != nil {
+			return err
+		}
+
+		a.CurrentBid, a.LeaderID = amount, profile.ID
+		if time.Until(a.EndsAt) < auctionExtendWindow {
+			a.EndsAt = time.Now().Add(auctionExtendWindow)
+			if err := tx.ScheduleJob(ctx, jobAuctionClose, int64(a.ID), a.EndsAt); err != nil {
+				return err
+			}
+		}
+		return tx.UpdateAuction(ctx, a)
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		SendMessage(bot, msg.Chat.ID, "Аукцион не найден.")
+		return
+	case errors.Is(err, errAuctionClosed):
+		SendMessage(bot, msg.Chat.ID, "Аукцион уже завершён.")
+		return
+	case errors.Is(err, errOwnAuction), errors.Is(err, errBidTooLow), errors.Is(err, errInsufficientFunds),
+		errors.Is(err, errProfileNotFound), errors.Is(err, errInDebt):
+		SendMessage(bot, msg.Chat.ID, "Ставка не принята: "+err.Error())
+		return
+	case err != nil:
+		log.Printf("Ошибка ставки на аукционе %d: %v", id, err)
+		SendMessage(bot, msg.Chat.ID, "Ошибка ставки, попробуйте позже.")
+		return
+	}
+
+	labels := currencyLabels(ctx)
+	SendMessage(bot, msg.Chat.ID, fmt.Sprintf("Ставка принята, вы лидируете: %s\nСумма ставки списана и вернётся, если вашу ставку перебьют.",
+		formatAuction(ctx, labels, a)))
+	if prevLeader != 0 && prevLeader != profile.ID {
+		notifyProfile(ctx, prevLeader, fmt.Sprintf("Вашу ставку на аукционе #%d (%s) перебили: новая ставка %d. Ваши %d возвращены на баланс.\nПеребить: /bid %d <сумма>",
+			a.ID, a.ItemName, a.CurrentBid, prevBid, a.ID))
+	}
+}
+
+// closeAuctionJob – задача планировщика: закрывает аукцион, время которого вышло.
+// Если аукцион продлили, задача переносится на новое время окончания.
+func closeAuctionJob(ctx context.Context, job *models.Job) error {
+	var a *models.Auction
+	var settled bool
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		a, err = tx.GetAuction(ctx, int(job.RefID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if a.Status != models.AuctionOpen {
+			return nil
+		}
+		if time.Now().Before(a.EndsAt) {
+			return tx.ScheduleJob(ctx, jobAuctionClose, job.RefID, a.EndsAt)
+		}
+		settled = true
+		return settleAuction(ctx, tx, a)
+	})
+	if err != nil || !settled {
+		return err
+	}
+
+	labels := currencyLabels(ctx)
+	label, ok := labels[a.Currency]
+	if !ok {
+		label = a.Currency
+	}
+	if a.Status == models.AuctionSold {
+		notifyProfile(ctx, a.LeaderID, fmt.Sprintf("Вы выиграли аукцион #%d: %s ×%d за %d %s. Предмет добавлен в инвентарь.",
+			a.ID, a.ItemName, a.Quantity, a.CurrentBid, label))
+		if a.SellerID != 0 {
+			notifyProfile(ctx, a.SellerID, fmt.Sprintf("Аукцион #%d завершён: %s ×%d продан за %d %s.",
+				a.ID, a.ItemName, a.Quantity, a.CurrentBid, label))
+		}
+		notifyAdmins(fmt.Sprintf("Аукцион #%d завершён: %s ×%d продан персонажу %s за %d %s.",
+			a.ID, a.ItemName, a.Quantity, profileName(ctx, a.LeaderID), a.CurrentBid, label))
+		return nil
+	}
+	if a.SellerID != 0 {
+		notifyProfile(ctx, a.SellerID, fmt.Sprintf("Аукцион #%d завершён без ставок, %s ×%d возвращён в инвентарь.",
+			a.ID, a.ItemName, a.Quantity))
+	}
+	notifyAdmins(fmt.Sprintf("Аукцион #%d завершён без ставок: %s ×%d.", a.ID, a.ItemName, a.Quantity))
+	return nil
+}
+
+// settleAuction закрывает аукцион внутри транзакции: лот уходит лидеру, его ставка –
+// продавцу. Без ставок лот возвращается продавцу. Если анкету лидера удалили,
+// аукцион считается не проданным; если удалили анкету продавца – выручка никому не зачисляется.
+func settleAuction(ctx context.Context, tx db.Store, a *models.Auction) error {
+	a.ClosedAt = time.Now()
+	if a.LeaderID != 0 {
+		if _, err := tx.GetProfileByID(ctx, a.LeaderID); err != nil {
+			a.LeaderID, a.CurrentBid = 0, 0
+		}
+	}
+	sellerExists := false
+	if a.SellerID != 0 {
+		_, err := tx.GetProfileByID(ctx, a.SellerID)
+		sellerExists = err == nil
+	}
+
+	if a.LeaderID == 0 {
+		a.Status = models.AuctionUnsold
+		if sellerExists {
+			if err := tx.AddInventoryItem(ctx, a.SellerID, a.ItemID, a.Quantity, ""); err != nil {
+				return err
+			}
+		}
+		return tx.UpdateAuction(ctx, a)
+	}
+
+	a.Status = models.AuctionSold
+	if err := tx.AddInventoryItem(ctx, a.LeaderID, a.ItemID, a.Quantity, ""); err != nil {
+		return err
+	}
+	if sellerExists {
+		err := tx.ChangeBalance(ctx, &models.LedgerEntry{
+			ProfileID: a.SellerID, Currency: a.Currency, Delta: a.CurrentBid,
+			Reason:     fmt.Sprintf("Продажа на аукционе #%d (%s ×%d)", a.ID, a.ItemName, a.Quantity),
+			SourceType: models.LedgerSourceAuction, SourceID: int64(a.ID),
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка зачисления выручки: %w", err)
+		}
+	}
+	return tx.UpdateAuction(ctx, a)
+}
+
+// handleAdminAuction обрабатывает команду админского бота
+// /auction <предмет> [количество]|<начальная цена> <валюта>|<длительность> – лот администрации.
+func handleAdminAuction(ctx context.Context, bot Sender, chatID int64, args string) {
+	itemRef, quantity, price, duration, err := parseAuctionArgs(ctx, args)
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /auction <предмет> [количество]|<начальная цена> <валюта>|<длительность> ("+err.Error()+")")
+		return
+	}
+	item, err := findItem(ctx, itemRef)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Предмет не найден в справочнике. Добавьте его: /newitem <название>|<описание>")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка поиска предмета: "+err.Error())
+		return
+	}
+	a, err := createAuction(ctx, 0, item, quantity, price, duration)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка создания аукциона: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, "Лот выставлен: "+formatAuction(ctx, currencyLabels(ctx), a))
+}
+
+// handleAdminAuctions обрабатывает команду /auctions – последние аукционы со статусами.
+func handleAdminAuctions(ctx context.Context, bot Sender, chatID int64) {
+	auctions, err := Store.ListAuctions(ctx, false, auctionListLimit)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения аукционов: "+err.Error())
+		return
+	}
+	if len(auctions) == 0 {
+		SendMessage(bot, chatID, "Аукционов нет.")
+		return
+	}
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("Аукционы:\n")
+	for _, a := range auctions {
+		fmt.Fprintf(&b, "%s [%s]\n", formatAuction(ctx, labels, a), auctionStatuses[a.Status])
+	}
+	b.WriteString("Подробнее: /viewauction <номер>")
+	SendMessage(bot, chatID, b.String())
+}
+
+// handleAdminViewAuction обрабатывает команду /viewauction <номер> – аукцион и история ставок.
+func handleAdminViewAuction(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /viewauction <номер аукциона>")
+		return
+	}
+	a, err := Store.GetAuction(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Аукцион не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения аукциона: "+err.Error())
+		return
+	}
+	bids, err := Store.ListBids(ctx, a.ID)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения ставок: "+err.Error())
+		return
+	}
+	seller := "администрация"
+	if a.SellerID != 0 {
+		seller = fmt.Sprintf("%s (ID %d)", profileName(ctx, a.SellerID), a.SellerID)
+	}
+	text := fmt.Sprintf("%s [%s]\nПродавец: %s\nНачальная цена: %d\nОткрыт: %s\nОкончание: %s",
+		formatAuction(ctx, currencyLabels(ctx), a), auctionStatuses[a.Status], seller, a.StartPrice,
+		eventTime(a.CreatedAt), eventTime(a.EndsAt))
+	if len(bids) == 0 {
+		text += "\nСтавок нет."
+	} else {
+		text += "\nСтавки:"
+		for _, bid := range bids {
+			text += fmt.Sprintf("\n%s  %d – %s (ID %d)", eventTime(bid.CreatedAt),
+				bid.Amount, profileName(ctx, bid.ProfileID), bid.ProfileID)
+		}
+	}
+	SendMessage(bot, chatID, text)
+}
+
+// handleAdminCancelAuction обрабатывает команду /cancelauction <номер>: лидеру
+// возвращается ставка, продавцу – лот.
+func handleAdminCancelAuction(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /cancelauction <номер аукциона>")
+		return
+	}
+	var a *models.Auction
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		if a, err = tx.GetAuction(ctx, id); err != nil {
+			return err
+		}
+		if a.Status != models.AuctionOpen {
+			return errAuctionClosed
+		}
+		if a.LeaderID != 0 {
+			err := tx.ChangeBalance(ctx, &models.LedgerEntry{
+				ProfileID: a.LeaderID, Currency: a.Currency, Delta: a.CurrentBid,
+				Reason:     fmt.Sprintf("Возврат ставки: аукцион #%d отменён", a.ID),
+				SourceType: models.LedgerSourceAuction, SourceID: int64(a.ID),
+			})
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("ошибка возврата ставки: %w", err)
+			}
+		}
+		if a.SellerID != 0 {
+			if _, err := tx.GetProfileByID(ctx, a.SellerID); err == nil {
+				if err := tx.AddInventoryItem(ctx, a.SellerID, a.ItemID, a.Quantity, ""); err != nil {
+					return err
+				}
+			}
+		}
+		a.Status = models.AuctionCancelled
+		a.ClosedAt = time.Now()
+		if err := tx.UpdateAuction(ctx, a); err != nil {
+			return err
+		}
+		return tx.CancelJob(ctx, jobAuctionClose, int64(a.ID))
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		SendMessage(bot, chatID, "Аукцион не найден.")
+		return
+	case errors.Is(err, errAuctionClosed):
+		SendMessage(bot, chatID, "Аукцион уже завершён.")
+		return
+	case err != nil:
+		SendMessage(bot, chatID, "Ошибка отмены аукциона: "+err.Error())
+		return
+	}
+
+	SendMessage(bot, chatID, fmt.Sprintf("Аукцион #%d отменён.", a.ID))
+	if a.LeaderID != 0 {
+		notifyProfile(ctx, a.LeaderID, fmt.Sprintf("Аукцион #%d (%s) отменён администратором, ваша ставка %d возвращена.",
+			a.ID, a.ItemName, a.CurrentBid))
+	}
+	if a.SellerID != 0 {
+		notifyProfile(ctx, a.SellerID, fmt.Sprintf("Аукцион #%d отменён администратором, %s ×%d возвращён в инвентарь.",
+			a.ID, a.ItemName, a.Quantity))
+	}
+}
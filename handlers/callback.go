@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// HandleCallbackQuery обрабатывает нажатия inline-кнопок пользовательского бота.
+// Данные кнопки имеют вид "<раздел>:<действие>:<параметры>", раздел выбирает обработчик.
+func HandleCallbackQuery(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery) {
+	section, rest, _ := strings.Cut(cq.Data, ":")
+	switch section {
+	case "pay":
+		handlePayCallback(ctx, bot, cq, rest)
+	case "shop":
+		handleShopCallback(ctx, bot, cq, rest)
+	case "trade":
+		handleTradeCallback(ctx, bot, cq, rest)
+	case "exchange":
+		handleExchangeCallback(ctx, bot, cq, rest)
+	case "event":
+		handleEventCallback(ctx, bot, cq, rest)
+	default:
+		answerCallback(bot, cq, "Кнопка больше не действует.")
+	}
+}
+
+// answerCallback отвечает на нажатие кнопки: Telegram убирает индикатор загрузки
+// и показывает text (если он не пустой) во всплывающем уведомлении.
+func answerCallback(bot Sender, cq *tgbotapi.CallbackQuery, text string) {
+	bot.Request(tgbotapi.NewCallback(cq.ID, text))
+}
+
+// editCallbackMessage заменяет текст сообщения, к которому была привязана кнопка,
+// и убирает клавиатуру.
+func editCallbackMessage(bot Sender, cq *tgbotapi.CallbackQuery, text string) {
+	if cq.Message == nil {
+		return
+	}
+	bot.Send(tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, text))
+}
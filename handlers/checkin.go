@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/config"
+	"telegram-bot/db"
+	"telegram-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	checkinMaxFailures = 5                // неверных кодов подряд до блокировки
+	checkinLockout     = 15 * time.Minute // на сколько блокируется отметка
+	checkinMinRotate   = time.Minute
+	checkinMaxRotate   = 24 * time.Hour
+	checkinLinkPrefix  = "attend_" // параметр /start в ссылке для отметки: attend_<ID>_<код>
+	checkinQRSize      = 512       // сторона QR-кода ссылки для отметки в пикселях
+)
+
+// jobEventCheckin – рассылка администраторам нового кода отметки при его смене; RefID – ID события.
+const jobEventCheckin = "event.checkin"
+
+// newCheckinSecret создаёт случайный секрет, из которого выводятся коды отметки события.
+func newCheckinSecret() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkinCodeAt – шестизначный код для периода counter: HMAC от секрета события,
+// поэтому код нельзя угадать по ID события или предыдущим кодам.
+func checkinCodeAt(secret string, counter int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint32(sum[:4])%1000000)
+}
+
+// checkinCounter – номер периода действия кода; для постоянного кода всегда 0.
+func checkinCounter(e *models.Event, t time.Time) int64 {
+	if e.CheckinRotate <= 0 {
+		return 0
+	}
+	return t.Unix() / int64(e.CheckinRotate/time.Second)
+}
+
+// checkinCode – код отметки, действующий в момент t.
+func checkinCode(e *models.Event, t time.Time) string {
+	return checkinCodeAt(e.CheckinSecret, checkinCounter(e, t))
+}
+
+// validCheckinCode проверяет код. Меняющийся код принимается и в течение
+// следующего периода, чтобы код, прочитанный перед сменой, не пропадал.
+func validCheckinCode(e *models.Event, code string, now time.Time) bool {
+	counter := checkinCounter(e, now)
+	if hmac.Equal([]byte(code), []byte(checkinCodeAt(e.CheckinSecret, counter))) {
+		return true
+	}
+	return e.CheckinRotate > 0 && hmac.Equal([]byte(code), []byte(checkinCodeAt(e.CheckinSecret, counter-1)))
+}
+
+// parseCheckinOption разбирает часть /createevent "код [период смены]", уже разбитую на слова.
+// Возвращает, как часто меняется код; 0 – код постоянный.
+func parseCheckinOption(fields []string) (time.Duration, error) {
+	switch len(fields) {
+	case 1:
+		return 0, nil
+	case 2:
+		rotate, err := parseDuration(fields[1], checkinMinRotate, checkinMaxRotate)
+		if err != nil {
+			return 0, fmt.Errorf("период смены кода: %w", err)
+		}
+		return rotate.Truncate(time.Minute), nil
+	default:
+		return 0, fmt.Errorf("неверный код отметки %q: используйте «код» или, например, «код 5m»", strings.Join(fields, " "))
+	}
+}
+
+// checkinLink – ссылка на пользовательского бота, которая сразу отмечает на событии,
+// или "", если имя бота неизвестно. Администраторы получают её и как QR-код.
+func checkinLink(e *models.Event, now time.Time) string {
+	if PrimaryBotUsername == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/%s?start=%s%d_%s", PrimaryBotUsername, checkinLinkPrefix, e.ID, checkinCode(e, now))
+}
+
+// parseCheckinLink разбирает параметр /start из ссылки для отметки.
+func parseCheckinLink(payload string) (eventID int, code string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(payload), checkinLinkPrefix)
+	if !ok {
+		return 0, "", false
+	}
+	idStr, code, ok := strings.Cut(rest, "_")
+	if !ok {
+		return 0, "", false
+	}
+	eventID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return 0, "", false
+	}
+	return eventID, code, true
+}
+
+// checkinText – код отметки и ссылка для администраторов.
+func checkinText(e *models.Event, now time.Time) string {
+	text := "Код отметки: " + checkinCode(e, now)
+	if e.CheckinRotate > 0 {
+		// Код принимается до конца следующего периода, см. validCheckinCode.
+		validUntil := time.Unix((checkinCounter(e, now)+2)*int64(e.CheckinRotate/time.Second), 0)
+		text += fmt.Sprintf(" (меняется каждые %d мин., этот код действует до %s; текущий код: /eventcode %d)",
+			int(e.CheckinRotate.Minutes()), eventTime(validUntil), e.ID)
+	}
+	if link := checkinLink(e, now); link != "" {
+		text += "\nСсылка для отметки: " + link
+	}
+	return text
+}
+
+// sendCheckin отправляет в чат chatID код отметки и QR-код ссылки для отметки,
+// который ведущий может показать участникам. Если ссылки нет (имя бота неизвестно)
+// или QR-код не получился, отправляется только текст.
+func sendCheckin(bot Sender, chatID int64, e *models.Event, now time.Time) {
+	text := fmt.Sprintf("Событие «%s» (ID %d).\n%s", e.Name, e.ID, checkinText(e, now))
+	link := checkinLink(e, now)
+	if link == "" {
+		SendMessage(bot, chatID, text)
+		return
+	}
+	png, err := qrcode.Encode(link, qrcode.Medium, checkinQRSize)
+	if err != nil {
+		log.Printf("Ошибка создания QR-кода отметки на событии %d: %v", e.ID, err)
+		SendMessage(bot, chatID, text)
+		return
+	}
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: fmt.Sprintf("checkin_%d.png", e.ID), Bytes: png})
+	photo.Caption = text
+	if _, err := bot.Send(photo); err != nil {
+		log.Printf("Ошибка отправки QR-кода отметки на событии %d: %v", e.ID, err)
+	}
+}
+
+// notifyAdminsCheckin отправляет код и QR-код отметки во все чаты администраторов.
+func notifyAdminsCheckin(e *models.Event, now time.Time) {
+	if AdminBot == nil {
+		log.Println("Админский бот не инициализирован")
+		return
+	}
+	for _, chatID := range config.Current.Admin.ChatIDs {
+		sendCheckin(AdminBot, chatID, e, now)
+	}
+}
+
+// nextCheckinRotation – когда разослать администраторам новый код отметки: в момент
+// начала события, затем при каждой смене кода. Нулевое время – рассылать не нужно:
+// код постоянный или событие закончится раньше.
+func nextCheckinRotation(e *models.Event, now time.Time) time.Time {
+	if e.CheckinSecret == "" || e.CheckinRotate <= 0 {
+		return time.Time{}
+	}
+	at := e.StartsAt
+	if !now.Before(at) {
+		at = time.Unix((checkinCounter(e, now)+1)*int64(e.CheckinRotate/time.Second), 0)
+	}
+	if !e.EndsAt.IsZero() && !at.Before(e.EndsAt) {
+		return time.Time{}
+	}
+	return at
+}
+
+// checkinRotationJob – задача планировщика: при смене кода отметки рассылает
+// администраторам новый код и QR-код и планирует следующую рассылку.
+func checkinRotationJob(ctx context.Context, job *models.Job) error {
+	e, err := Store.GetEventByID(ctx, int(job.RefID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !e.Active {
+		return nil
+	}
+	now := time.Now()
+	if !now.Before(e.StartsAt) {
+		notifyAdminsCheckin(e, now)
+	}
+	next := nextCheckinRotation(e, now)
+	if next.IsZero() {
+		return nil
+	}
+	return Store.ScheduleJob(ctx, jobEventCheckin, int64(e.ID), next)
+}
+
+// verifyCheckin проверяет код отметки на событии и считает неверные попытки.
+// Возвращает ответ пользователю или "", если код верный.
+func verifyCheckin(ctx context.Context, e *models.Event, telegramID int64, code string) string {
+	now := time.Now()
+	a, err := Store.GetCheckinAttempts(ctx, e.ID, telegramID)
+	if err != nil {
+		log.Printf("Ошибка проверки кода отметки: %v", err)
+		return "Ошибка проверки кода: " + err.Error()
+	}
+	if now.Before(a.LockedUntil) {
+		return "Слишком много неверных кодов. Попробуйте снова после " + eventTime(a.LockedUntil) + "."
+	}
+	if code == "" {
+		return fmt.Sprintf("Для отметки на этом событии нужен код: /attend %d <код>. Код сообщает ведущий.", e.ID)
+	}
+	if validCheckinCode(e, code, now) {
+		if a.Failures > 0 {
+			if err := Store.ResetCheckinAttempts(ctx, e.ID, telegramID); err != nil {
+				log.Printf("Ошибка сброса неверных кодов отметки: %v", err)
+			}
+		}
+		return ""
+	}
+
+	var text string
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		// Перечитываем блокировку в транзакции: параллельный неверный код мог её уже поставить.
+		a, err := tx.GetCheckinAttempts(ctx, e.ID, telegramID)
+		if err != nil {
+			return err
+		}
+		if now.Before(a.LockedUntil) {
+			text = "Слишком много неверных кодов. Попробуйте снова после " + eventTime(a.LockedUntil) + "."
+			return nil
+		}
+		failures, err := tx.AddCheckinFailure(ctx, e.ID, telegramID)
+		if err != nil {
+			return err
+		}
+		if failures < checkinMaxFailures {
+			text = fmt.Sprintf("Неверный код. Осталось попыток: %d.", checkinMaxFailures-failures)
+			return nil
+		}
+		lockedUntil := now.Add(checkinLockout)
+		text = "Неверный код. Отметка на событии заблокирована до " + eventTime(lockedUntil) + "."
+		return tx.LockCheckinAttempts(ctx, e.ID, telegramID, lockedUntil)
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения неверного кода отметки: %v", err)
+		return "Ошибка проверки кода: " + err.Error()
+	}
+	return text
+}
+
+// handleAdminEventCode обрабатывает команду админского бота /eventcode <ID> –
+// текущий код отметки события, ссылка для отметки и её QR-код.
+func handleAdminEventCode(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := parseEventID(args)
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /eventcode <ID события>")
+		return
+	}
+	e, err := Store.GetEventByID(ctx, id)
+	if err != nil {
+		SendMessage(bot, chatID, "Событие не найдено.")
+		return
+	}
+	if e.CheckinSecret == "" {
+		SendMessage(bot, chatID, "Для отметки на этом событии код не нужен.")
+		return
+	}
+	sendCheckin(bot, chatID, e, time.Now())
+}
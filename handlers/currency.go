@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+	"telegram-bot/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// currencyCodePattern – допустимый код новой валюты.
+var currencyCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// resolveCurrency находит валюту из справочника по коду, названию или алиасу.
+// Если activeOnly, для выведенной из оборота валюты возвращается errCurrencyRetired.
+func resolveCurrency(ctx context.Context, store db.Store, name string, activeOnly bool) (*models.Currency, error) {
+	c, err := store.ResolveCurrency(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if activeOnly && c.Retired {
+		return nil, errCurrencyRetired
+	}
+	return c, nil
+}
+
+// currencyErrorText – ответ пользователю на ошибку resolveCurrency.
+func currencyErrorText(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, db.ErrUnknownCurrency):
+		return "Неизвестный тип валюты. " + currencyHint(ctx)
+	case errors.Is(err, errCurrencyRetired):
+		return "Эта валюта выведена из оборота. " + currencyHint(ctx)
+	}
+	return "Ошибка получения валюты: " + err.Error()
+}
+
+// currencyHint перечисляет действующие валюты для подсказок в ответах.
+func currencyHint(ctx context.Context) string {
+	currencies, err := Store.ListCurrencies(ctx, false)
+	if err != nil || len(currencies) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		names = append(names, fmt.Sprintf("%s (%s)", c.Code, c.Label()))
+	}
+	return "Доступные валюты: " + strings.Join(names, ", ")
+}
+
+// historyLimit – сколько последних операций показывают /history и /ledger.
+const historyLimit = 20
+
+// HandleBalance обрабатывает команду /balance – текущий баланс пользователя.
+func HandleBalance(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	SendMessage(bot, msg.Chat.ID, utils.FormatBalance(profile))
+}
+
+// HandleHistory обрабатывает команду /history – последние операции с валютой.
+func HandleHistory(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	entries, err := Store.GetLedger(ctx, profile.ID, historyLimit)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения истории: "+err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		SendMessage(bot, msg.Chat.ID, "Операций с валютой пока не было.")
+		return
+	}
+	SendMessage(bot, msg.Chat.ID, "Последние операции:\n"+utils.FormatLedger(entries, false))
+}
+
+// handleAdminLedger обрабатывает команду админского бота /ledger <ID анкеты>.
+func handleAdminLedger(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Fields(args)
+	if len(parts) != 1 {
+		SendMessage(bot, chatID, "Используйте: /ledger <ID>")
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		SendMessage(bot, chatID, "Неверный ID анкеты.")
+		return
+	}
+	profile, err := Store.GetProfileByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Анкета не найдена.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения анкеты: "+err.Error())
+		return
+	}
+	entries, err := Store.GetLedger(ctx, id, historyLimit)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения журнала: "+err.Error())
+		return
+	}
+	text := fmt.Sprintf("Журнал операций анкеты ID %d (@%s)\n%s\n\n", profile.ID, profile.Username, utils.FormatBalance(profile))
+	if len(entries) == 0 {
+		text += "Операций нет."
+	} else {
+		text += utils.FormatLedger(entries, true)
+	}
+	SendMessage(bot, chatID, text)
+}
+
+// handleAdminCurrencies обрабатывает команду админского бота /currencies – справочник валют.
+func handleAdminCurrencies(ctx context.Context, bot Sender, chatID int64) {
+	currencies, err := Store.ListCurrencies(ctx, true)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения валют: "+err.Error())
+		return
+	}
+	if len(currencies) == 0 {
+		SendMessage(bot, chatID, "Валют нет. Добавьте: /newcurrency <код>|<название>|<алиасы>|<иконка>")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("Валюты:\n")
+	for _, c := range currencies {
+		fmt.Fprintf(&b, "%s – %s", c.Code, c.Label())
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(&b, " (алиасы: %s)", strings.Join(c.Aliases, ", "))
+		}
+		if c.Retired {
+			b.WriteString(" [выведена из оборота]")
+		}
+		b.WriteString("\n")
+	}
+	SendMessage(bot, chatID, strings.TrimRight(b.String(), "\n"))
+}
+
+// parseAliases разбирает список алиасов через запятую.
+func parseAliases(s string) []string {
+	var aliases []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+			aliases = append(aliases, a)
+		}
+	}
+	return aliases
+}
+
+// handleAdminNewCurrency обрабатывает команду /newcurrency <код>|<название>|<алиасы>|<иконка>.
+// Алиасы (через запятую) и иконка необязательны.
+func handleAdminNewCurrency(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Split(args, "|")
+	if len(parts) < 2 || len(parts) > 4 {
+		SendMessage(bot, chatID, "Используйте: /newcurrency <код>|<название>|<алиасы через запятую>|<иконка>")
+		return
+	}
+	c := &models.Currency{
+		Code: strings.ToLower(strings.TrimSpace(parts[0])),
+		Name: strings.TrimSpace(parts[1]),
+	}
+	if !currencyCodePattern.MatchString(c.Code) {
+		SendMessage(bot, chatID, "Код валюты – латинские буквы, цифры и _, от 2 до 32 символов, например: gems")
+		return
+	}
+	if c.Name == "" {
+		SendMessage(bot, chatID, "Название валюты не может быть пустым.")
+		return
+	}
+	if len(parts) > 2 {
+		c.Aliases = parseAliases(parts[2])
+	}
+	if len(parts) > 3 {
+		c.Icon = strings.TrimSpace(parts[3])
+	}
+	err := Store.CreateCurrency(ctx, c)
+	if errors.Is(err, db.ErrCurrencyConflict) {
+		SendMessage(bot, chatID, "Не удалось добавить валюту: "+err.Error())
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка добавления валюты: "+err.Error())
+		return
+	}
+	log.Printf("Добавлена валюта %s (%s)", c.Code, c.Name)
+	SendMessage(bot, chatID, fmt.Sprintf("Валюта %s добавлена: %s", c.Code, c.Label()))
+}
+
+// handleAdminRenameCurrency обрабатывает команду /renamecurrency <валюта>|<название>|<алиасы>|<иконка>.
+// Алиасы и иконка заменяются, только если указаны.
+func handleAdminRenameCurrency(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Split(args, "|")
+	if len(parts) < 2 || len(parts) > 4 {
+		SendMessage(bot, chatID, "Используйте: /renamecurrency <валюта>|<новое название>|<алиасы через запятую>|<иконка>")
+		return
+	}
+	c, err := resolveCurrency(ctx, Store, parts[0], false)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	name := strings.TrimSpace(parts[1])
+	if name == "" {
+		SendMessage(bot, chatID, "Название валюты не может быть пустым.")
+		return
+	}
+	old := c.Label()
+	c.Name = name
+	if len(parts) > 2 {
+		c.Aliases = parseAliases(parts[2])
+	}
+	if len(parts) > 3 {
+		c.Icon = strings.TrimSpace(parts[3])
+	}
+	err = Store.UpdateCurrency(ctx, c)
+	if errors.Is(err, db.ErrCurrencyConflict) {
+		SendMessage(bot, chatID, "Не удалось переименовать валюту: "+err.Error())
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка обновления валюты: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Валюта %s: %s → %s", c.Code, old, c.Label()))
+}
+
+// handleAdminRetireCurrency обрабатывает команды /retirecurrency и /restorecurrency.
+// Выведенная из оборота валюта остаётся в балансах и журнале, но не используется
+// для новых начислений и событий.
+func handleAdminRetireCurrency(ctx context.Context, bot Sender, chatID int64, args string, retired bool) {
+	c, err := resolveCurrency(ctx, Store, args, false)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	if c.Retired == retired {
+		SendMessage(bot, chatID, "Статус валюты не изменился.")
+		return
+	}
+	c.Retired = retired
+	if err := Store.UpdateCurrency(ctx, c); err != nil {
+		SendMessage(bot, chatID, "Ошибка обновления валюты: "+err.Error())
+		return
+	}
+	if retired {
+		SendMessage(bot, chatID, fmt.Sprintf("Валюта %s выведена из оборота.", c.Label()))
+	} else {
+		SendMessage(bot, chatID, fmt.Sprintf("Валюта %s снова в обороте.", c.Label()))
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/config"
+	"telegram-bot/db"
+	"telegram-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	errDailyClaimed       = errors.New("награда уже получена")
+	errDailyNotConfigured = errors.New("награды не настроены")
+)
+
+// gameNow – текущее время в часовом поясе игровых суток (параметр timezone).
+func gameNow() time.Time {
+	return time.Now().In(config.Current.Location())
+}
+
+// dailyReward возвращает награду для дня серии streak – ступень таблицы
+// с наибольшим днём, не превышающим streak.
+func dailyReward(rewards []models.DailyReward, streak int) []models.Price {
+	day := 0
+	for _, r := range rewards {
+		if r.Day <= streak && r.Day > day {
+			day = r.Day
+		}
+	}
+	var prices []models.Price
+	for _, r := range rewards {
+		if r.Day == day {
+			prices = append(prices, models.Price{Currency: r.Currency, Amount: r.Amount})
+		}
+	}
+	return prices
+}
+
+// currentStreak – серия, которая ещё не прервалась к сегодняшним игровым суткам.
+func currentStreak(ds *models.DailyStreak, now time.Time) int {
+	if ds.LastDay == now.Format(time.DateOnly) || ds.LastDay == now.AddDate(0, 0, -1).Format(time.DateOnly) {
+		return ds.Streak
+	}
+	return 0
+}
+
+// dailyStreakText – строка о серии ежедневных наград для /profile.
+func dailyStreakText(ctx context.Context, profileID int) string {
+	ds, err := Store.GetDailyStreak(ctx, profileID)
+	if err != nil {
+		log.Printf("Ошибка получения серии наград анкеты %d: %v", profileID, err)
+		return ""
+	}
+	return fmt.Sprintf("\nСерия /daily: %d дн. подряд (лучшая: %d)", currentStreak(ds, gameNow()), ds.Best)
+}
+
+// HandleDaily обрабатывает команду /daily – ежедневная награда. Награда выдаётся
+// раз в игровые сутки и растёт с серией дней подряд; пропуск дня сбрасывает серию.
+func HandleDaily(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	now := gameNow()
+	today, yesterday := now.Format(time.DateOnly), now.AddDate(0, 0, -1).Format(time.DateOnly)
+
+	var ds *models.DailyStreak
+	var rewards []models.DailyReward
+	var paid []models.Price
+	var broken bool
+	var repaid strings.Builder
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		profile, err := tx.GetProfile(ctx, msg.From.ID)
+		if err != nil {
+			return errProfileNotFound
+		}
+		if ds, err = tx.GetDailyStreak(ctx, profile.ID); err != nil {
+			return err
+		}
+		if ds.LastDay == today {
+			return errDailyClaimed
+		}
+		if rewards, err = tx.ListDailyRewards(ctx); err != nil {
+			return err
+		}
+		if len(rewards) == 0 {
+			return errDailyNotConfigured
+		}
+
+		if ds.LastDay == yesterday {
+			ds.Streak++
+		} else {
+			broken = ds.Streak > 1
+			ds.Streak = 1
+		}
+		ds.Best = max(ds.Best, ds.Streak)
+		ds.LastDay = today
+		for _, p := range dailyReward(rewards, ds.Streak) {
+			c, err := tx.GetCurrency(ctx, p.Currency)
+			if err != nil {
+				return err
+			}
+			if c.Retired {
+				continue
+			}
+			credit := &models.LedgerEntry{
+				ProfileID: profile.ID, Currency: p.Currency, Delta: p.Amount,
+				Reason:     fmt.Sprintf("Ежедневная награда, день %d", ds.Streak),
+				SourceType: models.LedgerSourceDaily, SourceID: int64(ds.Streak),
+			}
+			if err := tx.ChangeBalance(ctx, credit); err != nil {
+				return err
+			}
+			paid = append(paid, p)
+			repaid.WriteString(debtRepaymentText(c.Label(), credit))
+		}
+		return tx.SaveDailyStreak(ctx, ds)
+	})
+	switch {
+	case errors.Is(err, errProfileNotFound):
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	case errors.Is(err, errDailyClaimed):
+		SendMessage(bot, msg.Chat.ID, fmt.Sprintf("Сегодня награда уже получена. Приходите завтра!\nСерия: %d дн. подряд.", ds.Streak))
+		return
+	case errors.Is(err, errDailyNotConfigured):
+		SendMessage(bot, msg.Chat.ID, "Ежедневные награды сейчас не выдаются.")
+		return
+	case err != nil:
+		log.Printf("Ошибка выдачи ежедневной награды: %v", err)
+		SendMessage(bot, msg.Chat.ID, "Ошибка выдачи награды: "+err.Error())
+		return
+	}
+
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	if broken {
+		b.WriteString("Серия прервалась – начинаем заново.\n")
+	}
+	fmt.Fprintf(&b, "Ежедневная награда: %s.\nСерия: %d дн. подряд (лучшая: %d).\nЗавтра: %s.",
+		formatPrices(labels, paid), ds.Streak, ds.Best, formatPrices(labels, dailyReward(rewards, ds.Streak+1)))
+	b.WriteString(repaid.String())
+	SendMessage(bot, msg.Chat.ID, b.String())
+}
+
+// handleAdminDailyRewards обрабатывает команду админского бота /dailyrewards – таблица наград /daily.
+func handleAdminDailyRewards(ctx context.Context, bot Sender, chatID int64) {
+	rewards, err := Store.ListDailyRewards(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения наград: "+err.Error())
+		return
+	}
+	if len(rewards) == 0 {
+		SendMessage(bot, chatID, "Ежедневные награды не настроены. Добавить: /setdailyreward <день серии> <сумма> <валюта>")
+		return
+	}
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("Награды /daily по дням серии:")
+	for i, r := range rewards {
+		if i > 0 && rewards[i-1].Day == r.Day {
+			continue
+		}
+		fmt.Fprintf(&b, "\nС %d-го дня: %s", r.Day, formatPrices(labels, dailyReward(rewards, r.Day)))
+	}
+	b.WriteString("\nИзменить: /setdailyreward <день серии> <сумма> <валюта> (0 – убрать)")
+	SendMessage(bot, chatID, b.String())
+}
+
+// handleAdminSetDailyReward обрабатывает команду /setdailyreward <день серии> <сумма> <валюта>.
+func handleAdminSetDailyReward(ctx context.Context, bot Sender, chatID int64, args string) {
+	const usage = "Используйте: /setdailyreward <день серии> <сумма> <валюта> (сумма 0 убирает валюту из ступени)"
+	parts := strings.Fields(args)
+	if len(parts) != 3 {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	day, err := strconv.Atoi(parts[0])
+	if err != nil || day < 1 {
+		SendMessage(bot, chatID, "День серии должен быть положительным числом.\n"+usage)
+		return
+	}
+	amount, err := strconv.Atoi(parts[1])
+	if err != nil || amount < 0 {
+		SendMessage(bot, chatID, "Сумма должна быть неотрицательным числом.\n"+usage)
+		return
+	}
+	currency, err := resolveCurrency(ctx, Store, parts[2], amount > 0)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	err = Store.SetDailyReward(ctx, models.DailyReward{Day: day, Currency: currency.Code, Amount: amount})
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка сохранения награды: "+err.Error())
+		return
+	}
+	handleAdminDailyRewards(ctx, bot, chatID)
+}
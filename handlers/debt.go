@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"telegram-bot/models"
+)
+
+// errInDebt – у персонажа отрицательный баланс: покупки и переводы недоступны, пока долг не погашен.
+var errInDebt = errors.New("есть непогашенный долг")
+
+// formatDebts – долги через запятую, например "20 🪙 Пиастры".
+func formatDebts(debts []models.Balance) string {
+	parts := make([]string, len(debts))
+	for i, d := range debts {
+		parts[i] = fmt.Sprintf("%d %s", -d.Amount, d.Label())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkNoDebt возвращает errInDebt, если у персонажа есть отрицательный баланс.
+func checkNoDebt(p *models.Profile) error {
+	if debts := p.Debts(); len(debts) > 0 {
+		return fmt.Errorf("%w: %s", errInDebt, formatDebts(debts))
+	}
+	return nil
+}
+
+// debtText – предупреждение о долгах персонажа или "", если долгов нет.
+func debtText(p *models.Profile) string {
+	debts := p.Debts()
+	if len(debts) == 0 {
+		return ""
+	}
+	return "\nДолг: " + formatDebts(debts) + ". Пока долг не погашен, покупки, ставки, переводы и обмены " +
+		"с персонажами недоступны; долг гасится автоматически из будущих начислений."
+}
+
+// debtRepaymentText – пояснение для начисления e, если оно ушло на погашение долга, иначе "".
+// Отдельного погашения нет: начисление просто увеличивает отрицательный баланс.
+func debtRepaymentText(label string, e *models.LedgerEntry) string {
+	before := e.BalanceAfter - e.Delta
+	if e.Delta <= 0 || before >= 0 {
+		return ""
+	}
+	repaid := min(e.Delta, -before)
+	if e.BalanceAfter < 0 {
+		return fmt.Sprintf("\nВ счёт долга удержано %d %s, осталось долга: %d.", repaid, label, -e.BalanceAfter)
+	}
+	return fmt.Sprintf("\nДолг %d %s погашен.", repaid, label)
+}
+
+// handleAdminFine обрабатывает команду админского бота /fine <ID> <сумма> <валюта> <причина>.
+// Штраф списывается даже при нехватке средств: баланс уходит в минус и становится долгом.
+func handleAdminFine(ctx context.Context, bot Sender, chatID, adminID int64, args string) {
+	const usage = "Используйте: /fine <ID анкеты или @username> <сумма> <валюта> <причина>"
+	parts := strings.Fields(args)
+	if len(parts) < 4 {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	amount, err := strconv.Atoi(parts[1])
+	if err != nil || amount <= 0 {
+		SendMessage(bot, chatID, "Сумма штрафа должна быть положительным числом.\n"+usage)
+		return
+	}
+	currency, err := resolveCurrency(ctx, Store, parts[2], false)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	profile, err := findProfile(ctx, parts[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Анкета не найдена.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка поиска анкеты: "+err.Error())
+		return
+	}
+	reason := strings.Join(parts[3:], " ")
+	entry := &models.LedgerEntry{
+		ProfileID: profile.ID, Currency: currency.Code, Delta: -amount,
+		Reason:     "Штраф: " + reason,
+		SourceType: models.LedgerSourceFine, SourceID: adminID,
+	}
+	if err := Store.ChangeBalance(ctx, entry); err != nil {
+		SendMessage(bot, chatID, "Ошибка списания штрафа: "+err.Error())
+		return
+	}
+
+	text := fmt.Sprintf("Вам назначен штраф: %d %s.\nПричина: %s\nБаланс: %d %s.",
+		amount, currency.Label(), reason, entry.BalanceAfter, currency.Label())
+	if entry.BalanceAfter < 0 {
+		text += fmt.Sprintf("\nДолг: %d %s. Пока долг не погашен, покупки, ставки, переводы и обмены с персонажами недоступны; "+
+			"долг гасится автоматически из будущих начислений в этой валюте.", -entry.BalanceAfter, currency.Label())
+	}
+	notifyProfile(ctx, profile.ID, text)
+	SendMessage(bot, chatID, fmt.Sprintf("Штраф %d %s назначен персонажу %s (ID %d). Баланс: %d.",
+		amount, currency.Label(), profile.Name, profile.ID, entry.BalanceAfter))
+}
+
+// handleAdminDebts обрабатывает команду /debts – персонажи с отрицательным балансом.
+func handleAdminDebts(ctx context.Context, bot Sender, chatID int64) {
+	profiles, err := Store.GetAllProfiles(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения анкет: "+err.Error())
+		return
+	}
+	var b strings.Builder
+	for _, p := range profiles {
+		if debts := p.Debts(); len(debts) > 0 {
+			fmt.Fprintf(&b, "\nID %d (%s): %s", p.ID, p.Name, formatDebts(debts))
+		}
+	}
+	if b.Len() == 0 {
+		SendMessage(bot, chatID, "Долгов нет.")
+		return
+	}
+	SendMessage(bot, chatID, "Долги персонажей:"+b.String())
+}
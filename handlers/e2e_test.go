@@ -0,0 +1,547 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram-bot/config"
+	"telegram-bot/db"
+	"telegram-bot/handlers"
+	"telegram-bot/models"
+	"telegram-bot/telegramtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	testAdminID     = 7   // Telegram ID администратора
+	testAdminChatID = 999 // чат администраторов из config.Admin.ChatIDs
+)
+
+// testEnv – бот на поддельном Bot API и пустой базе во временном каталоге.
+type testEnv struct {
+	t     *testing.T
+	ctx   context.Context
+	srv   *telegramtest.Server
+	bot   *tgbotapi.BotAPI
+	admin *tgbotapi.BotAPI
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := db.InitDB("file:" + filepath.Join(t.TempDir(), "bot.db") + "?_txlock=immediate&_pragma=busy_timeout(5000)")
+	srv := telegramtest.NewServer()
+	bot, err := srv.Bot("1:primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := srv.Bot("2:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Тест меняет копию конфигурации, чтобы изменения не попали в другие тесты.
+	prevConfig := config.Current
+	cfg := *prevConfig
+	cfg.Admin.ChatIDs = []int64{testAdminChatID}
+	cfg.Admin.Password = "secret"
+	config.Current = &cfg
+	handlers.Store, handlers.PrimaryBot, handlers.AdminBot = store, bot, admin
+	t.Cleanup(func() {
+		handlers.Store, handlers.PrimaryBot, handlers.AdminBot = nil, nil, nil
+		config.Current = prevConfig
+		store.Close()
+		srv.Close()
+	})
+
+	env := &testEnv{t: t, ctx: context.Background(), srv: srv, bot: bot, admin: admin}
+	env.adminSay("/auth secret")
+	return env
+}
+
+// say отправляет пользовательскому боту сообщение или команду от userID.
+func (e *testEnv) say(userID int64, text string) {
+	update := telegramtest.Text(userID, text)
+	if strings.HasPrefix(text, "/") {
+		update = telegramtest.Command(userID, text)
+	}
+	handlers.HandleUpdate(e.ctx, e.bot, update)
+}
+
+// adminSay отправляет команду админскому боту от администратора.
+func (e *testEnv) adminSay(text string) {
+	handlers.HandleAdminUpdate(e.ctx, e.admin, telegramtest.Command(testAdminID, text))
+}
+
+// lastText – последнее сообщение, отправленное в чат chatID.
+func (e *testEnv) lastText(chatID int64) string {
+	e.t.Helper()
+	texts := e.srv.Texts(chatID)
+	if len(texts) == 0 {
+		e.t.Fatalf("в чат %d ничего не отправлено", chatID)
+	}
+	return texts[len(texts)-1]
+}
+
+// register проходит регистрацию анкеты за пользователя userID.
+func (e *testEnv) register(userID int64, name string) *models.Profile {
+	e.t.Helper()
+	e.say(userID, "/start")
+	for _, answer := range []string{name, "20", "180", "70", "меч", "", "рядовой", "альфа", "эльф"} {
+		e.say(userID, answer)
+	}
+	profile, err := handlers.Store.GetProfile(e.ctx, userID)
+	if err != nil {
+		e.t.Fatalf("анкета %d не сохранена: %v", userID, err)
+	}
+	return profile
+}
+
+// balance – баланс пользователя userID в валюте code.
+func (e *testEnv) balance(userID int64, code string) int {
+	e.t.Helper()
+	profile, err := handlers.Store.GetProfile(e.ctx, userID)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return profile.Balance(code)
+}
+
+// ledger – операции журнала анкеты от старых к новым.
+func (e *testEnv) ledger(profileID int) []*models.LedgerEntry {
+	e.t.Helper()
+	entries, err := handlers.Store.GetLedger(e.ctx, profileID, 100)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// assertNoDrifts проверяет, что журнал сходится с участием в событиях и начислениями.
+func (e *testEnv) assertNoDrifts() {
+	e.t.Helper()
+	drifts, err := handlers.Store.LedgerDrifts(e.ctx)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	if len(drifts) > 0 {
+		e.t.Errorf("расхождения журнала: %+v", drifts)
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	env := newTestEnv(t)
+	env.say(42, "/start")
+	if got := env.lastText(42); !strings.Contains(got, "Введите ваше имя") {
+		t.Fatalf("/start ответил %q", got)
+	}
+	env.say(42, "Вася")
+	env.say(42, "двадцать")
+	if got := env.lastText(42); !strings.Contains(got, "Возраст должен быть числом") {
+		t.Fatalf("неверный возраст: ответ %q", got)
+	}
+	for _, answer := range []string{"20", "180.5", "70", "меч", "", "рядовой", "альфа", "эльф"} {
+		env.say(42, answer)
+	}
+
+	texts := env.srv.Texts(42)
+	if !containsText(texts, "Профиль успешно создан!") {
+		t.Fatalf("регистрация не завершилась: %q", texts)
+	}
+	profile, err := handlers.Store.GetProfile(env.ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Name != "Вася" || profile.Age != 20 || profile.Height != 180.5 || profile.Race != "эльф" {
+		t.Errorf("сохранена анкета %+v", profile)
+	}
+	if !containsText(env.srv.Texts(testAdminChatID), "Вася") {
+		t.Error("анкета не отправлена администраторам")
+	}
+
+	env.say(42, "/start")
+	if got := env.lastText(42); !strings.Contains(got, "Ваша анкета уже создана") {
+		t.Errorf("повторный /start ответил %q", got)
+	}
+}
+
+func TestAttendCreditsReward(t *testing.T) {
+	env := newTestEnv(t)
+	profile := env.register(42, "Вася")
+	env.adminSay("/createevent Бал|piastres|30|2h")
+	if got := env.lastText(testAdminID); !strings.Contains(got, "Событие создано") {
+		t.Fatalf("/createevent ответил %q", got)
+	}
+
+	env.say(42, "/attend 1")
+	if got := env.lastText(42); !strings.Contains(got, "Вы успешно приняли участие") {
+		t.Fatalf("/attend ответил %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 30 {
+		t.Errorf("баланс после /attend = %d, want 30", got)
+	}
+	entries := env.ledger(profile.ID)
+	if len(entries) != 1 {
+		t.Fatalf("в журнале %d операций, want 1", len(entries))
+	}
+	if e := entries[0]; e.Delta != 30 || e.BalanceAfter != 30 || e.SourceType != models.LedgerSourceEvent || e.SourceID != 1 {
+		t.Errorf("операция журнала %+v", e)
+	}
+
+	env.say(42, "/attend 1")
+	if got := env.lastText(42); !strings.Contains(got, "уже приняли участие") {
+		t.Errorf("повторный /attend ответил %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 30 {
+		t.Errorf("повторный /attend изменил баланс: %d", got)
+	}
+	env.assertNoDrifts()
+}
+
+func TestUnattendRefunds(t *testing.T) {
+	env := newTestEnv(t)
+	profile := env.register(42, "Вася")
+	env.adminSay("/createevent Бал|piastres|30|2h")
+	env.say(42, "/attend 1")
+
+	env.say(42, "/unattend 1")
+	if got := env.lastText(42); !strings.Contains(got, "Вы отменили участие") {
+		t.Fatalf("/unattend ответил %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 0 {
+		t.Errorf("баланс после /unattend = %d, want 0", got)
+	}
+	entries := env.ledger(profile.ID)
+	if len(entries) != 2 {
+		t.Fatalf("в журнале %d операций, want 2", len(entries))
+	}
+	if e := entries[1]; e.Delta != -30 || e.BalanceAfter != 0 || e.SourceType != models.LedgerSourceEvent {
+		t.Errorf("операция возврата %+v", e)
+	}
+
+	env.say(42, "/unattend 1")
+	if got := env.lastText(42); !strings.Contains(got, "не принимали участие") {
+		t.Errorf("повторный /unattend ответил %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 0 {
+		t.Errorf("повторный /unattend изменил баланс: %d", got)
+	}
+	env.assertNoDrifts()
+}
+
+func TestReregisterKeepsLedgerReconciled(t *testing.T) {
+	env := newTestEnv(t)
+	env.register(42, "Вася")
+	env.adminSay("/createevent Бал|piastres|30|2h")
+	env.say(42, "/attend 1")
+
+	env.say(42, "/deleteprofile")
+	if got := env.lastText(42); !strings.Contains(got, "Профиль успешно удалён") {
+		t.Fatalf("/deleteprofile ответил %q", got)
+	}
+	profile := env.register(42, "Вася")
+	env.assertNoDrifts()
+
+	env.adminSay("/reconcile apply")
+	if got := env.balance(42, "piastres"); got != 0 {
+		t.Errorf("баланс новой анкеты после /reconcile apply = %d, want 0", got)
+	}
+	if got := len(env.ledger(profile.ID)); got != 0 {
+		t.Errorf("в журнале новой анкеты %d операций, want 0", got)
+	}
+
+	env.say(42, "/attend 1")
+	if got := env.balance(42, "piastres"); got != 30 {
+		t.Errorf("баланс после повторной отметки = %d, want 30", got)
+	}
+	env.assertNoDrifts()
+}
+
+func TestUnattendAfterEnd(t *testing.T) {
+	env := newTestEnv(t)
+	env.register(42, "Вася")
+	env.adminSay("/createevent Бал|piastres|30|2h")
+	env.say(42, "/attend 1")
+
+	// Событие закончилось, но задача закрытия ещё не выполнилась.
+	event, err := handlers.Store.GetEventByID(env.ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.EndsAt = time.Now().Add(-time.Minute)
+	if err := handlers.Store.UpdateEvent(env.ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	env.say(42, "/unattend 1")
+	if got := env.lastText(42); !strings.Contains(got, "уже завершилось") {
+		t.Errorf("/unattend после окончания ответил %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 30 {
+		t.Errorf("баланс после /unattend после окончания = %d, want 30", got)
+	}
+}
+
+func TestConcurrentJoinsTakeOneSeat(t *testing.T) {
+	env := newTestEnv(t)
+	users := []int64{42, 43, 44}
+	for _, id := range users {
+		env.register(id, fmt.Sprintf("Игрок %d", id))
+	}
+	env.adminSay("/createevent Бал|piastres|30|2h|мест 1")
+
+	var wg sync.WaitGroup
+	for _, id := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env.say(id, "/attend 1")
+		}()
+	}
+	wg.Wait()
+
+	participants, err := handlers.Store.CountEventParticipants(env.ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if participants != 1 {
+		t.Errorf("участников %d, want 1", participants)
+	}
+	waiting, err := handlers.Store.WaitlistLength(env.ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waiting != 2 {
+		t.Errorf("в листе ожидания %d, want 2", waiting)
+	}
+	paid := 0
+	for _, id := range users {
+		paid += env.balance(id, "piastres")
+	}
+	if paid != 30 {
+		t.Errorf("выплачено %d, want 30", paid)
+	}
+	env.assertNoDrifts()
+}
+
+func TestAdminGrant(t *testing.T) {
+	env := newTestEnv(t)
+	profile := env.register(42, "Вася")
+
+	env.adminSay("/addcurrency 1 piastres 100 oblomki 5")
+	if got := env.balance(42, "piastres"); got != 100 {
+		t.Errorf("пиастры после начисления = %d, want 100", got)
+	}
+	if got := env.balance(42, "oblomki"); got != 5 {
+		t.Errorf("обломки после начисления = %d, want 5", got)
+	}
+	entries := env.ledger(profile.ID)
+	if len(entries) != 2 {
+		t.Fatalf("в журнале %d операций, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.SourceType != models.LedgerSourceAdmin || e.SourceID == 0 {
+			t.Errorf("операция начисления %+v", e)
+		}
+	}
+	env.assertNoDrifts()
+
+	env.adminSay("/addcurrency 99 piastres 100")
+	if got := env.lastText(testAdminID); !strings.Contains(got, "не найдена") {
+		t.Errorf("начисление несуществующей анкете: ответ %q", got)
+	}
+	if got := len(env.ledger(99)); got != 0 {
+		t.Errorf("в журнале несуществующей анкеты %d операций", got)
+	}
+	env.assertNoDrifts()
+}
+
+func TestConcurrentWrongCheckinCodesLock(t *testing.T) {
+	env := newTestEnv(t)
+	env.register(42, "Игрок")
+	env.adminSay("/createevent Бал|piastres|30|2h|код 5m")
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env.say(42, "/attend 1 000000")
+		}()
+	}
+	wg.Wait()
+
+	a, err := handlers.Store.GetCheckinAttempts(env.ctx, 1, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !time.Now().Before(a.LockedUntil) {
+		t.Fatalf("после 8 неверных кодов отметка не заблокирована: %+v", a)
+	}
+	if a.Failures != 0 {
+		t.Errorf("неверных кодов после блокировки %d, want 0", a.Failures)
+	}
+}
+
+func TestCheckinQRSentToAdmins(t *testing.T) {
+	env := newTestEnv(t)
+	handlers.PrimaryBotUsername = "test_1_bot"
+	t.Cleanup(func() { handlers.PrimaryBotUsername = "" })
+
+	env.adminSay("/createevent Бал|piastres|30|2h|код 5m")
+	env.adminSay("/eventcode 1")
+	var photos []telegramtest.Call
+	for _, c := range env.srv.CallsTo("sendPhoto") {
+		if c.ChatID() == testAdminID {
+			photos = append(photos, c)
+		}
+	}
+	if len(photos) != 2 {
+		t.Fatalf("администратору отправлено %d QR-кодов, want 2 (при создании и по /eventcode)", len(photos))
+	}
+	for _, p := range photos {
+		if !strings.Contains(p.Params["caption"], "https://t.me/test_1_bot?start=attend_1_") {
+			t.Errorf("подпись QR-кода %q", p.Params["caption"])
+		}
+	}
+}
+
+// tap нажимает за пользователя userID кнопку с данными data под сообщением messageID.
+func (e *testEnv) tap(userID int64, messageID int, data string) {
+	handlers.HandleUpdate(e.ctx, e.bot, telegramtest.Callback(userID, messageID, data))
+}
+
+// lastEdit – последний текст, которым бот заменил сообщение в чате chatID.
+func (e *testEnv) lastEdit(chatID int64) string {
+	e.t.Helper()
+	var text string
+	for _, c := range e.srv.CallsTo("editMessageText") {
+		if c.ChatID() == chatID {
+			text = c.Params["text"]
+		}
+	}
+	return text
+}
+
+func TestBuyConfirmedOnce(t *testing.T) {
+	env := newTestEnv(t)
+	profile := env.register(42, "Вася")
+	env.adminSay("/addcurrency 1 piastres 100")
+	env.adminSay("/additem Меч|Острый|30 piastres|5")
+
+	env.say(42, "/buy 1")
+	if got := env.lastText(42); !strings.Contains(got, "Купить «Меч»") {
+		t.Fatalf("/buy ответил %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 100 {
+		t.Fatalf("/buy списал валюту до подтверждения: баланс %d", got)
+	}
+	env.tap(43, 1, "shop:confirm:1")
+	if got := env.balance(42, "piastres"); got != 100 {
+		t.Fatalf("чужое подтверждение списало валюту: баланс %d", got)
+	}
+
+	env.tap(42, 1, "shop:confirm:1")
+	env.tap(42, 1, "shop:confirm:1")
+	if got := env.lastEdit(42); !strings.Contains(got, "Вы купили «Меч»") {
+		t.Errorf("подтверждение покупки: сообщение %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 70 {
+		t.Errorf("баланс после двух нажатий = %d, want 70", got)
+	}
+	if got := len(env.ledger(profile.ID)); got != 2 {
+		t.Errorf("в журнале %d операций, want 2 (начисление и одна покупка)", got)
+	}
+	item, err := handlers.Store.GetShopItem(env.ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Stock != 4 {
+		t.Errorf("запас после покупки = %d, want 4", item.Stock)
+	}
+}
+
+func TestBuyCancelledOnPriceChange(t *testing.T) {
+	env := newTestEnv(t)
+	env.register(42, "Вася")
+	env.adminSay("/addcurrency 1 piastres 100")
+	env.adminSay("/additem Меч|Острый|30 piastres|5")
+
+	env.say(42, "/buy 1")
+	env.adminSay("/edititem 1 price 60 piastres")
+	env.tap(42, 1, "shop:confirm:1")
+	if got := env.lastEdit(42); !strings.Contains(got, "цена товара изменилась") {
+		t.Errorf("подтверждение после смены цены: сообщение %q", got)
+	}
+	if got := env.balance(42, "piastres"); got != 100 {
+		t.Errorf("баланс после смены цены = %d, want 100", got)
+	}
+
+	env.say(42, "/buy 1")
+	env.tap(42, 1, "shop:cancel:2")
+	env.tap(42, 1, "shop:confirm:2")
+	if got := env.balance(42, "piastres"); got != 100 {
+		t.Errorf("отменённая покупка списала валюту: баланс %d", got)
+	}
+}
+
+func TestTradeEscrow(t *testing.T) {
+	env := newTestEnv(t)
+	env.register(42, "Вася")
+	env.register(43, "Петя")
+	env.adminSay("/addcurrency 1 piastres 100")
+
+	env.say(42, "/trade 2")
+	env.say(42, "/offer 30 piastres")
+	if got := env.balance(42, "piastres"); got != 70 {
+		t.Fatalf("баланс после /offer = %d, want 70 (30 в залоге)", got)
+	}
+	env.say(42, "/offer 200 piastres")
+	if got := env.lastText(42); !strings.Contains(got, "недостаточно") {
+		t.Errorf("/offer сверх баланса ответил %q", got)
+	}
+	env.say(42, "/offer 10 piastres")
+	if got := env.balance(42, "piastres"); got != 90 {
+		t.Errorf("баланс после уменьшения предложения = %d, want 90", got)
+	}
+	env.say(42, "/tradecancel")
+	if got := env.balance(42, "piastres"); got != 100 {
+		t.Errorf("баланс после отмены обмена = %d, want 100", got)
+	}
+
+	env.say(42, "/trade 2")
+	env.say(42, "/offer 30 piastres")
+	trade, err := handlers.Store.GetOpenTrade(env.ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf("trade:confirm:%d:%d", trade.ID, trade.Revision)
+	env.tap(42, 1, data)
+	env.tap(43, 1, data)
+	if got := env.balance(42, "piastres"); got != 70 {
+		t.Errorf("баланс отдавшего после обмена = %d, want 70", got)
+	}
+	if got := env.balance(43, "piastres"); got != 30 {
+		t.Errorf("баланс получившего после обмена = %d, want 30", got)
+	}
+	env.say(42, "/tradecancel")
+	if got := env.balance(42, "piastres"); got != 70 {
+		t.Errorf("отмена выполненного обмена изменила баланс: %d", got)
+	}
+}
+
+func containsText(texts []string, substr string) bool {
+	for _, text := range texts {
+		if strings.Contains(text, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,69 +1,704 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"telegram-bot/config"
 	"telegram-bot/db"
 	"telegram-bot/models"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// HandleCreateEvent создает новое событие и рассылает уведомление всем пользователям.
+const (
+	eventMinDuration = 10 * time.Minute
+	eventMaxDuration = 30 * 24 * time.Hour
+	eventListLimit   = 20
+)
+
+// Задачи планировщика для событий; RefID – ID события.
+const (
+	jobEventClose   = "event.close"   // закрытие по истечении срока
+	jobEventStart   = "event.start"   // объявление о начале запланированного события
+	jobEventRemind  = "event.remind"  // напоминание перед началом
+	jobEventRefresh = "event.refresh" // обновление разосланных объявлений
+)
+
+const (
+	eventRefreshDelay    = 5 * time.Second       // через сколько после отметки обновляются объявления
+	eventRefreshInterval = 50 * time.Millisecond // пауза между правками объявлений
+)
+
+// Ошибки жизненного цикла событий.
+var (
+	errEventClosed = errors.New("событие уже закрыто")
+	errEventOpen   = errors.New("событие уже открыто")
+	errEventWindow = errors.New("событие ещё не началось или уже завершилось")
+)
+
+// eventLabel – название валюты события для сообщений.
+func eventLabel(labels map[string]string, e *models.Event) string {
+	if label, ok := labels[e.CurrencyType]; ok {
+		return label
+	}
+	return e.CurrencyType
+}
+
+// eventTime – время в часовом поясе игры (параметр timezone). Так показываются
+// все времена: событий, аукционов, обменов, курсов.
+func eventTime(t time.Time) string {
+	return t.In(config.Current.Location()).Format("02.01.2006 15:04")
+}
+
+// eventStatus – состояние события: запланировано, открыто (с автозакрытием или без) или закрыто.
+func eventStatus(e *models.Event) string {
+	switch {
+	case !e.Active && !e.ClosedAt.IsZero():
+		return "закрыто " + eventTime(e.ClosedAt)
+	case !e.Active:
+		return "закрыто"
+	case !e.StartsAt.IsZero() && time.Now().Before(e.StartsAt):
+		return "запланировано на " + eventTime(e.StartsAt) + " – " + eventTime(e.EndsAt)
+	case !e.EndsAt.IsZero():
+		return "открыто до " + eventTime(e.EndsAt)
+	default:
+		return "открыто без срока"
+	}
+}
+
+// eventWindowText – почему отметиться на открытом событии сейчас нельзя,
+// или "", если now попадает в окно события.
+func eventWindowText(e *models.Event, now time.Time) string {
+	if !e.StartsAt.IsZero() && now.Before(e.StartsAt) {
+		return "Событие ещё не началось. Начало: " + eventTime(e.StartsAt) + "."
+	}
+	if !e.EndsAt.IsZero() && !now.Before(e.EndsAt) {
+		return "Событие уже завершилось."
+	}
+	return ""
+}
+
+// eventAnnouncement – объявление о событии для пользователей.
+func eventAnnouncement(ctx context.Context, title string, e *models.Event, label string) string {
+	text := fmt.Sprintf("%s: \"%s\" (ID: %d)\nЗа участие: %d %s.\n", title, e.Name, e.ID, e.Amount, label)
+	if !e.EndsAt.IsZero() {
+		text += "Событие открыто до " + eventTime(e.EndsAt) + ".\n"
+	}
+	if seats := eventSeatsText(ctx, e); seats != "" {
+		text += "Места ограничены (" + seats + "). Когда места закончатся, можно встать в лист ожидания.\n"
+	}
+	if e.CheckinSecret != "" {
+		return text + fmt.Sprintf("Для участия введите: /attend %d <код> – код сообщит ведущий.\nДля отмены участия: /unattend %d", e.ID, e.ID)
+	}
+	return text + fmt.Sprintf("Для участия нажмите «Участвую» или введите: /attend %d\nДля отмены участия: /unattend %d", e.ID, e.ID)
+}
+
+// eventSummary – итог события для администраторов: число участников и сколько выплачено.
+func eventSummary(ctx context.Context, e *models.Event) string {
+	participants, paid, err := Store.EventStats(ctx, e.ID)
+	if err != nil {
+		log.Printf("Ошибка подсчёта итогов события %d: %v", e.ID, err)
+		return fmt.Sprintf("Событие «%s» (ID %d) закрыто.", e.Name, e.ID)
+	}
+	return fmt.Sprintf("Событие «%s» (ID %d) закрыто.\nУчастников: %d, выплачено: %d %s.",
+		e.Name, e.ID, participants, paid, eventLabel(currencyLabels(ctx), e))
+}
+
+// broadcastEvent рассылает text всем зарегистрированным пользователям через пользовательского бота.
+func broadcastEvent(ctx context.Context, text string) {
+	if PrimaryBot == nil {
+		log.Println("PrimaryBot не инициализирован")
+		return
+	}
+	profiles, err := Store.GetAllProfiles(ctx)
+	if err != nil {
+		log.Printf("Ошибка получения профилей для рассылки: %v", err)
+		return
+	}
+	for _, profile := range profiles {
+		SendMessage(PrimaryBot, profile.TelegramID, text)
+	}
+}
+
+// scheduleEventJobs планирует начало, напоминание и закрытие события.
+// Напоминание не планируется, если его время уже прошло.
+func scheduleEventJobs(ctx context.Context, tx db.Store, e *models.Event) error {
+	now := time.Now()
+	if !e.StartsAt.IsZero() && now.Before(e.StartsAt) {
+		if err := tx.ScheduleJob(ctx, jobEventStart, int64(e.ID), e.StartsAt); err != nil {
+			return err
+		}
+		if remindAt := e.StartsAt.Add(-e.RemindBefore); e.RemindBefore > 0 && now.Before(remindAt) {
+			if err := tx.ScheduleJob(ctx, jobEventRemind, int64(e.ID), remindAt); err != nil {
+				return err
+			}
+		}
+	}
+	if at := nextCheckinRotation(e, now); !at.IsZero() {
+		if err := tx.ScheduleJob(ctx, jobEventCheckin, int64(e.ID), at); err != nil {
+			return err
+		}
+	}
+	if e.EndsAt.IsZero() {
+		return nil
+	}
+	return tx.ScheduleJob(ctx, jobEventClose, int64(e.ID), e.EndsAt)
+}
+
+// closeEvent закрывает событие и отменяет его задачи в планировщике.
+func closeEvent(ctx context.Context, tx db.Store, e *models.Event) error {
+	e.Active = false
+	e.ClosedAt = time.Now()
+	if err := tx.UpdateEvent(ctx, e); err != nil {
+		return err
+	}
+	for _, kind := range []string{jobEventClose, jobEventStart, jobEventRemind, jobEventCheckin} {
+		if err := tx.CancelJob(ctx, kind, int64(e.ID)); err != nil {
+			return err
+		}
+	}
+	// Объявления заменятся итогом без кнопок.
+	return tx.ScheduleJob(ctx, jobEventRefresh, int64(e.ID), e.ClosedAt)
+}
+
+// parseEventTime разбирает время "25.10.2026 18:00", "25.10 18:00" или "18:00"
+// в часовом поясе base; недостающие год и дата берутся из base.
+func parseEventTime(s string, base time.Time) (time.Time, error) {
+	s = strings.Join(strings.Fields(s), " ")
+	loc := base.Location()
+	if t, err := time.ParseInLocation("2.1.2006 15:04", s, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2.1 15:04", s, loc); err == nil {
+		return time.Date(base.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc), nil
+	}
+	if t, err := time.ParseInLocation("15:04", s, loc); err == nil {
+		return time.Date(base.Year(), base.Month(), base.Day(), t.Hour(), t.Minute(), 0, 0, loc), nil
+	}
+	return time.Time{}, fmt.Errorf("неверное время %q: используйте, например, 25.10 18:00", s)
+}
+
+// parseEventWindow разбирает срок события: длительность от текущего момента ("3h")
+// или окно "<начало> - <конец>", где конец – время ("21:00", "26.10 02:00")
+// или длительность от начала ("3h"). Для события, открытого сразу, start нулевое.
+func parseEventWindow(s string, now time.Time) (start, end time.Time, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		d, err := parseDuration(s, eventMinDuration, eventMaxDuration)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return time.Time{}, now.Add(d), nil
+	}
+	if start, err = parseEventTime(from, now); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !start.After(now) {
+		return time.Time{}, time.Time{}, fmt.Errorf("время начала %s уже прошло", eventTime(start))
+	}
+	if to = strings.TrimSpace(to); strings.ContainsAny(to, ".:") {
+		if end, err = parseEventTime(to, start); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if !strings.Contains(to, ".") && !end.After(start) {
+			end = end.AddDate(0, 0, 1) // "23:00 - 02:00" – конец на следующий день
+		}
+	} else {
+		d, err := parseDuration(to, eventMinDuration, eventMaxDuration)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		end = start.Add(d)
+	}
+	if d := end.Sub(start); d < eventMinDuration || d > eventMaxDuration {
+		return time.Time{}, time.Time{}, fmt.Errorf("событие должно длиться от %d минут до %d дней",
+			int(eventMinDuration.Minutes()), int(eventMaxDuration.Hours()/24))
+	}
+	return start, end, nil
+}
+
+// parseReminder разбирает, за сколько до начала напомнить о событии:
+// число минут или длительность ("1h"); 0 – без напоминания.
+func parseReminder(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	d, err := time.ParseDuration(s)
+	if n, convErr := strconv.Atoi(s); convErr == nil {
+		d, err = time.Duration(n)*time.Minute, nil
+	}
+	if err != nil || d < 0 || d > eventMaxDuration {
+		return 0, fmt.Errorf("неверное напоминание %q: укажите число минут, например 30, или 0 – без напоминания", s)
+	}
+	return d.Truncate(time.Minute), nil
+}
+
+// eventOptions – необязательные части /createevent после срока и напоминания.
+type eventOptions struct {
+	checkin  bool          // отметка по коду
+	rotate   time.Duration // период смены кода
+	capacity int           // число мест
+}
+
+// cutEventOptions отрезает с конца частей /createevent части-параметры
+// "код [период]" и "мест <число>" в любом порядке.
+func cutEventOptions(parts []string) ([]string, eventOptions, error) {
+	var opts eventOptions
+	for len(parts) > 3 {
+		fields := strings.Fields(parts[len(parts)-1])
+		if len(fields) == 0 {
+			break
+		}
+		switch strings.ToLower(fields[0]) {
+		case "код", "code":
+			rotate, err := parseCheckinOption(fields)
+			if err != nil {
+				return nil, opts, err
+			}
+			opts.checkin, opts.rotate = true, rotate
+		case "мест", "места", "seats":
+			n := 0
+			if len(fields) == 2 {
+				n, _ = strconv.Atoi(fields[1])
+			}
+			if n <= 0 {
+				return nil, opts, fmt.Errorf("неверное число мест %q: используйте, например, «мест 20»", parts[len(parts)-1])
+			}
+			opts.capacity = n
+		default:
+			return parts, opts, nil
+		}
+		parts = parts[:len(parts)-1]
+	}
+	return parts, opts, nil
+}
+
+// parseEventID разбирает ID события из аргумента команды.
+func parseEventID(s string) (int, error) {
+	return strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+}
+
+// handleAdminCreateEvent создает новое событие и рассылает уведомление всем пользователям.
 // Формат команды (админская команда):
 //
-//	/createevent Название события|валюта|количество
-func HandleCreateEvent(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
-	args := msg.CommandArguments()
-	parts := strings.Split(args, "|")
-	if len(parts) != 3 {
-		SendMessage(bot, msg.Chat.ID, "Используйте формат: /createevent Название события|валюта|количество")
+//	/createevent Название события|валюта|количество[|срок[|напоминание]][|код [период]][|мест N]
+//
+// Срок – длительность ("3h"), после которой событие закроется автоматически,
+// или окно "25.10 18:00 - 21:00". Запланированное событие объявляется в момент
+// начала, а за напоминание минут до начала пользователи получают напоминание.
+// С частью "код" отметиться можно только с кодом, который видят администраторы,
+// с частью "мест N" сверх N участников пользователи встают в лист ожидания.
+func handleAdminCreateEvent(ctx context.Context, bot Sender, chatID int64, args string) {
+	const usage = "Используйте формат команды: /createevent <название|валюта|количество[|срок[|напоминание]][|код [период]][|мест N]>\n" +
+		"Срок: длительность, например 2h или 3d, – через сколько событие закроется автоматически, " +
+		"или время начала и конца, например 25.10 18:00 - 21:00.\n" +
+		"Напоминание: за сколько минут до начала напомнить (0 – без напоминания).\n" +
+		"Код: отметка только по коду, который видят администраторы; с периодом, например «код 5m», код меняется.\n" +
+		"Мест N: не больше N участников, остальные встают в лист ожидания."
+	parts, opts, err := cutEventOptions(strings.Split(args, "|"))
+	if err != nil {
+		SendMessage(bot, chatID, err.Error()+"\n"+usage)
+		return
+	}
+	if len(parts) < 3 || len(parts) > 5 {
+		SendMessage(bot, chatID, usage)
 		return
 	}
-
 	name := strings.TrimSpace(parts[0])
-	currency := strings.ToLower(strings.TrimSpace(parts[1]))
-	amount, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+	if name == "" {
+		SendMessage(bot, chatID, "Укажите название события.\n"+usage)
+		return
+	}
+	currency, err := resolveCurrency(ctx, Store, parts[1], true)
 	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Количество должно быть числом.")
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	amount, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+	if err != nil || amount <= 0 {
+		SendMessage(bot, chatID, "Количество должно быть положительным числом.")
 		return
 	}
 
-	// Создаем объект события
 	event := &models.Event{
 		Name:         name,
-		CurrencyType: currency,
+		CurrencyType: currency.Code,
 		Amount:       amount,
 		Active:       true,
 		CreatedAt:    time.Now(),
+		Capacity:     opts.capacity,
+	}
+	if len(parts) >= 4 {
+		if event.StartsAt, event.EndsAt, err = parseEventWindow(parts[3], gameNow()); err != nil {
+			SendMessage(bot, chatID, err.Error()+"\n"+usage)
+			return
+		}
+	}
+	if !event.StartsAt.IsZero() {
+		event.RemindBefore = config.Current.Events.RemindBefore
+	}
+	if opts.checkin {
+		if event.CheckinSecret, err = newCheckinSecret(); err != nil {
+			SendMessage(bot, chatID, "Ошибка создания кода отметки: "+err.Error())
+			return
+		}
+		event.CheckinRotate = opts.rotate
+	}
+	if len(parts) == 5 {
+		if event.StartsAt.IsZero() {
+			SendMessage(bot, chatID, "Напоминание задаётся только для события с временем начала.\n"+usage)
+			return
+		}
+		if event.RemindBefore, err = parseReminder(parts[4]); err != nil {
+			SendMessage(bot, chatID, err.Error()+"\n"+usage)
+			return
+		}
 	}
 
-	err = db.CreateEvent(event)
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		if err := tx.CreateEvent(ctx, event); err != nil {
+			return err
+		}
+		return scheduleEventJobs(ctx, tx, event)
+	})
 	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Ошибка создания события: "+err.Error())
+		log.Printf("Ошибка создания события: %v", err)
+		SendMessage(bot, chatID, "Ошибка создания события: "+err.Error())
 		return
 	}
-
 	log.Printf("Создано событие: ID=%d, Название=%s, Валюта=%s, Сумма=%d", event.ID, event.Name, event.CurrencyType, event.Amount)
 
-	// Формируем текст уведомления для пользователей.
-	notifyText := fmt.Sprintf("Новое событие: \"%s\" (ID: %d)\nДля участия введите: /attend %d\nДля отмены участия: /unattend %d",
-		event.Name, event.ID, event.ID, event.ID)
+	status := eventStatus(event)
+	if event.Capacity > 0 {
+		status += fmt.Sprintf(", мест %d", event.Capacity)
+	}
 
-	// Рассылка уведомления всем зарегистрированным пользователям.
-	profiles, err := db.GetAllProfiles()
-	if err != nil || len(profiles) == 0 {
-		SendMessage(bot, msg.Chat.ID, "Ошибка рассылки уведомления или нет зарегистрированных пользователей.")
+	if !event.StartsAt.IsZero() {
+		text := fmt.Sprintf("Событие запланировано: \"%s\" (ID: %d), %d %s за участие, %s.\nОбъявление будет разослано в момент начала",
+			event.Name, event.ID, event.Amount, currency.Label(), status)
+		if event.RemindBefore > 0 {
+			text += fmt.Sprintf(", напоминание – за %d мин. до начала", int(event.RemindBefore.Minutes()))
+		}
+		text += "."
+		SendMessage(bot, chatID, text)
+		if event.CheckinSecret != "" {
+			sendCheckin(bot, chatID, event, time.Now())
+		}
 		return
 	}
+	SendMessage(bot, chatID, fmt.Sprintf("Событие создано: \"%s\" (ID: %d), %d %s за участие, %s.",
+		event.Name, event.ID, event.Amount, currency.Label(), status))
+	if event.CheckinSecret != "" {
+		sendCheckin(bot, chatID, event, time.Now())
+	}
+	announceEvent(ctx, event, "Новое событие")
+}
 
-	for _, profile := range profiles {
-		notification := tgbotapi.NewMessage(profile.TelegramID, notifyText)
-		bot.Send(notification)
+// startEventJob – задача планировщика: объявляет пользователям о начале
+// запланированного события. Если начало перенесли, задача переносится.
+func startEventJob(ctx context.Context, job *models.Job) error {
+	e, err := Store.GetEventByID(ctx, int(job.RefID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !e.Active || e.StartsAt.IsZero() || (!e.EndsAt.IsZero() && !now.Before(e.EndsAt)) {
+		return nil
+	}
+	if now.Before(e.StartsAt) {
+		return Store.ScheduleJob(ctx, jobEventStart, job.RefID, e.StartsAt)
+	}
+	log.Printf("Событие %d началось", e.ID)
+	announceEvent(ctx, e, "Началось событие")
+	return nil
+}
+
+// remindEventJob – задача планировщика: напоминает пользователям о скором начале события.
+func remindEventJob(ctx context.Context, job *models.Job) error {
+	e, err := Store.GetEventByID(ctx, int(job.RefID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !e.Active || e.StartsAt.IsZero() || e.RemindBefore <= 0 || !now.Before(e.StartsAt) {
+		return nil
 	}
+	if remindAt := e.StartsAt.Add(-e.RemindBefore); now.Before(remindAt) {
+		return Store.ScheduleJob(ctx, jobEventRemind, job.RefID, remindAt)
+	}
+	remindEvent(ctx, e)
+	return nil
+}
 
+// remindEvent рассылает напоминание о скором начале события.
+func remindEvent(ctx context.Context, e *models.Event) {
+	broadcastEvent(ctx, fmt.Sprintf("Скоро начнётся событие \"%s\" (ID: %d): %s – %s.\nЗа участие: %d %s.\nОтметиться можно будет после начала: /attend %d",
+		e.Name, e.ID, eventTime(e.StartsAt), eventTime(e.EndsAt), e.Amount, eventLabel(currencyLabels(ctx), e), e.ID))
+}
+
+// closeEventJob – задача планировщика: закрывает событие, срок которого истёк,
+// и отправляет итог в чаты администраторов. Если срок продлили, задача переносится.
+func closeEventJob(ctx context.Context, job *models.Job) error {
+	var e *models.Event
+	var closed bool
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		e, err = tx.GetEventByID(ctx, int(job.RefID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !e.Active || e.EndsAt.IsZero() {
+			return nil
+		}
+		if time.Now().Before(e.EndsAt) {
+			return tx.ScheduleJob(ctx, jobEventClose, job.RefID, e.EndsAt)
+		}
+		closed = true
+		return closeEvent(ctx, tx, e)
+	})
+	if err != nil || !closed {
+		return err
+	}
+	log.Printf("Событие %d закрыто по истечении срока", e.ID)
+	notifyAdmins(eventSummary(ctx, e))
+	return nil
+}
+
+// handleAdminEvents обрабатывает команду админского бота /events – последние события
+// с состоянием, числом участников и выплаченной суммой.
+func handleAdminEvents(ctx context.Context, bot Sender, chatID int64) {
+	events, err := Store.ListEvents(ctx, eventListLimit)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения событий: "+err.Error())
+		return
+	}
+	if len(events) == 0 {
+		SendMessage(bot, chatID, "Событий нет. Создать: /createevent <название|валюта|количество[|длительность]>")
+		return
+	}
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("Последние события:")
+	for _, e := range events {
+		participants, paid, err := Store.EventStats(ctx, e.ID)
+		if err != nil {
+			SendMessage(bot, chatID, "Ошибка подсчёта итогов события: "+err.Error())
+			return
+		}
+		label := eventLabel(labels, e)
+		fmt.Fprintf(&b, "\n#%d «%s»: %d %s за участие, %s; участников %d, выплачено %d %s",
+			e.ID, e.Name, e.Amount, label, eventStatus(e), participants, paid, label)
+		if seats := eventSeatsText(ctx, e); seats != "" {
+			b.WriteString("; " + seats)
+		}
+		if e.CheckinSecret != "" {
+			b.WriteString("; отметка по коду")
+		}
+	}
+	b.WriteString("\nЗакрыть: /closeevent <ID>, открыть снова: /reopenevent <ID> [длительность]")
+	SendMessage(bot, chatID, b.String())
+}
+
+// handleAdminCloseEvent обрабатывает команду /closeevent <ID> – досрочное закрытие события.
+func handleAdminCloseEvent(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := parseEventID(args)
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /closeevent <ID события>")
+		return
+	}
+	var e *models.Event
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		if e, err = tx.GetEventByID(ctx, id); err != nil {
+			return err
+		}
+		if !e.Active {
+			return errEventClosed
+		}
+		return closeEvent(ctx, tx, e)
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		SendMessage(bot, chatID, "Событие не найдено.")
+		return
+	case errors.Is(err, errEventClosed):
+		SendMessage(bot, chatID, "Событие уже закрыто.")
+		return
+	case err != nil:
+		SendMessage(bot, chatID, "Ошибка закрытия события: "+err.Error())
+		return
+	}
+	log.Printf("Событие %d закрыто администратором", e.ID)
+	SendMessage(bot, chatID, eventSummary(ctx, e))
+}
+
+// handleAdminReopenEvent обрабатывает команду /reopenevent <ID> [длительность].
+// Без длительности событие открывается без срока.
+func handleAdminReopenEvent(ctx context.Context, bot Sender, chatID int64, args string) {
+	const usage = "Используйте: /reopenevent <ID события> [длительность, например 2h или 3d]"
+	parts := strings.Fields(args)
+	if len(parts) != 1 && len(parts) != 2 {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	id, err := parseEventID(parts[0])
+	if err != nil {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	var endsAt time.Time
+	if len(parts) == 2 {
+		duration, err := parseDuration(parts[1], eventMinDuration, eventMaxDuration)
+		if err != nil {
+			SendMessage(bot, chatID, err.Error()+"\n"+usage)
+			return
+		}
+		endsAt = time.Now().Add(duration)
+	}
+
+	var e *models.Event
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		if e, err = tx.GetEventByID(ctx, id); err != nil {
+			return err
+		}
+		if e.Active {
+			return errEventOpen
+		}
+		e.Active = true
+		e.ClosedAt = time.Time{}
+		e.EndsAt = endsAt
+		if e.StartsAt.After(time.Now()) {
+			e.StartsAt = time.Time{} // запланированное событие открывается сразу
+		}
+		if err := tx.UpdateEvent(ctx, e); err != nil {
+			return err
+		}
+		if at := nextCheckinRotation(e, time.Now()); !at.IsZero() {
+			if err := tx.ScheduleJob(ctx, jobEventCheckin, int64(e.ID), at); err != nil {
+				return err
+			}
+		}
+		if e.EndsAt.IsZero() {
+			return tx.CancelJob(ctx, jobEventClose, int64(e.ID))
+		}
+		return tx.ScheduleJob(ctx, jobEventClose, int64(e.ID), e.EndsAt)
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		SendMessage(bot, chatID, "Событие не найдено.")
+		return
+	case errors.Is(err, errEventOpen):
+		SendMessage(bot, chatID, "Событие уже открыто.")
+		return
+	case err != nil:
+		SendMessage(bot, chatID, "Ошибка открытия события: "+err.Error())
+		return
+	}
+	log.Printf("Событие %d снова открыто администратором", e.ID)
+	SendMessage(bot, chatID, fmt.Sprintf("Событие «%s» (ID %d) снова %s.", e.Name, e.ID, eventStatus(e)))
+	announceEvent(ctx, e, "Снова открыто событие")
+}
+
+// HandleEvents обрабатывает команду /events – список открытых событий.
+func HandleEvents(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	events, err := Store.GetActiveEvents(ctx)
+	if err != nil {
+		log.Printf("Ошибка получения событий: %v", err)
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения событий.")
+		return
+	}
+	if len(events) == 0 {
+		SendMessage(bot, msg.Chat.ID, "Сейчас нет открытых событий.")
+		return
+	}
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("Открытые события:")
+	for _, e := range events {
+		fmt.Fprintf(&b, "\n#%d «%s»: %d %s за участие", e.ID, e.Name, e.Amount, eventLabel(labels, e))
+		if !e.StartsAt.IsZero() || !e.EndsAt.IsZero() {
+			b.WriteString(", " + eventStatus(e))
+		}
+		if seats := eventSeatsText(ctx, e); seats != "" {
+			b.WriteString(", " + seats)
+		}
+		if participated, err := Store.UserParticipatedInEvent(ctx, e.ID, msg.From.ID); err == nil && participated {
+			b.WriteString(" – вы участвуете")
+		} else if pos, err := Store.WaitlistPosition(ctx, e.ID, msg.From.ID); err == nil && pos > 0 {
+			fmt.Fprintf(&b, " – вы в листе ожидания, место %d", pos)
+		}
+	}
+	b.WriteString("\nУчаствовать: /attend <ID>, отменить участие: /unattend <ID>")
+	SendMessage(bot, msg.Chat.ID, b.String())
+}
+
+// handleAdminSetCapacity обрабатывает команду /setcapacity <ID> <мест> – число мест
+// события (0 – без ограничения). Освободившиеся места сразу получает лист ожидания.
+func handleAdminSetCapacity(ctx context.Context, bot Sender, chatID int64, args string) {
+	const usage = "Используйте: /setcapacity <ID события> <число мест> (0 – без ограничения)"
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	id, err := parseEventID(parts[0])
+	if err != nil {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	capacity, err := strconv.Atoi(parts[1])
+	if err != nil || capacity < 0 {
+		SendMessage(bot, chatID, "Число мест должно быть неотрицательным числом.\n"+usage)
+		return
+	}
+
+	var e *models.Event
+	var promoted []promotion
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		if e, err = tx.GetEventByID(ctx, id); err != nil {
+			return err
+		}
+		e.Capacity = capacity
+		if err := tx.UpdateEvent(ctx, e); err != nil {
+			return err
+		}
+		if !e.Active {
+			return nil
+		}
+		promoted, err = promoteWaitlist(ctx, tx, e)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Событие не найдено.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка изменения числа мест: "+err.Error())
+		return
+	}
+	notifyPromoted(e, promoted)
+	requestEventRefresh(ctx, e.ID)
+
+	text := fmt.Sprintf("Событие «%s» (ID %d): ", e.Name, e.ID)
+	if seats := eventSeatsText(ctx, e); seats != "" {
+		text += seats
+	} else {
+		text += "без ограничения мест"
+	}
+	if len(promoted) > 0 {
+		text += fmt.Sprintf(".\nИз листа ожидания отмечено: %d", len(promoted))
+	}
+	SendMessage(bot, chatID, text+".")
 }
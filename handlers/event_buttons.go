@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// eventKeyboard – кнопки участия под объявлением о событии.
+func eventKeyboard(eventID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Участвую", fmt.Sprintf("event:join:%d", eventID)),
+		tgbotapi.NewInlineKeyboardButtonData("Отменить", fmt.Sprintf("event:leave:%d", eventID)),
+	))
+}
+
+// eventMessageText – текст объявления о событии с текущим числом участников.
+func eventMessageText(ctx context.Context, title string, e *models.Event, label string) string {
+	text := eventAnnouncement(ctx, title, e, label)
+	participants, err := Store.CountEventParticipants(ctx, e.ID)
+	if err != nil {
+		log.Printf("Ошибка подсчёта участников события %d: %v", e.ID, err)
+		return text
+	}
+	return text + fmt.Sprintf("\nУчастников: %d", participants)
+}
+
+// announceEvent рассылает объявление о событии с кнопками участия всем
+// зарегистрированным пользователям и запоминает сообщения, чтобы потом
+// обновлять в них число участников.
+func announceEvent(ctx context.Context, e *models.Event, title string) {
+	if PrimaryBot == nil {
+		log.Println("PrimaryBot не инициализирован")
+		return
+	}
+	profiles, err := Store.GetAllProfiles(ctx)
+	if err != nil {
+		log.Printf("Ошибка получения профилей для рассылки: %v", err)
+		return
+	}
+	text := eventMessageText(ctx, title, e, eventLabel(currencyLabels(ctx), e))
+	for _, profile := range profiles {
+		msg := tgbotapi.NewMessage(profile.TelegramID, text)
+		msg.ReplyMarkup = eventKeyboard(e.ID)
+		sent, err := PrimaryBot.Send(msg)
+		if err != nil {
+			log.Printf("Ошибка рассылки события %d пользователю %d: %v", e.ID, profile.TelegramID, err)
+			continue
+		}
+		err = Store.SaveEventMessage(ctx, &models.EventMessage{
+			EventID: e.ID, ChatID: sent.Chat.ID, MessageID: sent.MessageID, Title: title,
+		})
+		if err != nil {
+			log.Printf("Ошибка сохранения объявления о событии %d: %v", e.ID, err)
+		}
+	}
+}
+
+// requestEventRefresh откладывает обновление разосланных объявлений о событии
+// на eventRefreshDelay. Нажатия за это время объединяются в одно обновление.
+func requestEventRefresh(ctx context.Context, eventID int) {
+	if err := Store.ScheduleJobOnce(ctx, jobEventRefresh, int64(eventID), time.Now().Add(eventRefreshDelay)); err != nil {
+		log.Printf("Ошибка планирования обновления объявлений о событии %d: %v", eventID, err)
+	}
+}
+
+// refreshEventMessagesJob – задача планировщика: обновляет число участников во всех
+// разосланных объявлениях о событии, а объявления о закрытом событии заменяет итогом.
+func refreshEventMessagesJob(ctx context.Context, job *models.Job) error {
+	e, err := Store.GetEventByID(ctx, int(job.RefID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !e.Active {
+		return closeEventMessages(ctx, e)
+	}
+	msgs, err := Store.ListEventMessages(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	label := eventLabel(currencyLabels(ctx), e)
+	return editEventMessages(ctx, msgs, func(m *models.EventMessage) tgbotapi.Chattable {
+		return tgbotapi.NewEditMessageTextAndMarkup(m.ChatID, m.MessageID,
+			eventMessageText(ctx, m.Title, e, label), eventKeyboard(e.ID))
+	})
+}
+
+// closeEventMessages заменяет разосланные объявления о закрытом событии итогом без кнопок.
+func closeEventMessages(ctx context.Context, e *models.Event) error {
+	msgs, err := Store.ListEventMessages(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	participants, err := Store.CountEventParticipants(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("Событие \"%s\" (ID: %d) закрыто.\nУчастников: %d", e.Name, e.ID, participants)
+	err = editEventMessages(ctx, msgs, func(m *models.EventMessage) tgbotapi.Chattable {
+		return tgbotapi.NewEditMessageText(m.ChatID, m.MessageID, text)
+	})
+	if err != nil {
+		return err
+	}
+	return Store.DeleteEventMessages(ctx, e.ID)
+}
+
+// editEventMessages редактирует объявления не чаще одного раза в eventRefreshInterval,
+// чтобы не упереться в ограничения Telegram на частоту запросов.
+func editEventMessages(ctx context.Context, msgs []*models.EventMessage, edit func(m *models.EventMessage) tgbotapi.Chattable) error {
+	if PrimaryBot == nil || len(msgs) == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(eventRefreshInterval)
+	defer ticker.Stop()
+	for i, m := range msgs {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+		// Ошибку "message is not modified" и удалённые пользователем сообщения пропускаем.
+		PrimaryBot.Send(edit(m))
+	}
+	return nil
+}
+
+// editTappedEventMessage сразу обновляет объявление, под которым нажали кнопку;
+// остальные копии обновит задача jobEventRefresh.
+func editTappedEventMessage(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, e *models.Event) {
+	if cq.Message == nil || cq.Message.Chat == nil {
+		return
+	}
+	m, err := Store.GetEventMessage(ctx, cq.Message.Chat.ID, cq.Message.MessageID)
+	if err != nil {
+		return
+	}
+	bot.Send(tgbotapi.NewEditMessageTextAndMarkup(m.ChatID, m.MessageID,
+		eventMessageText(ctx, m.Title, e, eventLabel(currencyLabels(ctx), e)), eventKeyboard(e.ID)))
+}
+
+// handleEventCallback обрабатывает кнопки "event:join:<ID>" и "event:leave:<ID>"
+// под объявлением о событии. Повторное нажатие безопасно: отметка и отмена
+// проверяют текущее участие в транзакции и ничего не начисляют дважды.
+func handleEventCallback(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, data string) {
+	action, idStr, _ := strings.Cut(data, ":")
+	eventID, err := strconv.Atoi(idStr)
+	if err != nil {
+		answerCallback(bot, cq, "Неверная кнопка.")
+		return
+	}
+	event, err := Store.GetEventByID(ctx, eventID)
+	if err != nil {
+		answerCallback(bot, cq, "Событие не найдено.")
+		return
+	}
+	if !event.Active {
+		answerCallback(bot, cq, "Событие уже закрыто.")
+		return
+	}
+
+	switch action {
+	case "join":
+		if text := eventWindowText(event, time.Now()); text != "" {
+			answerCallback(bot, cq, text)
+			return
+		}
+		if event.CheckinSecret != "" {
+			// Кнопка не передаёт код: verifyCheckin подскажет, как отметиться с кодом.
+			answerCallback(bot, cq, verifyCheckin(ctx, event, cq.From.ID, ""))
+			return
+		}
+		res, err := joinEvent(ctx, event.ID, cq.From.ID)
+		switch {
+		case errors.Is(err, errEventClosed):
+			answerCallback(bot, cq, "Событие уже закрыто.")
+			return
+		case errors.Is(err, errEventWindow):
+			answerCallback(bot, cq, eventWindowText(res.event, time.Now()))
+			return
+		case errors.Is(err, errAlreadyParticipated):
+			answerCallback(bot, cq, "Вы уже участвуете в этом событии.")
+			return
+		case errors.Is(err, errProfileNotFound):
+			answerCallback(bot, cq, "Профиль не найден. Зарегистрируйтесь через /start.")
+			return
+		case errors.Is(err, db.ErrUnknownCurrency):
+			answerCallback(bot, cq, "Неизвестный тип валюты в событии.")
+			return
+		case err != nil:
+			log.Printf("Ошибка отметки на событии %d: %v", event.ID, err)
+			answerCallback(bot, cq, "Ошибка отметки, попробуйте позже.")
+			return
+		case res.waitlist > 0:
+			answerCallback(bot, cq, fmt.Sprintf("Свободных мест нет. Вы в листе ожидания, место в очереди: %d.", res.waitlist))
+		default:
+			answerCallback(bot, cq, fmt.Sprintf("Вы участвуете! Начислено: %d %s.", res.credit.Delta, res.label))
+			if text := debtRepaymentText(res.label, res.credit); text != "" {
+				SendMessage(bot, cq.From.ID, strings.TrimPrefix(text, "\n"))
+			}
+		}
+
+	case "leave":
+		res, err := leaveEvent(ctx, event.ID, cq.From.ID)
+		switch {
+		case errors.Is(err, errEventClosed):
+			answerCallback(bot, cq, "Событие уже закрыто.")
+			return
+		case errors.Is(err, errEventWindow):
+			answerCallback(bot, cq, eventWindowText(res.event, time.Now()))
+			return
+		case errors.Is(err, errNotParticipated):
+			answerCallback(bot, cq, "Вы не участвуете в этом событии.")
+			return
+		case errors.Is(err, errProfileNotFound):
+			answerCallback(bot, cq, "Профиль не найден.")
+			return
+		case errors.Is(err, db.ErrUnknownCurrency):
+			answerCallback(bot, cq, "Неизвестный тип валюты в событии.")
+			return
+		case err != nil:
+			log.Printf("Ошибка отмены участия в событии %d: %v", event.ID, err)
+			answerCallback(bot, cq, "Ошибка отмены участия, попробуйте позже.")
+			return
+		case res.waitlist > 0:
+			answerCallback(bot, cq, "Вы покинули лист ожидания.")
+		default:
+			answerCallback(bot, cq, "Участие отменено, валюта списана.")
+			if text := debtText(res.profile); text != "" {
+				SendMessage(bot, cq.From.ID, strings.TrimPrefix(text, "\n"))
+			}
+			notifyPromoted(event, res.promoted)
+		}
+
+	default:
+		answerCallback(bot, cq, "Неверная кнопка.")
+		return
+	}
+	editTappedEventMessage(ctx, bot, cq, event)
+	requestEventRefresh(ctx, event.ID)
+}
@@ -0,0 +1,558 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/config"
+	"telegram-bot/db"
+	"telegram-bot/models"
+	"telegram-bot/scheduler"
+)
+
+// jobEventTemplate – задача планировщика, создающая событие по шаблону; RefID – ID шаблона.
+const jobEventTemplate = "eventtemplate.create"
+
+const (
+	upcomingDefaultDays = 7  // на сколько дней вперёд /upcomingevents показывает события
+	upcomingMaxDays     = 90 // наибольший срок /upcomingevents
+	upcomingLimit       = 30 // сколько строк /upcomingevents показывает
+)
+
+// templateLead – насколько раньше напоминания (или начала) создаётся событие по шаблону,
+// чтобы задача напоминания была запланирована до его времени.
+const templateLead = 10 * time.Minute
+
+// templateRunAt – когда создавать ближайшее событие шаблона.
+func templateRunAt(t *models.EventTemplate) time.Time {
+	return t.NextRunAt.Add(-t.RemindBefore - templateLead)
+}
+
+// templateNext – ближайший после after повтор правила в часовом поясе игры.
+func templateNext(rec scheduler.Recurrence, after time.Time) time.Time {
+	return rec.Next(after.In(config.Current.Location()))
+}
+
+// templateOccurrences – повторы шаблона с ближайшего несозданного до until включительно, не больше limit.
+func templateOccurrences(t *models.EventTemplate, rec scheduler.Recurrence, until time.Time, limit int) []time.Time {
+	var occurrences []time.Time
+	for at := t.NextRunAt; !at.IsZero() && !at.After(until) && len(occurrences) < limit; at = templateNext(rec, at) {
+		occurrences = append(occurrences, at)
+	}
+	return occurrences
+}
+
+// durationText – длительность события для сообщений, например "2 ч." или "90 мин.".
+func durationText(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%d ч.", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d мин.", int(d.Minutes()))
+}
+
+// formatEventTemplate – строка с шаблоном события для списка администратора.
+func formatEventTemplate(labels map[string]string, t *models.EventTemplate) string {
+	label, ok := labels[t.CurrencyType]
+	if !ok {
+		label = t.CurrencyType
+	}
+	text := fmt.Sprintf("#%d «%s»: %d %s, %s, длится %s", t.ID, t.Name, t.Amount, label, t.Schedule, durationText(t.Duration))
+	if t.Checkin {
+		text += ", по коду"
+	}
+	if t.Capacity > 0 {
+		text += fmt.Sprintf(", мест %d", t.Capacity)
+	}
+	if t.Paused {
+		return text + " [приостановлен]"
+	}
+	return text + ", следующее " + eventTime(t.NextRunAt)
+}
+
+// createTemplateEventJob – задача планировщика: создаёт событие по шаблону и
+// планирует следующее. Создание события и перенос задачи выполняются в одной
+// транзакции, поэтому повтор после ошибки не создаст событие дважды.
+// Пропущенный повтор и повтор, время которого прошло, пока бот не работал, не создаются.
+func createTemplateEventJob(ctx context.Context, job *models.Job) error {
+	var t *models.EventTemplate
+	var e *models.Event
+	var occurrence time.Time
+	var skipped bool
+	var retired *models.Currency
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		t, err = tx.GetEventTemplate(ctx, int(job.RefID))
+		if errors.Is(err, sql.ErrNoRows) {
+			t = nil
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if t.Paused {
+			t = nil
+			return nil
+		}
+		now := time.Now()
+		if now.Before(templateRunAt(t)) {
+			err := tx.ScheduleJob(ctx, jobEventTemplate, job.RefID, templateRunAt(t))
+			t = nil
+			return err
+		}
+		rec, err := scheduler.ParseRecurrence(t.Schedule)
+		if err != nil {
+			return err
+		}
+
+		occurrence = t.NextRunAt
+		err = tx.UnskipOccurrence(ctx, t.ID, occurrence)
+		switch {
+		case err == nil:
+			skipped = true
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		case now.Before(occurrence.Add(t.Duration)):
+			currency, err := tx.GetCurrency(ctx, t.CurrencyType)
+			if err != nil {
+				return err
+			}
+			if currency.Retired {
+				retired = currency
+				break
+			}
+			e = &models.Event{
+				Name:         t.Name,
+				CurrencyType: t.CurrencyType,
+				Amount:       t.Amount,
+				Active:       true,
+				CreatedAt:    now,
+				StartsAt:     occurrence,
+				EndsAt:       occurrence.Add(t.Duration),
+				RemindBefore: t.RemindBefore,
+				Capacity:     t.Capacity,
+				TemplateID:   t.ID,
+			}
+			if t.Checkin {
+				if e.CheckinSecret, err = newCheckinSecret(); err != nil {
+					return err
+				}
+				e.CheckinRotate = t.CheckinRotate
+			}
+			if err := tx.CreateEvent(ctx, e); err != nil {
+				return err
+			}
+			if err := scheduleEventJobs(ctx, tx, e); err != nil {
+				return err
+			}
+			t.LastEventID = e.ID
+		}
+
+		// Повторы, которые целиком прошли, пока бот не работал, пропускаются.
+		after := occurrence
+		if missedUntil := now.Add(-t.Duration); missedUntil.After(after) {
+			after = missedUntil
+		}
+		t.NextRunAt = templateNext(rec, after)
+		if t.NextRunAt.IsZero() {
+			t.Paused = true
+			t.NextRunAt = occurrence
+			return tx.UpdateEventTemplate(ctx, t)
+		}
+		if err := tx.UpdateEventTemplate(ctx, t); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobEventTemplate, int64(t.ID), templateRunAt(t))
+	})
+	if err != nil || t == nil {
+		return err
+	}
+
+	next := "следующее " + eventTime(t.NextRunAt)
+	if t.Paused {
+		next = "других повторов нет, шаблон приостановлен"
+	}
+	switch {
+	case e != nil:
+		log.Printf("По шаблону %d создано событие %d", t.ID, e.ID)
+		text := fmt.Sprintf("По шаблону #%d создано событие «%s» (ID %d): %s – %s.",
+			t.ID, e.Name, e.ID, eventTime(e.StartsAt), eventTime(e.EndsAt))
+		notifyAdmins(text + "\nШаблон: " + next + ".")
+		if e.CheckinSecret != "" {
+			notifyAdminsCheckin(e, time.Now())
+		}
+		// Если задача опоздала (бот не работал), scheduleEventJobs не планирует
+		// уже прошедшие напоминание и объявление – рассылаем их сразу.
+		now := time.Now()
+		switch {
+		case !now.Before(e.StartsAt):
+			announceEvent(ctx, e, "Началось событие")
+		case e.RemindBefore > 0 && !now.Before(e.StartsAt.Add(-e.RemindBefore)):
+			remindEvent(ctx, e)
+		}
+	case skipped:
+		notifyAdmins(fmt.Sprintf("Событие по шаблону #%d «%s» на %s пропущено. Шаблон: %s.",
+			t.ID, t.Name, eventTime(occurrence), next))
+	case retired != nil:
+		notifyAdmins(fmt.Sprintf("Событие по шаблону #%d «%s» на %s не создано: валюта %s выведена из оборота. Шаблон: %s.",
+			t.ID, t.Name, eventTime(occurrence), retired.Label(), next))
+	default:
+		notifyAdmins(fmt.Sprintf("Событие по шаблону #%d «%s» на %s не создано: его время прошло, пока бот не работал. Шаблон: %s.",
+			t.ID, t.Name, eventTime(occurrence), next))
+	}
+	return nil
+}
+
+// handleAdminEventTemplates обрабатывает команду админского бота /eventtemplates – список шаблонов событий.
+func handleAdminEventTemplates(ctx context.Context, bot Sender, chatID int64) {
+	templates, err := Store.ListEventTemplates(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения шаблонов событий: "+err.Error())
+		return
+	}
+	if len(templates) == 0 {
+		SendMessage(bot, chatID, "Шаблонов событий нет. Добавить: /addeventtemplate <название|валюта|количество|расписание|длительность>")
+		return
+	}
+	labels := currencyLabels(ctx)
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("Шаблоны событий:\n")
+	for _, t := range templates {
+		b.WriteString(formatEventTemplate(labels, t))
+		skipped, err := Store.ListSkippedOccurrences(ctx, t.ID)
+		if err != nil {
+			log.Printf("Ошибка получения пропусков шаблона %d: %v", t.ID, err)
+		}
+		var dates []string
+		for _, at := range skipped {
+			if at.After(now) {
+				dates = append(dates, eventTime(at))
+			}
+		}
+		if len(dates) > 0 {
+			b.WriteString("; пропуск: " + strings.Join(dates, ", "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Ближайшие события: /upcomingevents. Пропустить повтор: /skipevent <номер> [время], " +
+		"приостановить: /pauseeventtemplate <номер>, удалить: /removeeventtemplate <номер>")
+	SendMessage(bot, chatID, b.String())
+}
+
+// handleAdminAddEventTemplate обрабатывает команду
+// /addeventtemplate <название|валюта|количество|расписание|длительность[|напоминание][|код [период]][|мест N]>,
+// например /addeventtemplate Сбор|piastres|50|weekly mon,thu 18:00|2h.
+func handleAdminAddEventTemplate(ctx context.Context, bot Sender, chatID int64, args string) {
+	const usage = "Используйте: /addeventtemplate <название|валюта|количество|расписание|длительность[|напоминание][|код [период]][|мест N]>\n" +
+		"Расписание: daily 18:00, weekly mon,thu 18:00, monthly 1 12:00 или cron 0 18 * * mon-fri.\n" +
+		"Длительность: сколько длится каждое событие, например 2h.\n" +
+		"Напоминание, код и мест – как в /createevent."
+	parts, opts, err := cutEventOptions(strings.Split(args, "|"))
+	if err != nil {
+		SendMessage(bot, chatID, err.Error()+"\n"+usage)
+		return
+	}
+	if len(parts) != 5 && len(parts) != 6 {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	t := &models.EventTemplate{
+		Name:          strings.TrimSpace(parts[0]),
+		RemindBefore:  config.Current.Events.RemindBefore,
+		Checkin:       opts.checkin,
+		CheckinRotate: opts.rotate,
+		Capacity:      opts.capacity,
+	}
+	if t.Name == "" {
+		SendMessage(bot, chatID, "Укажите название события.\n"+usage)
+		return
+	}
+	currency, err := resolveCurrency(ctx, Store, parts[1], true)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	t.CurrencyType = currency.Code
+	if t.Amount, err = strconv.Atoi(strings.TrimSpace(parts[2])); err != nil || t.Amount <= 0 {
+		SendMessage(bot, chatID, "Количество должно быть положительным числом.")
+		return
+	}
+	rec, err := scheduler.ParseRecurrence(parts[3])
+	if err != nil {
+		SendMessage(bot, chatID, err.Error()+"\n"+usage)
+		return
+	}
+	t.Schedule = rec.String()
+	if t.Duration, err = parseDuration(strings.TrimSpace(parts[4]), eventMinDuration, eventMaxDuration); err != nil {
+		SendMessage(bot, chatID, err.Error()+"\n"+usage)
+		return
+	}
+	t.Duration = t.Duration.Truncate(time.Minute)
+	if len(parts) == 6 {
+		if t.RemindBefore, err = parseReminder(parts[5]); err != nil {
+			SendMessage(bot, chatID, err.Error()+"\n"+usage)
+			return
+		}
+	}
+	t.NextRunAt = templateNext(rec, time.Now())
+
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		if err := tx.CreateEventTemplate(ctx, t); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobEventTemplate, int64(t.ID), templateRunAt(t))
+	})
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка добавления шаблона события: "+err.Error())
+		return
+	}
+	log.Printf("Добавлен шаблон события %d: %s, %s", t.ID, t.Name, t.Schedule)
+	SendMessage(bot, chatID, "Шаблон события добавлен: "+formatEventTemplate(currencyLabels(ctx), t))
+}
+
+// handleAdminRemoveEventTemplate обрабатывает команду /removeeventtemplate <номер>.
+// Уже созданные по шаблону события остаются.
+func handleAdminRemoveEventTemplate(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := parseEventID(args)
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /removeeventtemplate <номер шаблона>")
+		return
+	}
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		if err := tx.DeleteEventTemplate(ctx, id); err != nil {
+			return err
+		}
+		return tx.CancelJob(ctx, jobEventTemplate, int64(id))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Шаблон события не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка удаления шаблона события: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Шаблон события #%d удалён. Уже созданные по нему события остаются.", id))
+}
+
+// handleAdminPauseEventTemplate обрабатывает команды /pauseeventtemplate и
+// /resumeeventtemplate <номер>. После возобновления ближайшее событие считается
+// от текущего момента, повторы за время паузы не создаются.
+func handleAdminPauseEventTemplate(ctx context.Context, bot Sender, chatID int64, args string, paused bool) {
+	cmd := "/resumeeventtemplate"
+	if paused {
+		cmd = "/pauseeventtemplate"
+	}
+	id, err := parseEventID(args)
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: "+cmd+" <номер шаблона>")
+		return
+	}
+	var t *models.EventTemplate
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		if t, err = tx.GetEventTemplate(ctx, id); err != nil {
+			return err
+		}
+		t.Paused = paused
+		if paused {
+			if err := tx.UpdateEventTemplate(ctx, t); err != nil {
+				return err
+			}
+			return tx.CancelJob(ctx, jobEventTemplate, int64(t.ID))
+		}
+		rec, err := scheduler.ParseRecurrence(t.Schedule)
+		if err != nil {
+			return err
+		}
+		if t.NextRunAt = templateNext(rec, time.Now()); t.NextRunAt.IsZero() {
+			return fmt.Errorf("у расписания %q нет будущих повторов", t.Schedule)
+		}
+		if err := tx.UpdateEventTemplate(ctx, t); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobEventTemplate, int64(t.ID), templateRunAt(t))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Шаблон события не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка изменения шаблона события: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, formatEventTemplate(currencyLabels(ctx), t))
+}
+
+// handleAdminSkipEvent обрабатывает команды /skipevent <номер шаблона> [время] и
+// /unskipevent <номер шаблона> <время>. Без времени /skipevent пропускает
+// ближайший повтор, который ещё не пропущен.
+func handleAdminSkipEvent(ctx context.Context, bot Sender, chatID int64, args string, skip bool) {
+	usage := "Используйте: /unskipevent <номер шаблона> <время повтора, например 25.10 18:00>"
+	if skip {
+		usage = "Используйте: /skipevent <номер шаблона> [время повтора, например 25.10 18:00]"
+	}
+	idStr, atStr, _ := strings.Cut(strings.TrimSpace(args), " ")
+	id, err := parseEventID(idStr)
+	if err != nil || (!skip && strings.TrimSpace(atStr) == "") {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	t, err := Store.GetEventTemplate(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Шаблон события не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения шаблона события: "+err.Error())
+		return
+	}
+	rec, err := scheduler.ParseRecurrence(t.Schedule)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка расписания шаблона: "+err.Error())
+		return
+	}
+	skipped, err := Store.ListSkippedOccurrences(ctx, t.ID)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения пропусков: "+err.Error())
+		return
+	}
+	isSkipped := func(at time.Time) bool {
+		return slices.ContainsFunc(skipped, at.Equal)
+	}
+
+	var at time.Time
+	if atStr = strings.TrimSpace(atStr); atStr != "" {
+		if at, err = parseEventTime(atStr, gameNow()); err != nil {
+			SendMessage(bot, chatID, err.Error()+"\n"+usage)
+			return
+		}
+		if at.After(time.Now().AddDate(0, 0, upcomingMaxDays)) {
+			SendMessage(bot, chatID, fmt.Sprintf("Пропустить можно повтор не дальше чем через %d дн.", upcomingMaxDays))
+			return
+		}
+		next := t.NextRunAt
+		for !next.IsZero() && next.Before(at) {
+			next = templateNext(rec, next)
+		}
+		if !next.Equal(at) {
+			SendMessage(bot, chatID, fmt.Sprintf("В %s у шаблона #%d нет повтора, который ещё не создан. Ближайшие повторы: /upcomingevents",
+				eventTime(at), t.ID))
+			return
+		}
+	} else {
+		for at = t.NextRunAt; !at.IsZero() && isSkipped(at); at = templateNext(rec, at) {
+		}
+		if at.IsZero() {
+			SendMessage(bot, chatID, "У шаблона нет будущих повторов.")
+			return
+		}
+	}
+
+	if skip {
+		if isSkipped(at) {
+			SendMessage(bot, chatID, fmt.Sprintf("Повтор шаблона #%d на %s уже пропускается.", t.ID, eventTime(at)))
+			return
+		}
+		if err := Store.SkipOccurrence(ctx, t.ID, at); err != nil {
+			SendMessage(bot, chatID, "Ошибка пропуска повтора: "+err.Error())
+			return
+		}
+		SendMessage(bot, chatID, fmt.Sprintf("Событие по шаблону #%d «%s» на %s не будет создано. Вернуть: /unskipevent %d %s",
+			t.ID, t.Name, eventTime(at), t.ID, eventTime(at)))
+		return
+	}
+	err = Store.UnskipOccurrence(ctx, t.ID, at)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, fmt.Sprintf("Повтор шаблона #%d на %s не пропускается.", t.ID, eventTime(at)))
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка отмены пропуска: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Событие по шаблону #%d «%s» на %s снова будет создано.", t.ID, t.Name, eventTime(at)))
+}
+
+// upcomingEvent – строка /upcomingevents: созданное событие или будущий повтор шаблона.
+type upcomingEvent struct {
+	at   time.Time
+	text string
+}
+
+// handleAdminUpcomingEvents обрабатывает команду /upcomingevents [дней] – ближайшие
+// события из шаблонов: уже созданные и ещё не начавшиеся или идущие, а также
+// будущие повторы с отметкой о пропуске.
+func handleAdminUpcomingEvents(ctx context.Context, bot Sender, chatID int64, args string) {
+	days := upcomingDefaultDays
+	if s := strings.TrimSpace(args); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > upcomingMaxDays {
+			SendMessage(bot, chatID, fmt.Sprintf("Используйте: /upcomingevents [дней, от 1 до %d]", upcomingMaxDays))
+			return
+		}
+		days = n
+	}
+	now := time.Now()
+	until := now.AddDate(0, 0, days)
+
+	var upcoming []upcomingEvent
+	events, err := Store.GetActiveEvents(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения событий: "+err.Error())
+		return
+	}
+	for _, e := range events {
+		if e.TemplateID == 0 || e.StartsAt.After(until) {
+			continue
+		}
+		upcoming = append(upcoming, upcomingEvent{e.StartsAt, fmt.Sprintf("%s – %s «%s», шаблон #%d: создано, ID %d",
+			eventTime(e.StartsAt), eventTime(e.EndsAt), e.Name, e.TemplateID, e.ID)})
+	}
+
+	templates, err := Store.ListEventTemplates(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения шаблонов событий: "+err.Error())
+		return
+	}
+	for _, t := range templates {
+		if t.Paused {
+			continue
+		}
+		rec, err := scheduler.ParseRecurrence(t.Schedule)
+		if err != nil {
+			log.Printf("Ошибка расписания шаблона %d: %v", t.ID, err)
+			continue
+		}
+		skipped, err := Store.ListSkippedOccurrences(ctx, t.ID)
+		if err != nil {
+			log.Printf("Ошибка получения пропусков шаблона %d: %v", t.ID, err)
+		}
+		for _, at := range templateOccurrences(t, rec, until, upcomingLimit+1) {
+			text := fmt.Sprintf("%s – %s «%s», шаблон #%d", eventTime(at), eventTime(at.Add(t.Duration)), t.Name, t.ID)
+			if slices.ContainsFunc(skipped, at.Equal) {
+				text += ": пропуск"
+			}
+			upcoming = append(upcoming, upcomingEvent{at, text})
+		}
+	}
+	if len(upcoming) == 0 {
+		SendMessage(bot, chatID, fmt.Sprintf("В ближайшие %d дн. событий по шаблонам нет. Шаблоны: /eventtemplates", days))
+		return
+	}
+
+	slices.SortStableFunc(upcoming, func(a, b upcomingEvent) int { return a.at.Compare(b.at) })
+	var b strings.Builder
+	fmt.Fprintf(&b, "События по шаблонам на %d дн.:\n", days)
+	for i, u := range upcoming {
+		if i == upcomingLimit {
+			fmt.Fprintf(&b, "… и ещё %d\n", len(upcoming)-upcomingLimit)
+			break
+		}
+		b.WriteString(u.text + "\n")
+	}
+	b.WriteString("Пропустить повтор: /skipevent <номер шаблона> [время]; отменить созданное событие: /closeevent <ID>")
+	SendMessage(bot, chatID, b.String())
+}
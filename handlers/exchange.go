@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// exchangeConfirmTTL – сколько действует котировка обмена валют.
+const exchangeConfirmTTL = 2 * time.Minute
+
+// rateHistoryLimit – сколько изменений курса показывает /ratehistory.
+const rateHistoryLimit = 30
+
+// Ключи настроек обмена валют.
+const (
+	settingExchangeSpread = "exchange.spread_percent"
+	settingExchangeLimit  = "exchange.daily_limit." // + код валюты, которую отдаёт игрок
+)
+
+// Ошибки обмена валют.
+var (
+	errRateChanged       = errors.New("курс изменился")
+	errExchangeLimit     = errors.New("превышен дневной лимит обмена")
+	errExchangeTooSmall  = errors.New("слишком маленькая сумма: после обмена ничего не останется")
+	errExchangeProcessed = errors.New("обмен уже обработан")
+	errQuoteExpired      = errors.New("котировка устарела")
+)
+
+// formatRate – курс направления, например "10 🪙 Пиастры → 1 💠 Обломки".
+func formatRate(labels map[string]string, r *models.ExchangeRate) string {
+	from, ok := labels[r.FromCurrency]
+	if !ok {
+		from = r.FromCurrency
+	}
+	to, ok := labels[r.ToCurrency]
+	if !ok {
+		to = r.ToCurrency
+	}
+	if r.ToAmount == 0 {
+		return fmt.Sprintf("%s → %s: закрыт", from, to)
+	}
+	return fmt.Sprintf("%d %s → %d %s", r.FromAmount, from, r.ToAmount, to)
+}
+
+// parseRate разбирает курс: "10:1" – за 10 единиц выдаётся 1, "8" – за 1 выдаётся 8,
+// "off" или "0" – направление закрыто.
+func parseRate(s string) (fromAmount, toAmount int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "0" {
+		return 1, 0, nil
+	}
+	fromStr, toStr, ok := strings.Cut(s, ":")
+	if !ok {
+		fromStr, toStr = "1", s
+	}
+	fromAmount, err1 := strconv.Atoi(fromStr)
+	toAmount, err2 := strconv.Atoi(toStr)
+	if err1 != nil || err2 != nil || fromAmount <= 0 || toAmount <= 0 {
+		return 0, 0, fmt.Errorf("неверный курс %q", s)
+	}
+	return fromAmount, toAmount, nil
+}
+
+// quoteExchange считает, сколько валюты получит игрок за amount по курсу rate:
+// результат округляется вниз, спред из настроек округляется вверх.
+func quoteExchange(ctx context.Context, store db.Store, rate *models.ExchangeRate, amount int) (received, fee int, err error) {
+	spread, err := intSetting(ctx, store, settingExchangeSpread)
+	if err != nil {
+		return 0, 0, err
+	}
+	gross := amount * rate.ToAmount / rate.FromAmount
+	fee = (gross*spread + 99) / 100
+	if gross-fee <= 0 {
+		return 0, 0, errExchangeTooSmall
+	}
+	return gross - fee, fee, nil
+}
+
+// checkExchange проверяет дневной лимит и баланс игрока для обмена e.
+func checkExchange(ctx context.Context, store db.Store, profile *models.Profile, e *models.Exchange) error {
+	limit, err := intSetting(ctx, store, settingExchangeLimit+e.FromCurrency)
+	if err != nil {
+		return err
+	}
+	if limit > 0 {
+		done, err := store.ExchangedSince(ctx, profile.ID, e.FromCurrency, startOfDay(gameNow()))
+		if err != nil {
+			return err
+		}
+		if done+e.Amount > limit {
+			return fmt.Errorf("%w: сегодня обменяно %d из %d", errExchangeLimit, done, limit)
+		}
+	}
+	if profile.Balance(e.FromCurrency) < e.Amount {
+		return fmt.Errorf("%w: нужно %d, на балансе %d", errInsufficientFunds, e.Amount, profile.Balance(e.FromCurrency))
+	}
+	return nil
+}
+
+// openRates – текущие открытые курсы для игроков.
+func openRates(ctx context.Context) (string, error) {
+	rates, err := Store.ListExchangeRates(ctx)
+	if err != nil {
+		return "", err
+	}
+	labels := currencyLabels(ctx)
+	var lines []string
+	for _, r := range rates {
+		if r.ToAmount > 0 {
+			lines = append(lines, formatRate(labels, r))
+		}
+	}
+	if len(lines) == 0 {
+		return "Обмен валют сейчас закрыт.", nil
+	}
+	return "Курсы обмена:\n" + strings.Join(lines, "\n"), nil
+}
+
+// HandleExchange обрабатывает команду /exchange <сумма> <из валюты> <в валюту>.
+// Бот показывает, сколько будет получено, и выполняет обмен после подтверждения кнопкой.
+// Без аргументов показывает текущие курсы.
+func HandleExchange(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 3 {
+		rates, err := openRates(ctx)
+		if err != nil {
+			SendMessage(bot, msg.Chat.ID, "Ошибка получения курсов: "+err.Error())
+			return
+		}
+		SendMessage(bot, msg.Chat.ID, rates+"\n\nИспользуйте: /exchange <сумма> <из валюты> <в валюту>")
+		return
+	}
+	amount, err := strconv.Atoi(args[0])
+	if err != nil || amount <= 0 {
+		SendMessage(bot, msg.Chat.ID, "Сумма должна быть положительным числом.")
+		return
+	}
+	from, err := resolveCurrency(ctx, Store, args[1], true)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, currencyErrorText(ctx, err))
+		return
+	}
+	to, err := resolveCurrency(ctx, Store, args[2], true)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, currencyErrorText(ctx, err))
+		return
+	}
+	if from.Code == to.Code {
+		SendMessage(bot, msg.Chat.ID, "Укажите две разные валюты.")
+		return
+	}
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+
+	rate, err := Store.GetExchangeRate(ctx, from.Code, to.Code)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && rate.ToAmount == 0) {
+		SendMessage(bot, msg.Chat.ID, fmt.Sprintf("Обмен %s на %s закрыт.", from.Label(), to.Label()))
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения курса: "+err.Error())
+		return
+	}
+	received, fee, err := quoteExchange(ctx, Store, rate, amount)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Обмен невозможен: "+err.Error())
+		return
+	}
+	exchange := &models.Exchange{
+		ProfileID:    profile.ID,
+		RateID:       rate.ID,
+		FromCurrency: from.Code,
+		ToCurrency:   to.Code,
+		Amount:       amount,
+		Received:     received,
+		Fee:          fee,
+		Status:       models.ExchangePending,
+	}
+	// Предварительная проверка; окончательная выполняется в транзакции при подтверждении.
+	if err := checkExchange(ctx, Store, profile, exchange); err != nil {
+		SendMessage(bot, msg.Chat.ID, "Обмен невозможен: "+err.Error())
+		return
+	}
+	if err := Store.CreateExchange(ctx, exchange); err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка создания обмена: "+err.Error())
+		return
+	}
+
+	text := fmt.Sprintf("Курс: %s\nОтдаёте: %d %s\nПолучаете: %d %s",
+		formatRate(currencyLabels(ctx), rate), amount, from.Label(), received, to.Label())
+	if fee > 0 {
+		text += fmt.Sprintf("\nСпред: %d %s", fee, to.Label())
+	}
+	text += fmt.Sprintf("\nКотировка действует %d мин.", int(exchangeConfirmTTL.Minutes()))
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Обменять", fmt.Sprintf("exchange:confirm:%d", exchange.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("Отменить", fmt.Sprintf("exchange:cancel:%d", exchange.ID)),
+	))
+	bot.Send(reply)
+}
+
+// handleExchangeCallback обрабатывает кнопки обмена валют: "confirm:<id>" и "cancel:<id>".
+func handleExchangeCallback(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, data string) {
+	action, idStr, _ := strings.Cut(data, ":")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		answerCallback(bot, cq, "Неверная кнопка.")
+		return
+	}
+	exchange, err := Store.GetExchange(ctx, id)
+	if err != nil {
+		answerCallback(bot, cq, "Обмен не найден.")
+		return
+	}
+	profile, err := Store.GetProfile(ctx, cq.From.ID)
+	if err != nil || profile.ID != exchange.ProfileID {
+		answerCallback(bot, cq, "Это не ваш обмен.")
+		return
+	}
+	if exchange.Status != models.ExchangePending {
+		answerCallback(bot, cq, "Обмен уже обработан.")
+		return
+	}
+
+	switch action {
+	case "cancel":
+		exchange.Status = models.ExchangeCancelled
+		if err := Store.UpdateExchange(ctx, exchange); err != nil {
+			answerCallback(bot, cq, "Ошибка отмены обмена.")
+			return
+		}
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, "Обмен отменён.")
+	case "confirm":
+		confirmExchange(ctx, bot, cq, exchange)
+	default:
+		answerCallback(bot, cq, "Неверная кнопка.")
+	}
+}
+
+// confirmExchange выполняет обмен по показанной котировке. Если курс с тех пор
+// изменился или котировка устарела, обмен отменяется в той же транзакции.
+func confirmExchange(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, exchange *models.Exchange) {
+	labels := currencyLabels(ctx)
+	var rejected error // причина отмены обмена; отмена сохраняется вместе с проверкой
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		e, err := tx.GetExchange(ctx, exchange.ID)
+		if err != nil {
+			return err
+		}
+		if e.Status != models.ExchangePending {
+			return errExchangeProcessed
+		}
+		if rejected, err = checkExchangeQuote(ctx, tx, e); err != nil {
+			return err
+		}
+		if rejected != nil {
+			e.Status = models.ExchangeCancelled
+			*exchange = *e
+			return tx.UpdateExchange(ctx, e)
+		}
+
+		entries := []*models.LedgerEntry{
+			{ProfileID: e.ProfileID, Currency: e.FromCurrency, Delta: -e.Amount},
+			{ProfileID: e.ProfileID, Currency: e.ToCurrency, Delta: e.Received},
+		}
+		for _, entry := range entries {
+			entry.Reason = fmt.Sprintf("Обмен валюты: %d %s → %d %s", e.Amount, labels[e.FromCurrency], e.Received, labels[e.ToCurrency])
+			entry.SourceType = models.LedgerSourceExchange
+			entry.SourceID = int64(e.ID)
+			if err := tx.ChangeBalance(ctx, entry); err != nil {
+				return fmt.Errorf("ошибка изменения баланса: %w", err)
+			}
+		}
+
+		e.Status = models.ExchangeCompleted
+		e.CompletedAt = time.Now()
+		*exchange = *e
+		return tx.UpdateExchange(ctx, e)
+	})
+	switch {
+	case errors.Is(err, errExchangeProcessed):
+		answerCallback(bot, cq, "Обмен уже обработан.")
+		return
+	case err != nil:
+		log.Printf("Ошибка обмена валют %d: %v", exchange.ID, err)
+		answerCallback(bot, cq, "Ошибка обмена, попробуйте позже.")
+		return
+	case errors.Is(rejected, errQuoteExpired):
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, "Котировка устарела, обмен отменён. Отправьте /exchange ещё раз.")
+		return
+	case rejected != nil:
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, "Обмен невозможен: "+rejected.Error()+". Отправьте /exchange ещё раз.")
+		return
+	}
+
+	answerCallback(bot, cq, "Обмен выполнен.")
+	editCallbackMessage(bot, cq, fmt.Sprintf("Обмен выполнен: %d %s → %d %s",
+		exchange.Amount, labels[exchange.FromCurrency], exchange.Received, labels[exchange.ToCurrency]))
+}
+
+// checkExchangeQuote проверяет, что котировка ещё действует: не устарела, курс не
+// менялся, а у персонажа хватает валюты и дневного лимита. Возвращает причину отмены
+// обмена или ошибку базы данных.
+func checkExchangeQuote(ctx context.Context, tx db.Store, e *models.Exchange) (rejected, err error) {
+	if time.Since(e.CreatedAt) > exchangeConfirmTTL {
+		return errQuoteExpired, nil
+	}
+	rate, err := tx.GetExchangeRate(ctx, e.FromCurrency, e.ToCurrency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errRateChanged, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if rate.ID != e.RateID {
+		return errRateChanged, nil
+	}
+	profile, err := tx.GetProfileByID(ctx, e.ProfileID)
+	if err != nil {
+		return errProfileNotFound, nil
+	}
+	err = checkExchange(ctx, tx, profile, e)
+	if errors.Is(err, errInsufficientFunds) || errors.Is(err, errExchangeLimit) {
+		return err, nil
+	}
+	return nil, err
+}
+
+// handleAdminRates обрабатывает команду админского бота /rates – курсы, спред и лимиты обмена.
+func handleAdminRates(ctx context.Context, bot Sender, chatID int64) {
+	rates, err := Store.ListExchangeRates(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения курсов: "+err.Error())
+		return
+	}
+	spread, err := intSetting(ctx, Store, settingExchangeSpread)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка чтения настроек: "+err.Error())
+		return
+	}
+	currencies, err := Store.ListCurrencies(ctx, false)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения валют: "+err.Error())
+		return
+	}
+
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("Курсы обмена:")
+	if len(rates) == 0 {
+		b.WriteString("\nне заданы")
+	}
+	for _, r := range rates {
+		fmt.Fprintf(&b, "\n%s (с %s)", formatRate(labels, r), eventTime(r.CreatedAt))
+	}
+	fmt.Fprintf(&b, "\nСпред: %d%%\nДневные лимиты обмена:", spread)
+	for _, c := range currencies {
+		limit, err := intSetting(ctx, Store, settingExchangeLimit+c.Code)
+		if err != nil {
+			SendMessage(bot, chatID, "Ошибка чтения настроек: "+err.Error())
+			return
+		}
+		if limit > 0 {
+			fmt.Fprintf(&b, "\n%s: %d", c.Label(), limit)
+		} else {
+			fmt.Fprintf(&b, "\n%s: без лимита", c.Label())
+		}
+	}
+	SendMessage(bot, chatID, b.String())
+}
+
+// handleAdminSetRate обрабатывает команду /setrate <из валюты> <в валюту> <курс>.
+func handleAdminSetRate(ctx context.Context, bot Sender, chatID int64, adminID int64, args string) {
+	const usage = "Используйте: /setrate <из валюты> <в валюту> <курс>, где курс – \"10:1\" (за 10 выдаётся 1), \"8\" (за 1 выдаётся 8) или \"off\""
+	parts := strings.Fields(args)
+	if len(parts) != 3 {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	from, err := resolveCurrency(ctx, Store, parts[0], false)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	to, err := resolveCurrency(ctx, Store, parts[1], false)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	if from.Code == to.Code {
+		SendMessage(bot, chatID, "Укажите две разные валюты.")
+		return
+	}
+	fromAmount, toAmount, err := parseRate(parts[2])
+	if err != nil {
+		SendMessage(bot, chatID, usage+" ("+err.Error()+")")
+		return
+	}
+	rate := &models.ExchangeRate{
+		FromCurrency: from.Code,
+		ToCurrency:   to.Code,
+		FromAmount:   fromAmount,
+		ToAmount:     toAmount,
+		SetBy:        adminID,
+	}
+	if err := Store.SetExchangeRate(ctx, rate); err != nil {
+		SendMessage(bot, chatID, "Ошибка сохранения курса: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, "Курс обмена: "+formatRate(currencyLabels(ctx), rate))
+}
+
+// handleAdminSetSpread обрабатывает команду /setspread <процент>.
+func handleAdminSetSpread(ctx context.Context, bot Sender, chatID int64, args string) {
+	percent, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || percent < 0 || percent >= 100 {
+		SendMessage(bot, chatID, "Используйте: /setspread <процент от 0 до 99>")
+		return
+	}
+	if err := Store.SetSetting(ctx, settingExchangeSpread, strconv.Itoa(percent)); err != nil {
+		SendMessage(bot, chatID, "Ошибка сохранения настройки: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Спред обмена валют: %d%%", percent))
+}
+
+// handleAdminSetExchangeLimit обрабатывает команду /setexchangelimit <валюта> <сумма в день>.
+// Лимит считается по валюте, которую игрок отдаёт; 0 снимает ограничение.
+func handleAdminSetExchangeLimit(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		SendMessage(bot, chatID, "Используйте: /setexchangelimit <валюта> <сумма в день, 0 – без лимита>")
+		return
+	}
+	currency, err := resolveCurrency(ctx, Store, parts[0], false)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	limit, err := strconv.Atoi(parts[1])
+	if err != nil || limit < 0 {
+		SendMessage(bot, chatID, "Лимит должен быть неотрицательным числом.")
+		return
+	}
+	if err := Store.SetSetting(ctx, settingExchangeLimit+currency.Code, strconv.Itoa(limit)); err != nil {
+		SendMessage(bot, chatID, "Ошибка сохранения настройки: "+err.Error())
+		return
+	}
+	if limit == 0 {
+		SendMessage(bot, chatID, fmt.Sprintf("Лимит обмена %s снят.", currency.Label()))
+	} else {
+		SendMessage(bot, chatID, fmt.Sprintf("Лимит обмена %s: %d в день.", currency.Label(), limit))
+	}
+}
+
+// handleAdminRateHistory обрабатывает команду /ratehistory [<из валюты> <в валюту>].
+func handleAdminRateHistory(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Fields(args)
+	var from, to string
+	switch len(parts) {
+	case 0:
+	case 2:
+		f, err := resolveCurrency(ctx, Store, parts[0], false)
+		if err != nil {
+			SendMessage(bot, chatID, currencyErrorText(ctx, err))
+			return
+		}
+		t, err := resolveCurrency(ctx, Store, parts[1], false)
+		if err != nil {
+			SendMessage(bot, chatID, currencyErrorText(ctx, err))
+			return
+		}
+		from, to = f.Code, t.Code
+	default:
+		SendMessage(bot, chatID, "Используйте: /ratehistory [<из валюты> <в валюту>]")
+		return
+	}
+	rates, err := Store.ExchangeRateHistory(ctx, from, to, rateHistoryLimit)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения истории курсов: "+err.Error())
+		return
+	}
+	if len(rates) == 0 {
+		SendMessage(bot, chatID, "Курсы ещё не задавались.")
+		return
+	}
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("История курсов:")
+	for _, r := range rates {
+		fmt.Fprintf(&b, "\n%s  %s (админ %d)", eventTime(r.CreatedAt), formatRate(labels, r), r.SetBy)
+	}
+	SendMessage(bot, chatID, b.String())
+}
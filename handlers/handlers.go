@@ -1,38 +1,89 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"strconv"
 	"strings"
+	"time"
+
 	"telegram-bot/db"
 	"telegram-bot/utils"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// Ошибки бизнес-логики, которыми транзакции сообщают обработчику, какой ответ отправить.
+var (
+	errAlreadyParticipated = errors.New("уже участвует")
+	errNotParticipated     = errors.New("не участвует")
+	errProfileNotFound     = errors.New("профиль не найден")
+	errCurrencyRetired     = errors.New("валюта выведена из оборота")
+)
+
 // HandleUpdate обрабатывает входящие обновления для пользовательского бота.
-func HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+func HandleUpdate(ctx context.Context, bot Sender, update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		HandleCallbackQuery(ctx, bot, update.CallbackQuery)
+		return
+	}
 	if update.Message != nil {
 		if update.Message.IsCommand() {
 			switch strings.ToLower(update.Message.Command()) {
 			case "start":
-				HandleStart(bot, update.Message)
+				HandleStart(ctx, bot, update.Message)
 			case "createprofile":
-				HandleStart(bot, update.Message) // Если профиль уже существует, HandleStart уведомит.
+				HandleStart(ctx, bot, update.Message) // Если профиль уже существует, HandleStart уведомит.
 			case "profile":
-				profile, err := db.GetProfile(update.Message.From.ID)
+				profile, err := Store.GetProfile(ctx, update.Message.From.ID)
 				if err != nil || profile == nil || profile.Name == "" {
 					SendMessage(bot, update.Message.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
 				} else {
-					SendMessage(bot, update.Message.Chat.ID, utils.FormatProfile(profile))
+					SendMessage(bot, update.Message.Chat.ID, utils.FormatProfile(profile)+dailyStreakText(ctx, profile.ID))
 				}
 			case "deleteprofile":
-				HandleDeleteProfile(bot, update.Message)
+				HandleDeleteProfile(ctx, bot, update.Message)
 			case "help":
-				HandleUserHelp(bot, update.Message)
+				HandleUserHelp(ctx, bot, update.Message)
 			case "attend":
-				HandleAttendEvent(bot, update.Message)
+				HandleAttendEvent(ctx, bot, update.Message)
 			case "unattend":
-				HandleUnattendEvent(bot, update.Message)
+				HandleUnattendEvent(ctx, bot, update.Message)
+			case "events":
+				HandleEvents(ctx, bot, update.Message)
+			case "cancel":
+				HandleCancelRegistration(ctx, bot, update.Message)
+			case "balance":
+				HandleBalance(ctx, bot, update.Message)
+			case "history":
+				HandleHistory(ctx, bot, update.Message)
+			case "pay":
+				HandlePay(ctx, bot, update.Message)
+			case "inventory":
+				HandleInventory(ctx, bot, update.Message)
+			case "shop":
+				HandleShop(ctx, bot, update.Message)
+			case "buy":
+				HandleBuy(ctx, bot, update.Message)
+			case "trade":
+				HandleTrade(ctx, bot, update.Message)
+			case "offer":
+				HandleOffer(ctx, bot, update.Message)
+			case "tradecancel":
+				HandleTradeCancel(ctx, bot, update.Message)
+			case "auction":
+				HandleAuction(ctx, bot, update.Message)
+			case "auctions":
+				HandleAuctions(ctx, bot, update.Message)
+			case "bid":
+				HandleBid(ctx, bot, update.Message)
+			case "daily":
+				HandleDaily(ctx, bot, update.Message)
+			case "exchange":
+				HandleExchange(ctx, bot, update.Message)
 			// Можно добавить другие команды (например, /setname, /setage, и т.д.)
 			default:
 				SendMessage(bot, update.Message.Chat.ID, "Неизвестная команда. Попробуйте /help для списка доступных команд.")
@@ -40,26 +91,40 @@ func HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 		} else {
 			// Если сообщение не команда и пользователь находится в процессе регистрации,
 			// обрабатываем последовательность регистрации.
-			if state, exists := registrationStates[update.Message.From.ID]; exists {
-				HandleRegistrationConversation(bot, update.Message, state)
+			state, err := Store.GetRegistrationState(ctx, update.Message.From.ID)
+			if err == nil {
+				HandleRegistrationConversation(ctx, bot, update.Message, state)
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Ошибка чтения черновика регистрации %d: %v", update.Message.From.ID, err)
 			}
 		}
 	}
 }
 
-// HandleAttendEvent обрабатывает команду /attend <event_id>.
-func HandleAttendEvent(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
-	arg := strings.TrimSpace(msg.CommandArguments())
-	if arg == "" {
-		SendMessage(bot, msg.Chat.ID, "Используйте: /attend <event_id>")
+// HandleAttendEvent обрабатывает команду /attend <event_id> [код].
+func HandleAttendEvent(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 || len(args) > 2 {
+		SendMessage(bot, msg.Chat.ID, "Используйте: /attend <event_id> [код]")
 		return
 	}
-	eventID, err := strconv.Atoi(arg)
+	eventID, err := strconv.Atoi(args[0])
 	if err != nil {
 		SendMessage(bot, msg.Chat.ID, "Event ID должно быть числом.")
 		return
 	}
-	event, err := db.GetEventByID(eventID)
+	var code string
+	if len(args) == 2 {
+		code = args[1]
+	}
+	attendEvent(ctx, bot, msg, eventID, code)
+}
+
+// attendEvent отмечает отправителя msg на событии и начисляет награду.
+// Для события с кодом отметки code проверяется, неверные коды ограничены.
+// Если свободных мест нет, пользователь встаёт в лист ожидания.
+func attendEvent(ctx context.Context, bot Sender, msg *tgbotapi.Message, eventID int, code string) {
+	event, err := Store.GetEventByID(ctx, eventID)
 	if err != nil || event == nil {
 		SendMessage(bot, msg.Chat.ID, "Событие не найдено.")
 		return
@@ -68,54 +133,46 @@ func HandleAttendEvent(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		SendMessage(bot, msg.Chat.ID, "Событие не активно.")
 		return
 	}
-
-	// Проверяем, участвовал ли пользователь.
-	participated, err := db.UserParticipatedInEvent(eventID, msg.From.ID)
-	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Ошибка проверки участия: "+err.Error())
+	if text := eventWindowText(event, time.Now()); text != "" {
+		SendMessage(bot, msg.Chat.ID, text)
 		return
 	}
-	if participated {
-		SendMessage(bot, msg.Chat.ID, "Вы уже приняли участие в этом событии.")
-		return
+	if event.CheckinSecret != "" {
+		if text := verifyCheckin(ctx, event, msg.From.ID, code); text != "" {
+			SendMessage(bot, msg.Chat.ID, text)
+			return
+		}
 	}
 
-	// Получаем профиль пользователя.
-	profile, err := db.GetProfile(msg.From.ID)
-	if err != nil || profile == nil {
+	res, err := joinEvent(ctx, event.ID, msg.From.ID)
+	switch {
+	case errors.Is(err, errEventClosed):
+		SendMessage(bot, msg.Chat.ID, "Событие не активно.")
+	case errors.Is(err, errEventWindow):
+		SendMessage(bot, msg.Chat.ID, eventWindowText(res.event, time.Now()))
+	case errors.Is(err, errAlreadyParticipated):
+		SendMessage(bot, msg.Chat.ID, "Вы уже приняли участие в этом событии.")
+	case errors.Is(err, errProfileNotFound):
 		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Зарегистрируйтесь через /start.")
-		return
-	}
-
-	// Начисляем валюту.
-	switch event.CurrencyType {
-	case "piastres", "пиastres":
-		profile.Piastres += event.Amount
-	case "oblomki", "обломки":
-		profile.Oblomki += event.Amount
-	default:
+	case errors.Is(err, db.ErrUnknownCurrency):
 		SendMessage(bot, msg.Chat.ID, "Неизвестный тип валюты в событии.")
-		return
-	}
-
-	// Сохраняем профиль и участие.
-	err = db.SaveProfile(profile)
-	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Ошибка сохранения профиля: "+err.Error())
-		return
+	case err != nil:
+		SendMessage(bot, msg.Chat.ID, "Ошибка: "+err.Error())
+	case res.waitlist > 0:
+		SendMessage(bot, msg.Chat.ID, fmt.Sprintf("Свободных мест нет. Вы в листе ожидания, ваше место в очереди: %d.\n"+
+			"Когда место освободится, вы будете отмечены автоматически и получите награду. Покинуть очередь: /unattend %d",
+			res.waitlist, event.ID))
+	default:
+		SendMessage(bot, msg.Chat.ID, "Вы успешно приняли участие в событии!"+debtRepaymentText(res.label, res.credit)+
+			"\nВаш профиль:\n"+utils.FormatProfile(res.profile))
 	}
-
-	err = db.AddEventParticipation(eventID, msg.From.ID)
-	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Ошибка регистрации участия: "+err.Error())
-		return
+	if err == nil {
+		requestEventRefresh(ctx, event.ID)
 	}
-
-	SendMessage(bot, msg.Chat.ID, "Вы успешно приняли участие в событии!\nВаш профиль:\n"+utils.FormatProfile(profile))
 }
 
 // HandleUnattendEvent обрабатывает команду /unattend <event_id>.
-func HandleUnattendEvent(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func HandleUnattendEvent(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
 	arg := strings.TrimSpace(msg.CommandArguments())
 	if arg == "" {
 		SendMessage(bot, msg.Chat.ID, "Используйте: /unattend <event_id>")
@@ -126,7 +183,7 @@ func HandleUnattendEvent(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		SendMessage(bot, msg.Chat.ID, "Event ID должно быть числом.")
 		return
 	}
-	event, err := db.GetEventByID(eventID)
+	event, err := Store.GetEventByID(ctx, eventID)
 	if err != nil || event == nil {
 		SendMessage(bot, msg.Chat.ID, "Событие не найдено.")
 		return
@@ -136,38 +193,28 @@ func HandleUnattendEvent(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
-	// Проверяем, участвовал ли пользователь.
-	participated, err := db.UserParticipatedInEvent(eventID, msg.From.ID)
-	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Ошибка проверки участия: "+err.Error())
-		return
-	}
-	if !participated {
+	res, err := leaveEvent(ctx, event.ID, msg.From.ID)
+	switch {
+	case errors.Is(err, errEventClosed):
+		SendMessage(bot, msg.Chat.ID, "Событие не активно.")
+	case errors.Is(err, errEventWindow):
+		SendMessage(bot, msg.Chat.ID, eventWindowText(res.event, time.Now()))
+	case errors.Is(err, errNotParticipated):
 		SendMessage(bot, msg.Chat.ID, "Вы не принимали участие в этом событии.")
-		return
-	}
-
-	// Получаем профиль пользователя.
-	profile, err := db.GetProfile(msg.From.ID)
-	if err != nil || profile == nil {
+	case errors.Is(err, errProfileNotFound):
 		SendMessage(bot, msg.Chat.ID, "Профиль не найден.")
-		return
-	}
-
-	// Списываем валюту.
-
-	// Сохраняем изменения.
-	err = db.SaveProfile(profile)
-	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Ошибка сохранения профиля: "+err.Error())
-		return
+	case errors.Is(err, db.ErrUnknownCurrency):
+		SendMessage(bot, msg.Chat.ID, "Неизвестный тип валюты в событии.")
+	case err != nil:
+		SendMessage(bot, msg.Chat.ID, "Ошибка: "+err.Error())
+	case res.waitlist > 0:
+		SendMessage(bot, msg.Chat.ID, "Вы покинули лист ожидания события.")
+	default:
+		SendMessage(bot, msg.Chat.ID, "Вы отменили участие в событии. Валюта списана."+debtText(res.profile)+
+			"\nВаш профиль:\n"+utils.FormatProfile(res.profile))
+		notifyPromoted(event, res.promoted)
 	}
-
-	err = db.RemoveEventParticipation(eventID, msg.From.ID)
-	if err != nil {
-		SendMessage(bot, msg.Chat.ID, "Ошибка отмены участия: "+err.Error())
-		return
+	if err == nil {
+		requestEventRefresh(ctx, event.ID)
 	}
-
-	SendMessage(bot, msg.Chat.ID, "Вы отменили участие в событии. Валюта списана.\nВаш профиль:\n"+utils.FormatProfile(profile))
 }
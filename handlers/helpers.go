@@ -4,8 +4,16 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// Sender – часть Bot API, которая нужна обработчикам.
+// Ему удовлетворяет *tgbotapi.BotAPI, а в тестах – бот, направленный
+// на поддельный сервер из пакета telegramtest.
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+}
+
 // sendMessage – утилита для отправки сообщений
-func sendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) {
+func sendMessage(bot Sender, chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	bot.Send(msg)
 }
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+	"telegram-bot/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// catalogItem возвращает предмет справочника с названием name, добавляя его при необходимости.
+func catalogItem(ctx context.Context, store db.Store, name, description string) (*models.Item, error) {
+	item, err := store.GetItemByName(ctx, name)
+	if err == nil {
+		return item, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	item = &models.Item{Name: name, Description: description}
+	if err := store.CreateItem(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// findItem находит предмет справочника по ID или названию.
+func findItem(ctx context.Context, ref string) (*models.Item, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return Store.GetItem(ctx, id)
+	}
+	return Store.GetItemByName(ctx, ref)
+}
+
+// HandleInventory обрабатывает команду /inventory – инвентарь персонажа.
+func HandleInventory(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	SendMessage(bot, msg.Chat.ID, "Инвентарь:\n"+utils.FormatInventory(profile.Items, false))
+}
+
+// handleAdminCatalog обрабатывает команду админского бота /catalog – справочник предметов.
+func handleAdminCatalog(ctx context.Context, bot Sender, chatID int64) {
+	items, err := Store.ListItems(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения предметов: "+err.Error())
+		return
+	}
+	if len(items) == 0 {
+		SendMessage(bot, chatID, "Справочник предметов пуст. Добавьте: /newitem <название>|<описание>")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("Предметы:\n")
+	for _, item := range items {
+		fmt.Fprintf(&b, "%d. %s", item.ID, item.Name)
+		if item.Description != "" {
+			b.WriteString(" – " + item.Description)
+		}
+		b.WriteString("\n")
+	}
+	SendMessage(bot, chatID, strings.TrimRight(b.String(), "\n"))
+}
+
+// handleAdminNewItem обрабатывает команду /newitem <название>|<описание>.
+func handleAdminNewItem(ctx context.Context, bot Sender, chatID int64, args string) {
+	name, description, _ := strings.Cut(args, "|")
+	item := &models.Item{Name: strings.TrimSpace(name), Description: strings.TrimSpace(description)}
+	if item.Name == "" {
+		SendMessage(bot, chatID, "Используйте: /newitem <название>|<описание>")
+		return
+	}
+	err := Store.CreateItem(ctx, item)
+	if errors.Is(err, db.ErrItemExists) {
+		SendMessage(bot, chatID, "Предмет с таким названием уже есть в справочнике.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка добавления предмета: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Предмет добавлен в справочник: %s (ID %d)", item.Name, item.ID))
+}
+
+// parseInventoryArgs разбирает аргументы вида "<ID анкеты> <предмет> [количество]",
+// где предмет – ID или название из справочника (название может содержать пробелы).
+func parseInventoryArgs(args string) (profileID int, itemRef string, quantity int, err error) {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return 0, "", 0, errors.New("не хватает аргументов")
+	}
+	profileID, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", 0, errors.New("неверный ID анкеты")
+	}
+	itemRef, quantity, err = parseItemQuantity(strings.Join(fields[1:], " "))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	return profileID, itemRef, quantity, nil
+}
+
+// parseItemQuantity разбирает "<предмет> [количество]"; без количества – 1 штука.
+func parseItemQuantity(s string) (itemRef string, quantity int, err error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return "", 0, errors.New("не указан предмет")
+	}
+	quantity = 1
+	if len(fields) > 1 {
+		if q, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			if q <= 0 {
+				return "", 0, errors.New("количество должно быть положительным")
+			}
+			quantity = q
+			fields = fields[:len(fields)-1]
+		}
+	}
+	return strings.Join(fields, " "), quantity, nil
+}
+
+// handleAdminGrantItem обрабатывает команду /grantitem <ID анкеты> <предмет> [количество] [| заметка].
+func handleAdminGrantItem(ctx context.Context, bot Sender, chatID int64, args string) {
+	args, note, _ := strings.Cut(args, "|")
+	profileID, itemRef, quantity, err := parseInventoryArgs(args)
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /grantitem <ID анкеты> <ID или название предмета> [количество] [| заметка] ("+err.Error()+")")
+		return
+	}
+	profile, err := Store.GetProfileByID(ctx, profileID)
+	if err != nil {
+		SendMessage(bot, chatID, "Анкета не найдена.")
+		return
+	}
+	item, err := findItem(ctx, itemRef)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Предмет не найден в справочнике. Добавьте его: /newitem <название>|<описание>")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка поиска предмета: "+err.Error())
+		return
+	}
+	if err := Store.AddInventoryItem(ctx, profile.ID, item.ID, quantity, strings.TrimSpace(note)); err != nil {
+		SendMessage(bot, chatID, "Ошибка выдачи предмета: "+err.Error())
+		return
+	}
+	profile, err = Store.GetProfileByID(ctx, profile.ID)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения анкеты: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Выдано: %s ×%d.\nИнвентарь анкеты ID %d:\n%s",
+		item.Name, quantity, profile.ID, utils.FormatInventory(profile.Items, true)))
+}
+
+// handleAdminRevokeItem обрабатывает команду /revokeitem <ID анкеты> <предмет> [количество].
+// Вместо предмета можно указать номер записи инвентаря #N (например, для старых заметок).
+func handleAdminRevokeItem(ctx context.Context, bot Sender, chatID int64, args string) {
+	profileID, itemRef, quantity, err := parseInventoryArgs(args)
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /revokeitem <ID анкеты> <ID или название предмета | #номер записи> [количество] ("+err.Error()+")")
+		return
+	}
+	profile, err := Store.GetProfileByID(ctx, profileID)
+	if err != nil {
+		SendMessage(bot, chatID, "Анкета не найдена.")
+		return
+	}
+
+	var result string
+	if entry, ok := strings.CutPrefix(itemRef, "#"); ok {
+		entryID, err := strconv.Atoi(entry)
+		if err != nil {
+			SendMessage(bot, chatID, "Неверный номер записи инвентаря.")
+			return
+		}
+		err = Store.DeleteInventoryEntry(ctx, profile.ID, entryID)
+		if errors.Is(err, sql.ErrNoRows) {
+			SendMessage(bot, chatID, "В инвентаре нет такой записи.")
+			return
+		} else if err != nil {
+			SendMessage(bot, chatID, "Ошибка удаления записи: "+err.Error())
+			return
+		}
+		result = fmt.Sprintf("Запись #%d удалена.", entryID)
+	} else {
+		item, err := findItem(ctx, itemRef)
+		if errors.Is(err, sql.ErrNoRows) {
+			SendMessage(bot, chatID, "Предмет не найден в справочнике.")
+			return
+		} else if err != nil {
+			SendMessage(bot, chatID, "Ошибка поиска предмета: "+err.Error())
+			return
+		}
+		removed, err := Store.RemoveInventoryItem(ctx, profile.ID, item.ID, quantity)
+		if errors.Is(err, sql.ErrNoRows) {
+			SendMessage(bot, chatID, "У персонажа нет этого предмета.")
+			return
+		} else if err != nil {
+			SendMessage(bot, chatID, "Ошибка изъятия предмета: "+err.Error())
+			return
+		}
+		result = fmt.Sprintf("Изъято: %s ×%d.", item.Name, removed)
+	}
+
+	profile, err = Store.GetProfileByID(ctx, profile.ID)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения анкеты: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("%s\nИнвентарь анкеты ID %d:\n%s",
+		result, profile.ID, utils.FormatInventory(profile.Items, true)))
+}
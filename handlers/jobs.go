@@ -0,0 +1,17 @@
+package handlers
+
+import "telegram-bot/scheduler"
+
+// RegisterJobs регистрирует обработчики отложенных задач бота.
+func RegisterJobs(s *scheduler.Scheduler) {
+	s.Handle(jobAuctionClose, closeAuctionJob)
+	s.Handle(jobTradeExpire, expireTradeJob)
+	s.Handle(jobEventClose, closeEventJob)
+	s.Handle(jobEventStart, startEventJob)
+	s.Handle(jobEventRemind, remindEventJob)
+	s.Handle(jobEventRefresh, refreshEventMessagesJob)
+	s.Handle(jobEventCheckin, checkinRotationJob)
+	s.Handle(jobEventTemplate, createTemplateEventJob)
+	s.Handle(jobStipendPay, payStipendJob)
+	s.Handle(jobReconcile, reconcileJob)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+	"telegram-bot/scheduler"
+)
+
+// jobReconcile – ежедневная задача планировщика, сверяющая журнал с источниками операций; RefID не используется.
+const jobReconcile = "balance.reconcile"
+
+// reconcileSchedule – когда выполняется ежедневная сверка (в часовом поясе timezone).
+var reconcileSchedule = scheduler.Recurrence{Hour: 4}
+
+// ScheduleReconcile планирует ближайшую ежедневную сверку. Вызывается при старте.
+func ScheduleReconcile(ctx context.Context) error {
+	return Store.ScheduleJob(ctx, jobReconcile, 0, reconcileSchedule.Next(gameNow()))
+}
+
+// driftSource – источник расхождения и что с ним не так.
+func driftSource(d models.LedgerDrift) string {
+	var source, problem string
+	switch d.SourceType {
+	case models.LedgerSourceEvent:
+		source = fmt.Sprintf("событие %d", d.SourceID)
+		switch {
+		case d.Expected == 0:
+			problem = "начислено без участия"
+		case d.Recorded == 0:
+			problem = "награда не начислена"
+		case d.Recorded > d.Expected:
+			problem = "награда начислена повторно"
+		default:
+			problem = "награда начислена не полностью"
+		}
+	default:
+		source = fmt.Sprintf("начисление администратора #%d", d.SourceID)
+		problem = "сумма в журнале не совпадает"
+		if d.Recorded == 0 {
+			problem = "нет операции в журнале"
+		}
+	}
+	return source + " – " + problem
+}
+
+// formatDrifts – отчёт о расхождениях журнала с источниками, сгруппированный по анкетам.
+func formatDrifts(ctx context.Context, drifts []models.LedgerDrift) string {
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Расхождения журнала операций с источниками (%d):", len(drifts))
+	for i, d := range drifts {
+		if i == 0 || drifts[i-1].ProfileID != d.ProfileID {
+			fmt.Fprintf(&b, "\nАнкета %d (%s):", d.ProfileID, profileName(ctx, d.ProfileID))
+		}
+		label, ok := labels[d.Currency]
+		if !ok {
+			label = d.Currency
+		}
+		fmt.Fprintf(&b, "\n  %s: в журнале %d, должно быть %d %s (исправление %+d)",
+			driftSource(d), d.Recorded, d.Expected, label, d.Expected-d.Recorded)
+	}
+	return b.String()
+}
+
+// reconcileJob – задача планировщика: сверяет журнал с источниками, сообщает
+// о расхождениях в чаты администраторов и планирует следующую сверку.
+func reconcileJob(ctx context.Context, job *models.Job) error {
+	drifts, err := Store.LedgerDrifts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(drifts) > 0 {
+		notifyAdmins(formatDrifts(ctx, drifts) + "\nИсправить: /reconcile apply")
+	}
+	return Store.ScheduleJob(ctx, jobReconcile, 0, reconcileSchedule.Next(gameNow()))
+}
+
+// handleAdminReconcile обрабатывает команду админского бота /reconcile [apply]:
+// без аргументов показывает расхождения журнала с источниками, с apply – исправляет
+// их: на каждое расхождение в журнал записывается операция на разницу с тем же
+// источником, и баланс меняется на ту же сумму.
+func handleAdminReconcile(ctx context.Context, bot Sender, chatID, adminID int64, args string) {
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "":
+		drifts, err := Store.LedgerDrifts(ctx)
+		if err != nil {
+			SendMessage(bot, chatID, "Ошибка сверки: "+err.Error())
+			return
+		}
+		if len(drifts) == 0 {
+			SendMessage(bot, chatID, "Журнал операций совпадает с участием в событиях и начислениями администраторов.")
+			return
+		}
+		SendMessage(bot, chatID, formatDrifts(ctx, drifts)+"\nИсправить: /reconcile apply")
+
+	case "apply":
+		var drifts []models.LedgerDrift
+		err := Store.WithTx(ctx, func(tx db.Store) error {
+			var err error
+			if drifts, err = tx.LedgerDrifts(ctx); err != nil {
+				return err
+			}
+			for _, d := range drifts {
+				err := tx.CorrectBalance(ctx, &models.LedgerEntry{
+					ProfileID: d.ProfileID, Currency: d.Currency, Delta: d.Expected - d.Recorded,
+					Reason:     fmt.Sprintf("Сверка: %s, исправил администратор %d", driftSource(d), adminID),
+					SourceType: d.SourceType, SourceID: d.SourceID,
+				})
+				if err != nil {
+					return fmt.Errorf("ошибка исправления анкеты %d: %w", d.ProfileID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			SendMessage(bot, chatID, "Ошибка исправления: "+err.Error())
+			return
+		}
+		if len(drifts) == 0 {
+			SendMessage(bot, chatID, "Журнал операций совпадает с источниками, исправлять нечего.")
+			return
+		}
+		log.Printf("Администратор %d исправил %d расхождений журнала", adminID, len(drifts))
+		SendMessage(bot, chatID, formatDrifts(ctx, drifts)+"\nИсправления записаны в журнал, балансы изменены.")
+
+	default:
+		SendMessage(bot, chatID, "Используйте: /reconcile – показать расхождения, /reconcile apply – исправить их")
+	}
+}
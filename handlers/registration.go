@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunRegistrationJanitor раз в минуту удаляет черновики регистрации, к которым
+// пользователь не возвращался дольше ttl, и за remindBefore до этого отправляет
+// напоминание. Работает, пока не отменён ctx.
+func RunRegistrationJanitor(ctx context.Context, bot Sender, ttl, remindBefore time.Duration) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		sweepRegistrationStates(ctx, bot, ttl, remindBefore)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepRegistrationStates выполняет один проход очистки черновиков.
+func sweepRegistrationStates(ctx context.Context, bot Sender, ttl, remindBefore time.Duration) {
+	now := time.Now()
+	expired, remind, err := Store.ExpireRegistrationStates(ctx, now.Add(-ttl), now.Add(-(ttl - remindBefore)))
+	if err != nil {
+		log.Printf("Ошибка очистки черновиков регистрации: %v", err)
+		return
+	}
+	for _, s := range remind {
+		SendMessage(bot, s.ChatID, "Вы не закончили регистрацию анкеты. Просто ответьте на последний вопрос, "+
+			"чтобы продолжить, или отправьте /cancel, чтобы отменить. "+
+			"Через "+remindBefore.Round(time.Minute).String()+" черновик будет удалён.")
+	}
+	for _, s := range expired {
+		SendMessage(bot, s.ChatID, "Черновик анкеты удалён из-за отсутствия ответа. Начать заново: /start")
+	}
+}
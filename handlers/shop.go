@@ -0,0 +1,666 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+	"telegram-bot/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	shopPageSize        = 5               // сколько товаров показывается на одной странице /shop
+	shopOrderConfirmTTL = 5 * time.Minute // сколько действует подтверждение покупки
+)
+
+// Ошибки покупки.
+var (
+	errItemNotFound       = errors.New("товар не найден")
+	errOutOfStock         = errors.New("товар закончился")
+	errItemRestricted     = errors.New("товар недоступен вашему персонажу")
+	errPriceChanged       = errors.New("цена товара изменилась")
+	errShopOrderExpired   = errors.New("подтверждение устарело")
+	errShopOrderProcessed = errors.New("покупка уже обработана")
+)
+
+// currencyLabels возвращает названия валют с иконками по кодам, включая выведенные из оборота.
+func currencyLabels(ctx context.Context) map[string]string {
+	labels := make(map[string]string)
+	currencies, err := Store.ListCurrencies(ctx, true)
+	if err != nil {
+		log.Printf("Ошибка получения валют: %v", err)
+		return labels
+	}
+	for _, c := range currencies {
+		labels[c.Code] = c.Label()
+	}
+	return labels
+}
+
+// formatPrices – цена товара, например "10 🪙 Пиастры + 2 💠 Обломки".
+func formatPrices(labels map[string]string, prices []models.Price) string {
+	if len(prices) == 0 {
+		return "бесплатно"
+	}
+	parts := make([]string, 0, len(prices))
+	for _, p := range prices {
+		label, ok := labels[p.Currency]
+		if !ok {
+			label = p.Currency
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", p.Amount, label))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// formatRestriction – ограничения товара по рангу, команде и расе или "".
+func formatRestriction(item *models.ShopItem) string {
+	var parts []string
+	if item.Rank != "" {
+		parts = append(parts, "ранг "+item.Rank)
+	}
+	if item.Team != "" {
+		parts = append(parts, "команда "+item.Team)
+	}
+	if item.Race != "" {
+		parts = append(parts, "раса "+item.Race)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatShopItem – карточка товара.
+func formatShopItem(labels map[string]string, item *models.ShopItem) string {
+	text := fmt.Sprintf("%s (ID %d)\nЦена: %s", item.Name, item.ID, formatPrices(labels, item.Prices))
+	if item.Description != "" {
+		text += "\n" + item.Description
+	}
+	switch {
+	case item.Stock == 0:
+		text += "\nНет в наличии"
+	case item.Stock > 0:
+		text += fmt.Sprintf("\nОсталось: %d", item.Stock)
+	}
+	if r := formatRestriction(item); r != "" {
+		text += "\nТолько для: " + r
+	}
+	return text
+}
+
+// checkRestriction проверяет, может ли персонаж купить товар.
+func checkRestriction(p *models.Profile, item *models.ShopItem) error {
+	if (item.Rank != "" && !strings.EqualFold(item.Rank, p.Rank)) ||
+		(item.Team != "" && !strings.EqualFold(item.Team, p.Team)) ||
+		(item.Race != "" && !strings.EqualFold(item.Race, p.Race)) {
+		return fmt.Errorf("%w (только для: %s)", errItemRestricted, formatRestriction(item))
+	}
+	return nil
+}
+
+// shopPage формирует страницу каталога: текст и клавиатуру с товарами и навигацией.
+func shopPage(ctx context.Context, page int) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	items, err := Store.ListShopItems(ctx)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+	if len(items) == 0 {
+		return "Магазин пуст.", tgbotapi.NewInlineKeyboardMarkup(), nil
+	}
+	pages := (len(items) + shopPageSize - 1) / shopPageSize
+	page = max(0, min(page, pages-1))
+	items = items[page*shopPageSize : min(len(items), (page+1)*shopPageSize)]
+
+	labels := currencyLabels(ctx)
+	text := fmt.Sprintf("Магазин (страница %d из %d). Выберите товар или купите: /buy <ID>", page+1, pages)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, item := range items {
+		title := fmt.Sprintf("%s – %s", item.Name, formatPrices(labels, item.Prices))
+		if item.Stock == 0 {
+			title += " (нет в наличии)"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(title, fmt.Sprintf("shop:item:%d:%d", item.ID, page))))
+	}
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("« Назад", fmt.Sprintf("shop:page:%d", page-1)))
+	}
+	if page < pages-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Вперёд »", fmt.Sprintf("shop:page:%d", page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...), nil
+}
+
+// HandleShop обрабатывает команду /shop – первая страница каталога.
+func HandleShop(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	text, markup, err := shopPage(ctx, 0)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения товаров: "+err.Error())
+		return
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	if len(markup.InlineKeyboard) > 0 {
+		reply.ReplyMarkup = markup
+	}
+	bot.Send(reply)
+}
+
+// HandleBuy обрабатывает команду /buy <ID товара> – предлагает подтвердить покупку.
+func HandleBuy(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	id, err := strconv.Atoi(strings.TrimSpace(msg.CommandArguments()))
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Используйте: /buy <ID товара>. Список товаров: /shop")
+		return
+	}
+	text, markup := offerPurchase(ctx, msg.From.ID, id)
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	if markup != nil {
+		reply.ReplyMarkup = *markup
+	}
+	bot.Send(reply)
+}
+
+// handleShopCallback обрабатывает кнопки каталога: "page:<n>", "item:<id>:<n>",
+// "buy:<id>" и кнопки подтверждения покупки "confirm:<заказ>" и "cancel:<заказ>".
+func handleShopCallback(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, data string) {
+	action, args, _ := strings.Cut(data, ":")
+	switch action {
+	case "page":
+		page, _ := strconv.Atoi(args)
+		text, markup, err := shopPage(ctx, page)
+		if err != nil {
+			answerCallback(bot, cq, "Ошибка получения товаров.")
+			return
+		}
+		answerCallback(bot, cq, "")
+		if cq.Message != nil {
+			bot.Send(tgbotapi.NewEditMessageTextAndMarkup(cq.Message.Chat.ID, cq.Message.MessageID, text, markup))
+		}
+	case "item":
+		idStr, pageStr, _ := strings.Cut(args, ":")
+		id, _ := strconv.Atoi(idStr)
+		item, err := Store.GetShopItem(ctx, id)
+		if err != nil {
+			answerCallback(bot, cq, "Товар не найден.")
+			return
+		}
+		markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Купить", fmt.Sprintf("shop:buy:%d", item.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("« К списку", "shop:page:"+pageStr),
+		))
+		answerCallback(bot, cq, "")
+		if cq.Message != nil {
+			bot.Send(tgbotapi.NewEditMessageTextAndMarkup(cq.Message.Chat.ID, cq.Message.MessageID,
+				formatShopItem(currencyLabels(ctx), item), markup))
+		}
+	case "buy":
+		id, _ := strconv.Atoi(args)
+		text, markup := offerPurchase(ctx, cq.From.ID, id)
+		if markup == nil || cq.Message == nil {
+			answerCallback(bot, cq, text)
+			return
+		}
+		answerCallback(bot, cq, "")
+		bot.Send(tgbotapi.NewEditMessageTextAndMarkup(cq.Message.Chat.ID, cq.Message.MessageID, text, *markup))
+	case "confirm", "cancel":
+		id, err := strconv.Atoi(args)
+		if err != nil {
+			answerCallback(bot, cq, "Неверная кнопка.")
+			return
+		}
+		if action == "cancel" {
+			cancelPurchase(ctx, bot, cq, id)
+			return
+		}
+		confirmPurchase(ctx, bot, cq, id)
+	default:
+		answerCallback(bot, cq, "Кнопка больше не действует.")
+	}
+}
+
+// priceKey – цена товара в виде "валюта:сумма,..." для сравнения показанной
+// цены с текущей; порядок валют не важен.
+func priceKey(prices []models.Price) string {
+	parts := make([]string, 0, len(prices))
+	for _, p := range prices {
+		parts = append(parts, fmt.Sprintf("%s:%d", p.Currency, p.Amount))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// checkPurchase проверяет, может ли персонаж купить товар: наличие, ограничения,
+// долг и баланс по каждой валюте цены.
+func checkPurchase(ctx context.Context, store db.Store, profile *models.Profile, item *models.ShopItem) error {
+	if item.Stock == 0 {
+		return errOutOfStock
+	}
+	if err := checkRestriction(profile, item); err != nil {
+		return err
+	}
+	if err := checkNoDebt(profile); err != nil {
+		return err
+	}
+	for _, p := range item.Prices {
+		if have := profile.Balance(p.Currency); have < p.Amount {
+			label := p.Currency
+			if c, err := store.GetCurrency(ctx, p.Currency); err == nil {
+				label = c.Label()
+			}
+			return fmt.Errorf("%w: нужно %d %s, на балансе %d", errInsufficientFunds, p.Amount, label, have)
+		}
+	}
+	return nil
+}
+
+// purchaseErrorText – ответ покупателю на ошибку покупки.
+func purchaseErrorText(err error) string {
+	switch {
+	case errors.Is(err, errProfileNotFound):
+		return "Профиль не найден. Используйте /createprofile для создания анкеты."
+	case errors.Is(err, errItemNotFound), errors.Is(err, errOutOfStock), errors.Is(err, errItemRestricted),
+		errors.Is(err, errInsufficientFunds), errors.Is(err, errInDebt), errors.Is(err, errPriceChanged),
+		errors.Is(err, errShopOrderExpired):
+		return "Покупка невозможна: " + err.Error()
+	default:
+		return "Ошибка покупки, попробуйте позже."
+	}
+}
+
+// offerPurchase проверяет, можно ли купить товар, и создаёт заказ, который нужно
+// подтвердить кнопкой. Возвращает текст и кнопки подтверждения или только текст ошибки.
+func offerPurchase(ctx context.Context, telegramID int64, itemID int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	profile, err := Store.GetProfile(ctx, telegramID)
+	if err != nil {
+		return purchaseErrorText(errProfileNotFound), nil
+	}
+	item, err := Store.GetShopItem(ctx, itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return purchaseErrorText(errItemNotFound), nil
+	} else if err != nil {
+		log.Printf("Ошибка получения товара %d: %v", itemID, err)
+		return purchaseErrorText(err), nil
+	}
+	// Предварительная проверка; окончательная выполняется в транзакции при подтверждении.
+	if err := checkPurchase(ctx, Store, profile, item); err != nil {
+		return purchaseErrorText(err), nil
+	}
+	order := &models.ShopOrder{
+		ProfileID: profile.ID, ShopItemID: item.ID, Price: priceKey(item.Prices), Status: models.ShopOrderPending,
+	}
+	if err := Store.CreateShopOrder(ctx, order); err != nil {
+		log.Printf("Ошибка создания заказа товара %d: %v", item.ID, err)
+		return purchaseErrorText(err), nil
+	}
+	text := fmt.Sprintf("Купить «%s» за %s?\nПодтверждение действует %d мин.",
+		item.Name, formatPrices(currencyLabels(ctx), item.Prices), int(shopOrderConfirmTTL.Minutes()))
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Подтвердить", fmt.Sprintf("shop:confirm:%d", order.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("Отменить", fmt.Sprintf("shop:cancel:%d", order.ID)),
+	))
+	return text, &markup
+}
+
+// cancelPurchase отменяет неподтверждённый заказ.
+func cancelPurchase(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, orderID int) {
+	order, err := Store.GetShopOrder(ctx, orderID)
+	if err != nil {
+		answerCallback(bot, cq, "Покупка не найдена.")
+		return
+	}
+	profile, err := Store.GetProfile(ctx, cq.From.ID)
+	if err != nil || profile.ID != order.ProfileID {
+		answerCallback(bot, cq, "Это не ваша покупка.")
+		return
+	}
+	if order.Status != models.ShopOrderPending {
+		answerCallback(bot, cq, "Покупка уже обработана.")
+		return
+	}
+	order.Status = models.ShopOrderCancelled
+	if err := Store.UpdateShopOrder(ctx, order); err != nil {
+		answerCallback(bot, cq, "Ошибка отмены покупки.")
+		return
+	}
+	answerCallback(bot, cq, "")
+	editCallbackMessage(bot, cq, "Покупка отменена.")
+}
+
+// confirmPurchase выполняет подтверждённый заказ. Заказ выполняется один раз:
+// повторное нажатие кнопки ничего не списывает. Если цена с тех пор изменилась,
+// товар закончился или подтверждение устарело, заказ отменяется.
+func confirmPurchase(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, orderID int) {
+	order, err := Store.GetShopOrder(ctx, orderID)
+	if err != nil {
+		answerCallback(bot, cq, "Покупка не найдена.")
+		return
+	}
+	profile, err := Store.GetProfile(ctx, cq.From.ID)
+	if err != nil || profile.ID != order.ProfileID {
+		answerCallback(bot, cq, "Это не ваша покупка.")
+		return
+	}
+
+	item, profile, err := buyItem(ctx, order)
+	switch {
+	case errors.Is(err, errShopOrderProcessed):
+		answerCallback(bot, cq, "Покупка уже обработана.")
+		return
+	case errors.Is(err, errProfileNotFound), errors.Is(err, errItemNotFound), errors.Is(err, errOutOfStock),
+		errors.Is(err, errItemRestricted), errors.Is(err, errInsufficientFunds), errors.Is(err, errInDebt),
+		errors.Is(err, errPriceChanged), errors.Is(err, errShopOrderExpired):
+		order.Status = models.ShopOrderCancelled
+		if err := Store.UpdateShopOrder(ctx, order); err != nil {
+			log.Printf("Ошибка отмены заказа %d: %v", order.ID, err)
+		}
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, purchaseErrorText(err)+". Список товаров: /shop")
+		return
+	case err != nil:
+		log.Printf("Ошибка покупки по заказу %d: %v", order.ID, err)
+		answerCallback(bot, cq, purchaseErrorText(err))
+		return
+	}
+	answerCallback(bot, cq, "Покупка выполнена.")
+	editCallbackMessage(bot, cq, fmt.Sprintf("Вы купили «%s». Предмет добавлен в инвентарь.\nИнвентарь:\n%s",
+		item.Name, utils.FormatInventory(profile.Items, false)))
+}
+
+// buyItem выполняет заказ: проверяет, что он ещё ждёт подтверждения и цена не
+// изменилась, списывает цену товара, уменьшает запас, добавляет товар в инвентарь
+// персонажа и отмечает заказ выполненным – всё в одной транзакции.
+func buyItem(ctx context.Context, order *models.ShopOrder) (*models.ShopItem, *models.Profile, error) {
+	var item *models.ShopItem
+	var profile *models.Profile
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		o, err := tx.GetShopOrder(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		if o.Status != models.ShopOrderPending {
+			return errShopOrderProcessed
+		}
+		if time.Since(o.CreatedAt) > shopOrderConfirmTTL {
+			return errShopOrderExpired
+		}
+		item, err = tx.GetShopItem(ctx, o.ShopItemID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errItemNotFound
+		} else if err != nil {
+			return err
+		}
+		if priceKey(item.Prices) != o.Price {
+			return errPriceChanged
+		}
+		profile, err = tx.GetProfileByID(ctx, o.ProfileID)
+		if err != nil {
+			return errProfileNotFound
+		}
+		if err := checkPurchase(ctx, tx, profile, item); err != nil {
+			return err
+		}
+
+		for _, p := range item.Prices {
+			err := tx.ChangeBalance(ctx, &models.LedgerEntry{
+				ProfileID:  profile.ID,
+				Currency:   p.Currency,
+				Delta:      -p.Amount,
+				Reason:     fmt.Sprintf("Покупка «%s»", item.Name),
+				SourceType: models.LedgerSourceShop,
+				SourceID:   int64(item.ID),
+			})
+			if err != nil {
+				return fmt.Errorf("ошибка списания валюты: %w", err)
+			}
+		}
+		if item.Stock > 0 {
+			item.Stock--
+			if err := tx.UpdateShopItem(ctx, item); err != nil {
+				return err
+			}
+		}
+		if item.ItemID == 0 {
+			catalog, err := catalogItem(ctx, tx, item.Name, item.Description)
+			if err != nil {
+				return err
+			}
+			item.ItemID = catalog.ID
+			if err := tx.UpdateShopItem(ctx, item); err != nil {
+				return err
+			}
+		}
+		if err := tx.AddInventoryItem(ctx, profile.ID, item.ItemID, 1, ""); err != nil {
+			return err
+		}
+		o.Status = models.ShopOrderCompleted
+		o.CompletedAt = time.Now()
+		if err := tx.UpdateShopOrder(ctx, o); err != nil {
+			return err
+		}
+		*order = *o
+		profile, err = tx.GetProfileByID(ctx, o.ProfileID)
+		return err
+	})
+	return item, profile, err
+}
+
+// parsePrices разбирает цену вида "10 piastres, 2 обломки".
+func parsePrices(ctx context.Context, s string) ([]models.Price, error) {
+	var prices []models.Price
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("неверная цена %q: ожидается <сумма> <валюта>", strings.TrimSpace(part))
+		}
+		amount, err := strconv.Atoi(fields[0])
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("неверная сумма %q", fields[0])
+		}
+		c, err := resolveCurrency(ctx, Store, fields[1], true)
+		if err != nil {
+			return nil, errors.New(currencyErrorText(ctx, err))
+		}
+		if seen[c.Code] {
+			return nil, fmt.Errorf("валюта %s указана дважды", c.Code)
+		}
+		seen[c.Code] = true
+		prices = append(prices, models.Price{Currency: c.Code, Amount: amount})
+	}
+	if len(prices) == 0 {
+		return nil, errors.New("не указана цена")
+	}
+	return prices, nil
+}
+
+// parseStock разбирает запас товара; пустое значение или "-" – без ограничения.
+func parseStock(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return models.ShopStockUnlimited, nil
+	}
+	stock, err := strconv.Atoi(s)
+	if err != nil || stock < 0 {
+		return 0, fmt.Errorf("неверный запас %q: ожидается число или -", s)
+	}
+	return stock, nil
+}
+
+// parseRestriction разбирает ограничения вида "rank=капитан, team=альфа, race=эльф"
+// и записывает их в товар. Пустое значение или "-" снимает ограничения.
+func parseRestriction(s string, item *models.ShopItem) error {
+	item.Rank, item.Team, item.Race = "", "", ""
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return fmt.Errorf("неверное ограничение %q: ожидается поле=значение", strings.TrimSpace(part))
+		}
+		switch key {
+		case "rank":
+			item.Rank = value
+		case "team":
+			item.Team = value
+		case "race":
+			item.Race = value
+		default:
+			return fmt.Errorf("неизвестное поле ограничения %q: доступны rank, team, race", key)
+		}
+	}
+	return nil
+}
+
+// handleAdminItems обрабатывает команду админского бота /items – каталог магазина.
+func handleAdminItems(ctx context.Context, bot Sender, chatID int64) {
+	items, err := Store.ListShopItems(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения товаров: "+err.Error())
+		return
+	}
+	if len(items) == 0 {
+		SendMessage(bot, chatID, "Товаров нет. Добавьте: /additem <название>|<описание>|<цена>|<запас>|<ограничения>")
+		return
+	}
+	labels := currencyLabels(ctx)
+	texts := make([]string, 0, len(items))
+	for _, item := range items {
+		texts = append(texts, formatShopItem(labels, item))
+	}
+	SendMessage(bot, chatID, strings.Join(texts, "\n\n"))
+}
+
+// handleAdminAddItem обрабатывает команду /additem <название>|<описание>|<цена>|<запас>|<ограничения>.
+// Запас и ограничения необязательны.
+func handleAdminAddItem(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Split(args, "|")
+	if len(parts) < 3 || len(parts) > 5 {
+		SendMessage(bot, chatID, "Используйте: /additem <название>|<описание>|<цена>|<запас>|<ограничения>\n"+
+			"Например: /additem Меч|Острый|10 piastres, 2 oblomki|5|rank=капитан")
+		return
+	}
+	item := &models.ShopItem{
+		Name:        strings.TrimSpace(parts[0]),
+		Description: strings.TrimSpace(parts[1]),
+		Stock:       models.ShopStockUnlimited,
+	}
+	if item.Name == "" {
+		SendMessage(bot, chatID, "Название товара не может быть пустым.")
+		return
+	}
+	var err error
+	if item.Prices, err = parsePrices(ctx, parts[2]); err != nil {
+		SendMessage(bot, chatID, err.Error())
+		return
+	}
+	if len(parts) > 3 {
+		if item.Stock, err = parseStock(parts[3]); err != nil {
+			SendMessage(bot, chatID, err.Error())
+			return
+		}
+	}
+	if len(parts) > 4 {
+		if err := parseRestriction(parts[4], item); err != nil {
+			SendMessage(bot, chatID, err.Error())
+			return
+		}
+	}
+	catalog, err := catalogItem(ctx, Store, item.Name, item.Description)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка добавления предмета в справочник: "+err.Error())
+		return
+	}
+	item.ItemID = catalog.ID
+	if err := Store.CreateShopItem(ctx, item); err != nil {
+		SendMessage(bot, chatID, "Ошибка добавления товара: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, "Товар добавлен:\n"+formatShopItem(currencyLabels(ctx), item))
+}
+
+// handleAdminEditItem обрабатывает команду /edititem <ID> <поле> <значение>.
+func handleAdminEditItem(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Fields(args)
+	if len(parts) < 2 {
+		SendMessage(bot, chatID, "Используйте: /edititem <ID> <поле> <значение>\n"+
+			"Поля: name, description, price, stock, restriction")
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		SendMessage(bot, chatID, "Неверный ID товара.")
+		return
+	}
+	field := strings.ToLower(parts[1])
+	value := strings.Join(parts[2:], " ")
+
+	item, err := Store.GetShopItem(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Товар не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения товара: "+err.Error())
+		return
+	}
+
+	switch field {
+	case "name":
+		if value == "" {
+			SendMessage(bot, chatID, "Название товара не может быть пустым.")
+			return
+		}
+		item.Name = value
+	case "description":
+		item.Description = value
+	case "price":
+		item.Prices, err = parsePrices(ctx, value)
+	case "stock":
+		item.Stock, err = parseStock(value)
+	case "restriction":
+		err = parseRestriction(value, item)
+	default:
+		SendMessage(bot, chatID, "Неизвестное поле. Доступны: name, description, price, stock, restriction")
+		return
+	}
+	if err != nil {
+		SendMessage(bot, chatID, err.Error())
+		return
+	}
+	if err := Store.UpdateShopItem(ctx, item); err != nil {
+		SendMessage(bot, chatID, "Ошибка обновления товара: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, "Товар обновлён:\n"+formatShopItem(currencyLabels(ctx), item))
+}
+
+// handleAdminRemoveItem обрабатывает команду /removeitem <ID>.
+func handleAdminRemoveItem(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /removeitem <ID>")
+		return
+	}
+	if err := Store.DeleteShopItem(ctx, id); err != nil {
+		SendMessage(bot, chatID, "Ошибка удаления товара: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Товар %d удалён из магазина.", id))
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"telegram-bot/config"
+	"telegram-bot/db"
+	"telegram-bot/models"
+	"telegram-bot/scheduler"
+)
+
+// jobStipendPay – задача планировщика, выплачивающая стипендию; RefID – ID стипендии.
+const jobStipendPay = "stipend.pay"
+
+// stipendTarget – кому выплачивается стипендия, для сообщений.
+func stipendTarget(st *models.Stipend) string {
+	var parts []string
+	if st.Rank != "" {
+		parts = append(parts, "ранг "+st.Rank)
+	}
+	if st.Team != "" {
+		parts = append(parts, "команда "+st.Team)
+	}
+	if len(parts) == 0 {
+		return "все персонажи"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stipendMatches проверяет, получает ли персонаж стипендию.
+func stipendMatches(st *models.Stipend, p *models.Profile) bool {
+	return (st.Rank == "" || strings.EqualFold(st.Rank, p.Rank)) &&
+		(st.Team == "" || strings.EqualFold(st.Team, p.Team))
+}
+
+// parseStipendTarget разбирает получателей вида "rank=капитан, team=альфа".
+// "-" означает всех персонажей.
+func parseStipendTarget(s string, st *models.Stipend) error {
+	s = strings.TrimSpace(s)
+	if s == "-" {
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return fmt.Errorf("неверные получатели %q: ожидается rank=<ранг> и/или team=<команда>", strings.TrimSpace(part))
+		}
+		switch key {
+		case "rank":
+			st.Rank = value
+		case "team":
+			st.Team = value
+		default:
+			return fmt.Errorf("неизвестное поле %q: доступны rank и team", key)
+		}
+	}
+	return nil
+}
+
+// formatStipend – строка со стипендией для списка администратора.
+func formatStipend(labels map[string]string, st *models.Stipend) string {
+	label, ok := labels[st.Currency]
+	if !ok {
+		label = st.Currency
+	}
+	text := fmt.Sprintf("#%d %s: %d %s, %s", st.ID, stipendTarget(st), st.Amount, label, st.Schedule)
+	if st.Paused {
+		return text + " [приостановлена]"
+	}
+	return text + ", следующая выплата " + st.NextRunAt.In(config.Current.Location()).Format("02.01.2006 15:04")
+}
+
+// payStipendJob – задача планировщика: выплачивает стипендию. Если не удалась
+// и последняя попытка, выплата пропускается: администраторы получают уведомление,
+// а стипендия переносится на следующий раз по расписанию.
+func payStipendJob(ctx context.Context, job *models.Job) error {
+	err := payStipend(ctx, job)
+	if err != nil && scheduler.LastAttempt(job) {
+		skipStipendRun(ctx, int(job.RefID), err)
+	}
+	return err
+}
+
+// skipStipendRun переносит стипендию, выплатить которую не удалось, на следующий
+// раз по расписанию и сообщает об этом администраторам.
+func skipStipendRun(ctx context.Context, stipendID int, cause error) {
+	var st *models.Stipend
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		st, err = tx.GetStipend(ctx, stipendID)
+		if errors.Is(err, sql.ErrNoRows) {
+			st = nil
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if st.Paused {
+			st = nil
+			return nil
+		}
+		rec, err := scheduler.ParseRecurrence(st.Schedule)
+		if err != nil {
+			return err
+		}
+		st.NextRunAt = rec.Next(gameNow())
+		if err := tx.UpdateStipend(ctx, st); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobStipendPay, int64(st.ID), st.NextRunAt)
+	})
+	if err != nil {
+		notifyAdmins(fmt.Sprintf("Стипендия #%d не выплачена: %v.\nПеренести её на следующий раз не удалось: %v. "+
+			"Проверьте стипендию в /stipends.", stipendID, cause, err))
+		return
+	}
+	if st == nil {
+		return
+	}
+	notifyAdmins(fmt.Sprintf("Стипендия #%d (%s) не выплачена: %v.\nЭта выплата пропущена, следующая %s.",
+		st.ID, stipendTarget(st), cause, st.NextRunAt.In(config.Current.Location()).Format("02.01.2006 15:04")))
+}
+
+// payStipend начисляет стипендию всем подходящим персонажам и планирует
+// следующую выплату. Выплата и перенос задачи выполняются в одной транзакции,
+// поэтому повтор после ошибки не начислит стипендию дважды.
+func payStipend(ctx context.Context, job *models.Job) error {
+	var st *models.Stipend
+	var currency *models.Currency
+	var paid []*models.Profile
+	var credits []*models.LedgerEntry
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		st, err = tx.GetStipend(ctx, int(job.RefID))
+		if errors.Is(err, sql.ErrNoRows) {
+			st = nil
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if st.Paused {
+			st = nil
+			return nil
+		}
+		rec, err := scheduler.ParseRecurrence(st.Schedule)
+		if err != nil {
+			return err
+		}
+		if currency, err = tx.GetCurrency(ctx, st.Currency); err != nil {
+			return err
+		}
+
+		if !currency.Retired {
+			profiles, err := tx.GetAllProfiles(ctx)
+			if err != nil {
+				return err
+			}
+			for _, p := range profiles {
+				if !stipendMatches(st, p) {
+					continue
+				}
+				credit := &models.LedgerEntry{
+					ProfileID: p.ID, Currency: st.Currency, Delta: st.Amount,
+					Reason:     fmt.Sprintf("Стипендия #%d (%s)", st.ID, stipendTarget(st)),
+					SourceType: models.LedgerSourceStipend, SourceID: int64(st.ID),
+				}
+				if err := tx.ChangeBalance(ctx, credit); err != nil {
+					return fmt.Errorf("ошибка начисления анкете %d: %w", p.ID, err)
+				}
+				paid = append(paid, p)
+				credits = append(credits, credit)
+			}
+		}
+
+		now := gameNow()
+		st.LastRunAt = now
+		st.NextRunAt = rec.Next(now)
+		if err := tx.UpdateStipend(ctx, st); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobStipendPay, int64(st.ID), st.NextRunAt)
+	})
+	if err != nil || st == nil {
+		return err
+	}
+
+	label := currency.Label()
+	next := st.NextRunAt.In(config.Current.Location()).Format("02.01.2006 15:04")
+	if currency.Retired {
+		notifyAdmins(fmt.Sprintf("Стипендия #%d (%s) не выплачена: валюта %s выведена из оборота. Следующая попытка %s.",
+			st.ID, stipendTarget(st), label, next))
+		return nil
+	}
+	for i, p := range paid {
+		notifyProfile(ctx, p.ID, fmt.Sprintf("Вам начислена стипендия: %d %s (%s).%s",
+			st.Amount, label, stipendTarget(st), debtRepaymentText(label, credits[i])))
+	}
+	if len(paid) == 0 {
+		notifyAdmins(fmt.Sprintf("Стипендия #%d (%s): подходящих персонажей нет. Следующая выплата %s.",
+			st.ID, stipendTarget(st), next))
+		return nil
+	}
+	names := make([]string, len(paid))
+	for i, p := range paid {
+		names[i] = fmt.Sprintf("%s (ID %d)", p.Name, p.ID)
+	}
+	notifyAdmins(fmt.Sprintf("Стипендия #%d (%s) выплачена %d персонажам по %d %s, всего %d: %s.\nСледующая выплата %s.",
+		st.ID, stipendTarget(st), len(paid), st.Amount, label, len(paid)*st.Amount, strings.Join(names, ", "), next))
+	return nil
+}
+
+// handleAdminStipends обрабатывает команду админского бота /stipends – список стипендий.
+func handleAdminStipends(ctx context.Context, bot Sender, chatID int64) {
+	stipends, err := Store.ListStipends(ctx)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения стипендий: "+err.Error())
+		return
+	}
+	if len(stipends) == 0 {
+		SendMessage(bot, chatID, "Стипендий нет. Добавить: /addstipend <получатели>|<сумма> <валюта>|<расписание>")
+		return
+	}
+	labels := currencyLabels(ctx)
+	var b strings.Builder
+	b.WriteString("Стипендии:\n")
+	for _, st := range stipends {
+		b.WriteString(formatStipend(labels, st) + "\n")
+	}
+	b.WriteString("Приостановить: /pausestipend <номер>, удалить: /removestipend <номер>")
+	SendMessage(bot, chatID, b.String())
+}
+
+// handleAdminAddStipend обрабатывает команду
+// /addstipend <получатели>|<сумма> <валюта>|<расписание>, например
+// /addstipend rank=Капитан|50 piastres|weekly mon 09:00.
+func handleAdminAddStipend(ctx context.Context, bot Sender, chatID int64, args string) {
+	const usage = "Используйте: /addstipend <получатели>|<сумма> <валюта>|<расписание>\n" +
+		"Получатели: rank=<ранг>, team=<команда> или - (все персонажи).\n" +
+		"Расписание: daily 09:00, weekly mon,thu 18:00 или monthly 1 12:00."
+	parts := strings.Split(args, "|")
+	if len(parts) != 3 {
+		SendMessage(bot, chatID, usage)
+		return
+	}
+	st := &models.Stipend{}
+	if err := parseStipendTarget(parts[0], st); err != nil {
+		SendMessage(bot, chatID, err.Error()+"\n"+usage)
+		return
+	}
+	prices, err := parsePrices(ctx, parts[1])
+	if err != nil {
+		SendMessage(bot, chatID, err.Error()+"\n"+usage)
+		return
+	}
+	if len(prices) != 1 {
+		SendMessage(bot, chatID, "Стипендия выплачивается в одной валюте.\n"+usage)
+		return
+	}
+	st.Currency, st.Amount = prices[0].Currency, prices[0].Amount
+	rec, err := scheduler.ParseRecurrence(parts[2])
+	if err != nil {
+		SendMessage(bot, chatID, err.Error()+"\n"+usage)
+		return
+	}
+	st.Schedule = rec.String()
+	st.NextRunAt = rec.Next(gameNow())
+
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		if err := tx.CreateStipend(ctx, st); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobStipendPay, int64(st.ID), st.NextRunAt)
+	})
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка добавления стипендии: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, "Стипендия добавлена: "+formatStipend(currencyLabels(ctx), st))
+}
+
+// handleAdminRemoveStipend обрабатывает команду /removestipend <номер>.
+func handleAdminRemoveStipend(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /removestipend <номер стипендии>")
+		return
+	}
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		if err := tx.DeleteStipend(ctx, id); err != nil {
+			return err
+		}
+		return tx.CancelJob(ctx, jobStipendPay, int64(id))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Стипендия не найдена.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка удаления стипендии: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Стипендия #%d удалена.", id))
+}
+
+// handleAdminPauseStipend обрабатывает команды /pausestipend и /resumestipend <номер>.
+// После возобновления следующая выплата считается от текущего момента,
+// пропущенные за время паузы выплаты не начисляются.
+func handleAdminPauseStipend(ctx context.Context, bot Sender, chatID int64, args string, paused bool) {
+	cmd := "/resumestipend"
+	if paused {
+		cmd = "/pausestipend"
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: "+cmd+" <номер стипендии>")
+		return
+	}
+	var st *models.Stipend
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		var err error
+		if st, err = tx.GetStipend(ctx, id); err != nil {
+			return err
+		}
+		st.Paused = paused
+		if paused {
+			if err := tx.UpdateStipend(ctx, st); err != nil {
+				return err
+			}
+			return tx.CancelJob(ctx, jobStipendPay, int64(st.ID))
+		}
+		rec, err := scheduler.ParseRecurrence(st.Schedule)
+		if err != nil {
+			return err
+		}
+		st.NextRunAt = rec.Next(gameNow())
+		if err := tx.UpdateStipend(ctx, st); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobStipendPay, int64(st.ID), st.NextRunAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Стипендия не найдена.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка изменения стипендии: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, formatStipend(currencyLabels(ctx), st))
+}
@@ -0,0 +1,678 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// tradeTTL – через сколько времени без изменений открытый обмен отменяется.
+const tradeTTL = 15 * time.Minute
+
+// jobTradeExpire – задача планировщика, отменяющая обмен без изменений дольше tradeTTL;
+// RefID – ID обмена.
+const jobTradeExpire = "trade.expire"
+
+// tradeListLimit – сколько последних обменов показывает /trades админского бота.
+const tradeListLimit = 20
+
+// Ошибки обмена.
+var (
+	errTradeClosed    = errors.New("обмен уже завершён или отменён")
+	errTradeStale     = errors.New("предложения изменились")
+	errTradeEmpty     = errors.New("в обмене нет предложений")
+	errTradeShortfall = errors.New("недостаточно")
+	errTradeExists    = errors.New("у участника уже идёт другой обмен")
+)
+
+// tradeSide возвращает, является ли профиль инициатором обмена, и участвует ли он в нём вообще.
+func tradeSide(t *models.Trade, profileID int) (initiator, ok bool) {
+	switch profileID {
+	case t.InitiatorID:
+		return true, true
+	case t.PartnerID:
+		return false, true
+	}
+	return false, false
+}
+
+// tradeParties загружает анкеты обоих участников обмена.
+func tradeParties(ctx context.Context, store db.Store, t *models.Trade) (initiator, partner *models.Profile, err error) {
+	if initiator, err = store.GetProfileByID(ctx, t.InitiatorID); err != nil {
+		return nil, nil, errProfileNotFound
+	}
+	if partner, err = store.GetProfileByID(ctx, t.PartnerID); err != nil {
+		return nil, nil, errProfileNotFound
+	}
+	return initiator, partner, nil
+}
+
+// profileName – имя персонажа для журналов; удалённая анкета показывается по ID.
+func profileName(ctx context.Context, id int) string {
+	p, err := Store.GetProfileByID(ctx, id)
+	if err != nil {
+		return fmt.Sprintf("анкета ID %d", id)
+	}
+	return p.Name
+}
+
+// formatTradeLines – что отдаёт участник profileID, по строке на предмет или валюту.
+func formatTradeLines(labels map[string]string, t *models.Trade, profileID int) string {
+	var b strings.Builder
+	for _, line := range t.Lines {
+		if line.ProfileID != profileID {
+			continue
+		}
+		if line.ItemID != 0 {
+			fmt.Fprintf(&b, "\n- %s ×%d", line.ItemName, line.Amount)
+			continue
+		}
+		label, ok := labels[line.Currency]
+		if !ok {
+			label = line.Currency
+		}
+		fmt.Fprintf(&b, "\n- %d %s", line.Amount, label)
+	}
+	if b.Len() == 0 {
+		return "\n- ничего"
+	}
+	return b.String()
+}
+
+// formatTrade – карточка обмена: предложения сторон и подтверждения.
+func formatTrade(ctx context.Context, t *models.Trade, initiatorName, partnerName string) string {
+	labels := currencyLabels(ctx)
+	mark := func(confirmed bool) string {
+		if confirmed {
+			return "✅"
+		}
+		return "⏳"
+	}
+	text := fmt.Sprintf("Обмен #%d: %s ⇄ %s\n%s отдаёт:%s\n%s отдаёт:%s",
+		t.ID, initiatorName, partnerName,
+		initiatorName, formatTradeLines(labels, t, t.InitiatorID),
+		partnerName, formatTradeLines(labels, t, t.PartnerID))
+	switch t.Status {
+	case models.TradeOpen:
+		text += fmt.Sprintf("\nПодтвердили: %s %s, %s %s",
+			initiatorName, mark(t.InitiatorConfirmed), partnerName, mark(t.PartnerConfirmed))
+	case models.TradeCompleted:
+		text += "\nОбмен выполнен " + eventTime(t.ClosedAt)
+	case models.TradeCancelled:
+		text += "\nОбмен отменён: " + t.CancelReason
+	}
+	return text
+}
+
+// sendTradeCard отправляет участникам открытого обмена его карточку с кнопками подтверждения.
+// Кнопки привязаны к ревизии: после изменения предложений старые кнопки не действуют.
+func sendTradeCard(ctx context.Context, bot Sender, t *models.Trade, header string, recipients ...*models.Profile) {
+	initiatorName, partnerName := profileName(ctx, t.InitiatorID), profileName(ctx, t.PartnerID)
+	text := formatTrade(ctx, t, initiatorName, partnerName)
+	if header != "" {
+		text = header + "\n\n" + text
+	}
+	text += "\n\nДобавить или изменить предложение: /offer [количество] <предмет или валюта>"
+	for _, p := range recipients {
+		reply := tgbotapi.NewMessage(p.TelegramID, text)
+		reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Подтвердить", fmt.Sprintf("trade:confirm:%d:%d", t.ID, t.Revision)),
+			tgbotapi.NewInlineKeyboardButtonData("Отменить", fmt.Sprintf("trade:cancel:%d", t.ID)),
+		))
+		bot.Send(reply)
+	}
+}
+
+// escrowTradeLine забирает у участника в залог delta штук предмета или единиц валюты
+// строки line; отрицательное delta возвращает залог. Вызывается внутри транзакции.
+func escrowTradeLine(ctx context.Context, tx db.Store, t *models.Trade, from *models.Profile, line models.TradeLine, delta int) error {
+	switch {
+	case delta > 0 && line.ItemID != 0:
+		removed, err := tx.RemoveInventoryItem(ctx, from.ID, line.ItemID, delta)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && removed < delta) {
+			return fmt.Errorf("%w: в инвентаре %d, а нужно ещё %d", errTradeShortfall, removed, delta)
+		}
+		return err
+	case delta < 0 && line.ItemID != 0:
+		return tx.AddInventoryItem(ctx, from.ID, line.ItemID, -delta, "")
+	case delta > 0:
+		if have := from.Balance(line.Currency); have < delta {
+			return fmt.Errorf("%w: на балансе %d, а нужно ещё %d", errTradeShortfall, have, delta)
+		}
+		return tx.ChangeBalance(ctx, &models.LedgerEntry{
+			ProfileID: from.ID, Currency: line.Currency, Delta: -delta,
+			Reason:     fmt.Sprintf("Залог в обмене #%d", t.ID),
+			SourceType: models.LedgerSourceTrade, SourceID: int64(t.ID),
+		})
+	case delta < 0:
+		return tx.ChangeBalance(ctx, &models.LedgerEntry{
+			ProfileID: from.ID, Currency: line.Currency, Delta: -delta,
+			Reason:     fmt.Sprintf("Возврат залога: обмен #%d", t.ID),
+			SourceType: models.LedgerSourceTrade, SourceID: int64(t.ID),
+		})
+	}
+	return nil
+}
+
+// cancelTrade отменяет открытый обмен, возвращает участникам залог и уведомляет их.
+// Если обмен уже закрыт, ничего не делает.
+func cancelTrade(ctx context.Context, bot Sender, t *models.Trade, reason string) {
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		cur, err := tx.GetTrade(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		if cur.Status != models.TradeOpen {
+			return errTradeClosed
+		}
+		for _, line := range cur.Lines {
+			// Залог удалённой анкеты пропадает вместе с её инвентарём.
+			from, err := tx.GetProfileByID(ctx, line.ProfileID)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if err := escrowTradeLine(ctx, tx, cur, from, line, -line.Amount); err != nil {
+				return err
+			}
+		}
+		cur.Status = models.TradeCancelled
+		cur.CancelReason = reason
+		cur.ClosedAt = time.Now()
+		if err := tx.UpdateTrade(ctx, cur); err != nil {
+			return err
+		}
+		*t = *cur
+		return tx.CancelJob(ctx, jobTradeExpire, int64(t.ID))
+	})
+	if errors.Is(err, errTradeClosed) {
+		return
+	} else if err != nil {
+		log.Printf("Ошибка отмены обмена %d: %v", t.ID, err)
+		return
+	}
+	text := fmt.Sprintf("Обмен #%d отменён: %s", t.ID, reason)
+	for _, id := range []int{t.InitiatorID, t.PartnerID} {
+		if p, err := Store.GetProfileByID(ctx, id); err == nil {
+			SendMessage(bot, p.TelegramID, text)
+		}
+	}
+}
+
+// openTrade возвращает открытый обмен профиля. Обмен, который не менялся дольше tradeTTL,
+// отменяется, и тогда возвращается sql.ErrNoRows.
+func openTrade(ctx context.Context, bot Sender, profileID int) (*models.Trade, error) {
+	t, err := Store.GetOpenTrade(ctx, profileID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(t.UpdatedAt) > tradeTTL {
+		cancelTrade(ctx, bot, t, "истекло время ожидания")
+		return nil, sql.ErrNoRows
+	}
+	return t, nil
+}
+
+// expireTradeJob – задача планировщика: отменяет обмен, который не менялся дольше tradeTTL.
+// Если обмен с тех пор изменился, задача переносится.
+func expireTradeJob(ctx context.Context, job *models.Job) error {
+	t, err := Store.GetTrade(ctx, int(job.RefID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if t.Status != models.TradeOpen {
+		return nil
+	}
+	if expiresAt := t.UpdatedAt.Add(tradeTTL); time.Now().Before(expiresAt) {
+		return Store.ScheduleJob(ctx, jobTradeExpire, job.RefID, expiresAt)
+	}
+	if PrimaryBot == nil {
+		return errors.New("PrimaryBot не инициализирован")
+	}
+	cancelTrade(ctx, PrimaryBot, t, "истекло время ожидания")
+	return nil
+}
+
+// HandleTrade обрабатывает команду /trade <ID анкеты или @username> – открывает обмен.
+// Без аргументов показывает текущий обмен.
+func HandleTrade(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	current, err := openTrade(ctx, bot, profile.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения обмена: "+err.Error())
+		return
+	}
+
+	ref := strings.TrimSpace(msg.CommandArguments())
+	if ref == "" {
+		if current == nil {
+			SendMessage(bot, msg.Chat.ID, "У вас нет открытого обмена. Используйте: /trade <ID анкеты или @username>")
+			return
+		}
+		sendTradeCard(ctx, bot, current, "", profile)
+		return
+	}
+	if current != nil {
+		SendMessage(bot, msg.Chat.ID, fmt.Sprintf("У вас уже открыт обмен #%d. Завершите или отмените его: /trade", current.ID))
+		return
+	}
+
+	partner, err := findProfile(ctx, ref)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, msg.Chat.ID, "Персонаж не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка поиска персонажа: "+err.Error())
+		return
+	}
+	if partner.ID == profile.ID {
+		SendMessage(bot, msg.Chat.ID, "Нельзя обменяться с самим собой.")
+		return
+	}
+	if _, err := openTrade(ctx, bot, partner.ID); err == nil {
+		SendMessage(bot, msg.Chat.ID, "У этого персонажа уже идёт другой обмен.")
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения обмена: "+err.Error())
+		return
+	}
+
+	trade := &models.Trade{InitiatorID: profile.ID, PartnerID: partner.ID, Status: models.TradeOpen}
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		// Проверки выше – для понятного ответа; здесь они повторяются, чтобы
+		// два одновременных /trade не открыли участнику второй обмен.
+		for _, id := range []int{profile.ID, partner.ID} {
+			if _, err := tx.GetOpenTrade(ctx, id); err == nil {
+				return errTradeExists
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+		if err := tx.CreateTrade(ctx, trade); err != nil {
+			return err
+		}
+		return tx.ScheduleJob(ctx, jobTradeExpire, int64(trade.ID), trade.UpdatedAt.Add(tradeTTL))
+	})
+	if errors.Is(err, errTradeExists) {
+		SendMessage(bot, msg.Chat.ID, "У вас или у этого персонажа уже идёт другой обмен.")
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка создания обмена: "+err.Error())
+		return
+	}
+	sendTradeCard(ctx, bot, trade, "Обмен открыт.", profile)
+	sendTradeCard(ctx, bot, trade, fmt.Sprintf("%s предлагает вам обмен.", profile.Name), partner)
+}
+
+// HandleOffer обрабатывает команду /offer [количество] <предмет или валюта>.
+// Команда задаёт, сколько предмета или валюты участник отдаёт в текущем обмене;
+// количество 0 убирает строку из предложения. Предложенное сразу забирается в залог
+// (уменьшение предложения возвращает разницу), поэтому до конца обмена его нельзя
+// потратить или передать. Подтверждения обеих сторон сбрасываются.
+func HandleOffer(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	const usage = "Используйте: /offer [количество] <предмет или валюта>, например /offer 10 piastres. Количество 0 убирает предложение."
+	fields := strings.Fields(msg.CommandArguments())
+	amount := 1
+	if len(fields) > 1 {
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			amount = n
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 || amount < 0 {
+		SendMessage(bot, msg.Chat.ID, usage)
+		return
+	}
+	ref := strings.Join(fields, " ")
+
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	trade, err := openTrade(ctx, bot, profile.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, msg.Chat.ID, "У вас нет открытого обмена. Используйте: /trade <ID анкеты или @username>")
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения обмена: "+err.Error())
+		return
+	}
+
+	line := models.TradeLine{ProfileID: profile.ID, Amount: amount}
+	currency, err := resolveCurrency(ctx, Store, ref, true)
+	switch {
+	case err == nil:
+		line.Currency = currency.Code
+	case errors.Is(err, db.ErrUnknownCurrency):
+		item, err := findItem(ctx, ref)
+		if errors.Is(err, sql.ErrNoRows) {
+			SendMessage(bot, msg.Chat.ID, "Нет такого предмета или валюты. Посмотреть инвентарь: /inventory")
+			return
+		} else if err != nil {
+			SendMessage(bot, msg.Chat.ID, "Ошибка поиска предмета: "+err.Error())
+			return
+		}
+		line.ItemID = item.ID
+	default:
+		SendMessage(bot, msg.Chat.ID, currencyErrorText(ctx, err))
+		return
+	}
+
+	err = Store.WithTx(ctx, func(tx db.Store) error {
+		t, err := tx.GetTrade(ctx, trade.ID)
+		if err != nil {
+			return err
+		}
+		if t.Status != models.TradeOpen {
+			return errTradeClosed
+		}
+		from, err := tx.GetProfileByID(ctx, profile.ID)
+		if err != nil {
+			return errProfileNotFound
+		}
+		offered := 0
+		for _, l := range t.Lines {
+			if l.ProfileID == line.ProfileID && l.ItemID == line.ItemID && l.Currency == line.Currency {
+				offered = l.Amount
+			}
+		}
+		if err := escrowTradeLine(ctx, tx, t, from, line, line.Amount-offered); err != nil {
+			return err
+		}
+		if err := tx.SetTradeLine(ctx, t.ID, line); err != nil {
+			return err
+		}
+		t.Revision++
+		t.InitiatorConfirmed, t.PartnerConfirmed = false, false
+		if err := tx.UpdateTrade(ctx, t); err != nil {
+			return err
+		}
+		if err := tx.ScheduleJob(ctx, jobTradeExpire, int64(t.ID), t.UpdatedAt.Add(tradeTTL)); err != nil {
+			return err
+		}
+		trade, err = tx.GetTrade(ctx, t.ID)
+		return err
+	})
+	if errors.Is(err, errTradeClosed) {
+		SendMessage(bot, msg.Chat.ID, "Обмен уже завершён или отменён.")
+		return
+	} else if errors.Is(err, errTradeShortfall) {
+		SendMessage(bot, msg.Chat.ID, "Не удалось изменить предложение – "+err.Error()+".")
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка изменения обмена: "+err.Error())
+		return
+	}
+
+	initiator, partner, err := tradeParties(ctx, Store, trade)
+	if err != nil {
+		cancelTrade(ctx, bot, trade, "анкета участника удалена")
+		return
+	}
+	sendTradeCard(ctx, bot, trade, fmt.Sprintf("%s изменяет предложение, подтверждения сброшены.", profile.Name),
+		initiator, partner)
+}
+
+// HandleTradeCancel обрабатывает команду /tradecancel – отмена текущего обмена.
+func HandleTradeCancel(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	profile, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	trade, err := openTrade(ctx, bot, profile.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, msg.Chat.ID, "У вас нет открытого обмена.")
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка получения обмена: "+err.Error())
+		return
+	}
+	cancelTrade(ctx, bot, trade, "отменил персонаж "+profile.Name)
+}
+
+// handleTradeCallback обрабатывает кнопки обмена: "confirm:<id>:<ревизия>" и "cancel:<id>".
+func handleTradeCallback(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, data string) {
+	parts := strings.Split(data, ":")
+	if len(parts) < 2 {
+		answerCallback(bot, cq, "Неверная кнопка.")
+		return
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		answerCallback(bot, cq, "Неверная кнопка.")
+		return
+	}
+	profile, err := Store.GetProfile(ctx, cq.From.ID)
+	if err != nil {
+		answerCallback(bot, cq, "Профиль не найден.")
+		return
+	}
+	trade, err := Store.GetTrade(ctx, id)
+	if err != nil {
+		answerCallback(bot, cq, "Обмен не найден.")
+		return
+	}
+	if _, ok := tradeSide(trade, profile.ID); !ok {
+		answerCallback(bot, cq, "Это не ваш обмен.")
+		return
+	}
+	if trade.Status != models.TradeOpen {
+		answerCallback(bot, cq, "Обмен уже завершён или отменён.")
+		editCallbackMessage(bot, cq, formatTrade(ctx, trade, profileName(ctx, trade.InitiatorID), profileName(ctx, trade.PartnerID)))
+		return
+	}
+	if time.Since(trade.UpdatedAt) > tradeTTL {
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, fmt.Sprintf("Обмен #%d отменён: истекло время ожидания", trade.ID))
+		cancelTrade(ctx, bot, trade, "истекло время ожидания")
+		return
+	}
+
+	switch parts[0] {
+	case "cancel":
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, fmt.Sprintf("Обмен #%d отменён.", trade.ID))
+		cancelTrade(ctx, bot, trade, "отменил персонаж "+profile.Name)
+	case "confirm":
+		if len(parts) != 3 {
+			answerCallback(bot, cq, "Неверная кнопка.")
+			return
+		}
+		revision, err := strconv.Atoi(parts[2])
+		if err != nil {
+			answerCallback(bot, cq, "Неверная кнопка.")
+			return
+		}
+		confirmTrade(ctx, bot, cq, profile, trade.ID, revision)
+	default:
+		answerCallback(bot, cq, "Неверная кнопка.")
+	}
+}
+
+// confirmTrade записывает подтверждение участника. Когда подтвердили оба, обмен
+// выполняется в той же транзакции; если анкету участника удалили, обмен отменяется.
+func confirmTrade(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, profile *models.Profile, tradeID, revision int) {
+	if err := checkNoDebt(profile); err != nil {
+		answerCallback(bot, cq, "Обмен недоступен: "+err.Error())
+		return
+	}
+	var trade *models.Trade
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		t, err := tx.GetTrade(ctx, tradeID)
+		if err != nil {
+			return err
+		}
+		trade = t
+		if t.Status != models.TradeOpen {
+			return errTradeClosed
+		}
+		if t.Revision != revision {
+			return errTradeStale
+		}
+		if len(t.Lines) == 0 {
+			return errTradeEmpty
+		}
+		if initiator, _ := tradeSide(t, profile.ID); initiator {
+			t.InitiatorConfirmed = true
+		} else {
+			t.PartnerConfirmed = true
+		}
+		if t.InitiatorConfirmed && t.PartnerConfirmed {
+			if err := executeTrade(ctx, tx, t); err != nil {
+				return err
+			}
+		}
+		return tx.UpdateTrade(ctx, t)
+	})
+	switch {
+	case errors.Is(err, errTradeClosed):
+		answerCallback(bot, cq, "Обмен уже завершён или отменён.")
+		return
+	case errors.Is(err, errTradeStale):
+		answerCallback(bot, cq, "Предложения изменились – подтвердите обмен в новом сообщении.")
+		editCallbackMessage(bot, cq, fmt.Sprintf("Предложения в обмене #%d изменились, это сообщение устарело.", tradeID))
+		return
+	case errors.Is(err, errTradeEmpty):
+		answerCallback(bot, cq, "Сначала добавьте предложения: /offer")
+		return
+	case errors.Is(err, errProfileNotFound):
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, fmt.Sprintf("Обмен #%d невозможен: %s", tradeID, err.Error()))
+		cancelTrade(ctx, bot, trade, err.Error())
+		return
+	case err != nil:
+		log.Printf("Ошибка обмена %d: %v", tradeID, err)
+		answerCallback(bot, cq, "Ошибка обмена, попробуйте позже.")
+		return
+	}
+
+	initiator, partner, err := tradeParties(ctx, Store, trade)
+	if err != nil {
+		answerCallback(bot, cq, "")
+		return
+	}
+	other := partner
+	if other.ID == profile.ID {
+		other = initiator
+	}
+	if trade.Status == models.TradeCompleted {
+		text := formatTrade(ctx, trade, initiator.Name, partner.Name)
+		answerCallback(bot, cq, "Обмен выполнен.")
+		editCallbackMessage(bot, cq, text)
+		SendMessage(bot, other.TelegramID, text)
+		return
+	}
+	answerCallback(bot, cq, "Вы подтвердили обмен.")
+	editCallbackMessage(bot, cq, fmt.Sprintf("Вы подтвердили обмен #%d. Ждём подтверждения от персонажа %s.", trade.ID, other.Name))
+	sendTradeCard(ctx, bot, trade, fmt.Sprintf("%s подтверждает обмен.", profile.Name), other)
+}
+
+// executeTrade передаёт получателям предметы и валюту из залога и закрывает обмен.
+// Вызывается внутри транзакции; при ошибке изменения откатываются.
+func executeTrade(ctx context.Context, tx db.Store, t *models.Trade) error {
+	initiator, partner, err := tradeParties(ctx, tx, t)
+	if err != nil {
+		return err
+	}
+	for _, line := range t.Lines {
+		from, to := initiator, partner
+		if line.ProfileID == partner.ID {
+			from, to = partner, initiator
+		}
+		if line.ItemID != 0 {
+			if err := tx.AddInventoryItem(ctx, to.ID, line.ItemID, line.Amount, ""); err != nil {
+				return err
+			}
+			continue
+		}
+		err := tx.ChangeBalance(ctx, &models.LedgerEntry{
+			ProfileID: to.ID, Currency: line.Currency, Delta: line.Amount,
+			Reason:     fmt.Sprintf("Обмен #%d с персонажем %s", t.ID, from.Name),
+			SourceType: models.LedgerSourceTrade, SourceID: int64(t.ID),
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка изменения баланса: %w", err)
+		}
+	}
+	t.Status = models.TradeCompleted
+	t.ClosedAt = time.Now()
+	return tx.CancelJob(ctx, jobTradeExpire, int64(t.ID))
+}
+
+// handleAdminTrades обрабатывает команду админского бота /trades [ID анкеты] – журнал обменов.
+func handleAdminTrades(ctx context.Context, bot Sender, chatID int64, args string) {
+	profileID := 0
+	if arg := strings.TrimSpace(args); arg != "" {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			SendMessage(bot, chatID, "Используйте: /trades [ID анкеты]")
+			return
+		}
+		profileID = id
+	}
+	trades, err := Store.ListTrades(ctx, profileID, tradeListLimit)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения обменов: "+err.Error())
+		return
+	}
+	if len(trades) == 0 {
+		SendMessage(bot, chatID, "Обменов нет.")
+		return
+	}
+	statuses := map[string]string{
+		models.TradeOpen:      "открыт",
+		models.TradeCompleted: "выполнен",
+		models.TradeCancelled: "отменён",
+	}
+	var b strings.Builder
+	b.WriteString("Последние обмены:\n")
+	for _, t := range trades {
+		fmt.Fprintf(&b, "#%d %s – %s ⇄ %s (ID %d ⇄ %d), %s\n",
+			t.ID, eventTime(t.CreatedAt),
+			profileName(ctx, t.InitiatorID), profileName(ctx, t.PartnerID), t.InitiatorID, t.PartnerID,
+			statuses[t.Status])
+	}
+	b.WriteString("Подробнее: /viewtrade <номер>")
+	SendMessage(bot, chatID, b.String())
+}
+
+// handleAdminViewTrade обрабатывает команду /viewtrade <номер обмена>.
+func handleAdminViewTrade(ctx context.Context, bot Sender, chatID int64, args string) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+	if err != nil {
+		SendMessage(bot, chatID, "Используйте: /viewtrade <номер обмена>")
+		return
+	}
+	trade, err := Store.GetTrade(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, chatID, "Обмен не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения обмена: "+err.Error())
+		return
+	}
+	text := formatTrade(ctx, trade, profileName(ctx, trade.InitiatorID), profileName(ctx, trade.PartnerID))
+	text += fmt.Sprintf("\nОткрыт: %s\nИзменён: %s\nРевизия предложений: %d",
+		eventTime(trade.CreatedAt), eventTime(trade.UpdatedAt),
+		trade.Revision)
+	SendMessage(bot, chatID, text)
+}
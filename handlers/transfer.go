@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-bot/db"
+	"telegram-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// transferConfirmTTL – сколько времени перевод ждёт подтверждения.
+const transferConfirmTTL = 10 * time.Minute
+
+// Ключи настроек переводов.
+const (
+	settingTransferFeePercent = "transfer.fee_percent"
+	settingTransferLimit      = "transfer.daily_limit." // + код валюты
+)
+
+// Ошибки проверки перевода.
+var (
+	errInsufficientFunds = errors.New("недостаточно средств")
+	errTransferLimit     = errors.New("превышен дневной лимит переводов")
+	errTransferProcessed = errors.New("перевод уже обработан")
+)
+
+// intSetting читает целочисленную настройку; незаданная настройка равна 0.
+func intSetting(ctx context.Context, store db.Store, key string) (int, error) {
+	value, err := store.GetSetting(ctx, key)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// transferFee – комиссия за перевод amount: процент из настроек, округлённый вверх.
+func transferFee(ctx context.Context, store db.Store, amount int) (int, error) {
+	percent, err := intSetting(ctx, store, settingTransferFeePercent)
+	if err != nil {
+		return 0, err
+	}
+	return (amount*percent + 99) / 100, nil
+}
+
+// startOfDay – начало суток, в которые попадает t, в часовом поясе t.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// checkTransfer проверяет долги, дневной лимит и баланс отправителя для перевода t.
+func checkTransfer(ctx context.Context, store db.Store, sender *models.Profile, t *models.Transfer) error {
+	if err := checkNoDebt(sender); err != nil {
+		return err
+	}
+	limit, err := intSetting(ctx, store, settingTransferLimit+t.Currency)
+	if err != nil {
+		return err
+	}
+	if limit > 0 {
+		sent, err := store.TransferredSince(ctx, sender.ID, t.Currency, startOfDay(gameNow()))
+		if err != nil {
+			return err
+		}
+		if sent+t.Amount > limit {
+			return fmt.Errorf("%w: сегодня переведено %d из %d", errTransferLimit, sent, limit)
+		}
+	}
+	if sender.Balance(t.Currency) < t.Amount+t.Fee {
+		return fmt.Errorf("%w: нужно %d, на балансе %d", errInsufficientFunds, t.Amount+t.Fee, sender.Balance(t.Currency))
+	}
+	return nil
+}
+
+// findProfile находит анкету по ID или по @username.
+func findProfile(ctx context.Context, ref string) (*models.Profile, error) {
+	if username, ok := strings.CutPrefix(ref, "@"); ok {
+		return Store.GetProfileByUsername(ctx, username)
+	}
+	id, err := strconv.Atoi(ref)
+	if err != nil {
+		return Store.GetProfileByUsername(ctx, ref)
+	}
+	return Store.GetProfileByID(ctx, id)
+}
+
+// HandlePay обрабатывает команду /pay <ID анкеты или @username> <сумма> <валюта> [комментарий].
+// Перевод сохраняется и выполняется только после подтверждения кнопкой.
+func HandlePay(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) < 3 {
+		SendMessage(bot, msg.Chat.ID, "Используйте: /pay <ID анкеты или @username> <сумма> <валюта> [комментарий]")
+		return
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil || amount <= 0 {
+		SendMessage(bot, msg.Chat.ID, "Сумма должна быть положительным числом.")
+		return
+	}
+	currency, err := resolveCurrency(ctx, Store, args[2], true)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, currencyErrorText(ctx, err))
+		return
+	}
+
+	sender, err := Store.GetProfile(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Используйте /createprofile для создания анкеты.")
+		return
+	}
+	recipient, err := findProfile(ctx, args[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		SendMessage(bot, msg.Chat.ID, "Получатель не найден.")
+		return
+	} else if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка поиска получателя: "+err.Error())
+		return
+	}
+	if recipient.ID == sender.ID {
+		SendMessage(bot, msg.Chat.ID, "Нельзя перевести валюту самому себе.")
+		return
+	}
+
+	fee, err := transferFee(ctx, Store, amount)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка чтения настроек переводов: "+err.Error())
+		return
+	}
+	transfer := &models.Transfer{
+		FromProfileID: sender.ID,
+		ToProfileID:   recipient.ID,
+		Currency:      currency.Code,
+		Amount:        amount,
+		Fee:           fee,
+		Comment:       strings.Join(args[3:], " "),
+		Status:        models.TransferPending,
+	}
+	// Предварительная проверка, чтобы не просить подтверждения заведомо невозможного перевода.
+	// Окончательная проверка выполняется в транзакции при подтверждении.
+	if err := checkTransfer(ctx, Store, sender, transfer); err != nil {
+		SendMessage(bot, msg.Chat.ID, "Перевод невозможен: "+err.Error())
+		return
+	}
+	if err := Store.CreateTransfer(ctx, transfer); err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка создания перевода: "+err.Error())
+		return
+	}
+
+	text := fmt.Sprintf("Перевести %d %s персонажу %s (ID %d)?", amount, currency.Label(), recipient.Name, recipient.ID)
+	if fee > 0 {
+		text += fmt.Sprintf("\nКомиссия: %d, всего будет списано: %d", fee, amount+fee)
+	}
+	if transfer.Comment != "" {
+		text += "\nКомментарий: " + transfer.Comment
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Подтвердить", fmt.Sprintf("pay:confirm:%d", transfer.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("Отменить", fmt.Sprintf("pay:cancel:%d", transfer.ID)),
+	))
+	bot.Send(reply)
+}
+
+// handlePayCallback обрабатывает кнопки подтверждения перевода: "confirm:<id>" и "cancel:<id>".
+func handlePayCallback(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, data string) {
+	action, idStr, _ := strings.Cut(data, ":")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		answerCallback(bot, cq, "Неверная кнопка.")
+		return
+	}
+	transfer, err := Store.GetTransfer(ctx, id)
+	if err != nil {
+		answerCallback(bot, cq, "Перевод не найден.")
+		return
+	}
+	sender, err := Store.GetProfile(ctx, cq.From.ID)
+	if err != nil || sender.ID != transfer.FromProfileID {
+		answerCallback(bot, cq, "Это не ваш перевод.")
+		return
+	}
+	if transfer.Status != models.TransferPending {
+		answerCallback(bot, cq, "Перевод уже обработан.")
+		return
+	}
+
+	switch action {
+	case "cancel":
+		transfer.Status = models.TransferCancelled
+		if err := Store.UpdateTransfer(ctx, transfer); err != nil {
+			answerCallback(bot, cq, "Ошибка отмены перевода.")
+			return
+		}
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, "Перевод отменён.")
+	case "confirm":
+		confirmTransfer(ctx, bot, cq, transfer)
+	default:
+		answerCallback(bot, cq, "Неверная кнопка.")
+	}
+}
+
+// confirmTransfer выполняет перевод: проверка лимита и баланса, списание с отправителя
+// (с комиссией) и зачисление получателю выполняются в одной транзакции.
+func confirmTransfer(ctx context.Context, bot Sender, cq *tgbotapi.CallbackQuery, transfer *models.Transfer) {
+	if time.Since(transfer.CreatedAt) > transferConfirmTTL {
+		transfer.Status = models.TransferCancelled
+		Store.UpdateTransfer(ctx, transfer)
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, "Время подтверждения истекло, перевод отменён. Отправьте /pay ещё раз.")
+		return
+	}
+
+	var sender, recipient *models.Profile
+	err := Store.WithTx(ctx, func(tx db.Store) error {
+		t, err := tx.GetTransfer(ctx, transfer.ID)
+		if err != nil {
+			return err
+		}
+		if t.Status != models.TransferPending {
+			return errTransferProcessed
+		}
+		if sender, err = tx.GetProfileByID(ctx, t.FromProfileID); err != nil {
+			return errProfileNotFound
+		}
+		if recipient, err = tx.GetProfileByID(ctx, t.ToProfileID); err != nil {
+			return errProfileNotFound
+		}
+		if err := checkTransfer(ctx, tx, sender, t); err != nil {
+			return err
+		}
+
+		reason := fmt.Sprintf("Перевод персонажу %s", recipient.Name)
+		if t.Comment != "" {
+			reason += ": " + t.Comment
+		}
+		entries := []*models.LedgerEntry{
+			{ProfileID: sender.ID, Delta: -t.Amount, Reason: reason},
+			{ProfileID: recipient.ID, Delta: t.Amount, Reason: fmt.Sprintf("Перевод от персонажа %s", sender.Name)},
+		}
+		if t.Comment != "" {
+			entries[1].Reason += ": " + t.Comment
+		}
+		if t.Fee > 0 {
+			entries = append(entries, &models.LedgerEntry{ProfileID: sender.ID, Delta: -t.Fee, Reason: "Комиссия за перевод"})
+		}
+		for _, e := range entries {
+			e.Currency = t.Currency
+			e.SourceType = models.LedgerSourceTransfer
+			e.SourceID = int64(t.ID)
+			if err := tx.ChangeBalance(ctx, e); err != nil {
+				return fmt.Errorf("ошибка изменения баланса: %w", err)
+			}
+		}
+
+		t.Status = models.TransferCompleted
+		t.CompletedAt = time.Now()
+		*transfer = *t
+		return tx.UpdateTransfer(ctx, t)
+	})
+	switch {
+	case errors.Is(err, errTransferProcessed):
+		answerCallback(bot, cq, "Перевод уже обработан.")
+		return
+	case errors.Is(err, errInsufficientFunds), errors.Is(err, errTransferLimit), errors.Is(err, errProfileNotFound),
+		errors.Is(err, errInDebt):
+		transfer.Status = models.TransferCancelled
+		Store.UpdateTransfer(ctx, transfer)
+		answerCallback(bot, cq, "")
+		editCallbackMessage(bot, cq, "Перевод невозможен: "+err.Error())
+		return
+	case err != nil:
+		log.Printf("Ошибка перевода %d: %v", transfer.ID, err)
+		answerCallback(bot, cq, "Ошибка перевода, попробуйте позже.")
+		return
+	}
+
+	label := transfer.Currency
+	if c, err := Store.GetCurrency(ctx, transfer.Currency); err == nil {
+		label = c.Label()
+	}
+	answerCallback(bot, cq, "Перевод выполнен.")
+	editCallbackMessage(bot, cq, fmt.Sprintf("Перевод выполнен: %d %s → %s. Остаток: %d",
+		transfer.Amount, label, recipient.Name, sender.Balance(transfer.Currency)-transfer.Amount-transfer.Fee))
+
+	notice := fmt.Sprintf("Вам перевод от персонажа %s: %d %s", sender.Name, transfer.Amount, label)
+	if transfer.Comment != "" {
+		notice += "\nКомментарий: " + transfer.Comment
+	}
+	SendMessage(bot, recipient.TelegramID, notice)
+}
+
+// handleAdminTransferSettings обрабатывает команду админского бота /transfersettings.
+func handleAdminTransferSettings(ctx context.Context, bot Sender, chatID int64) {
+	percent, err := intSetting(ctx, Store, settingTransferFeePercent)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка чтения настроек: "+err.Error())
+		return
+	}
+	currencies, err := Store.ListCurrencies(ctx, false)
+	if err != nil {
+		SendMessage(bot, chatID, "Ошибка получения валют: "+err.Error())
+		return
+	}
+	text := fmt.Sprintf("Настройки переводов:\nКомиссия: %d%%\nДневные лимиты:", percent)
+	for _, c := range currencies {
+		limit, err := intSetting(ctx, Store, settingTransferLimit+c.Code)
+		if err != nil {
+			SendMessage(bot, chatID, "Ошибка чтения настроек: "+err.Error())
+			return
+		}
+		if limit > 0 {
+			text += fmt.Sprintf("\n%s: %d", c.Label(), limit)
+		} else {
+			text += fmt.Sprintf("\n%s: без лимита", c.Label())
+		}
+	}
+	SendMessage(bot, chatID, text)
+}
+
+// handleAdminSetTransferFee обрабатывает команду /settransferfee <процент>.
+func handleAdminSetTransferFee(ctx context.Context, bot Sender, chatID int64, args string) {
+	percent, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || percent < 0 || percent > 100 {
+		SendMessage(bot, chatID, "Используйте: /settransferfee <процент от 0 до 100>")
+		return
+	}
+	if err := Store.SetSetting(ctx, settingTransferFeePercent, strconv.Itoa(percent)); err != nil {
+		SendMessage(bot, chatID, "Ошибка сохранения настройки: "+err.Error())
+		return
+	}
+	SendMessage(bot, chatID, fmt.Sprintf("Комиссия за перевод: %d%%", percent))
+}
+
+// handleAdminSetTransferLimit обрабатывает команду /settransferlimit <валюта> <сумма в день>.
+// Лимит 0 снимает ограничение.
+func handleAdminSetTransferLimit(ctx context.Context, bot Sender, chatID int64, args string) {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		SendMessage(bot, chatID, "Используйте: /settransferlimit <валюта> <сумма в день, 0 – без лимита>")
+		return
+	}
+	currency, err := resolveCurrency(ctx, Store, parts[0], false)
+	if err != nil {
+		SendMessage(bot, chatID, currencyErrorText(ctx, err))
+		return
+	}
+	limit, err := strconv.Atoi(parts[1])
+	if err != nil || limit < 0 {
+		SendMessage(bot, chatID, "Лимит должен быть неотрицательным числом.")
+		return
+	}
+	if err := Store.SetSetting(ctx, settingTransferLimit+currency.Code, strconv.Itoa(limit)); err != nil {
+		SendMessage(bot, chatID, "Ошибка сохранения настройки: "+err.Error())
+		return
+	}
+	if limit == 0 {
+		SendMessage(bot, chatID, fmt.Sprintf("Лимит переводов %s снят.", currency.Label()))
+	} else {
+		SendMessage(bot, chatID, fmt.Sprintf("Лимит переводов %s: %d в день.", currency.Label(), limit))
+	}
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"log"
 	"strconv"
 
 	"fmt"
+	"telegram-bot/config"
 	"telegram-bot/db"
 	"telegram-bot/models"
 	"telegram-bot/utils"
@@ -26,15 +28,17 @@ const (
 	StepCompleted
 )
 
-type ConversationState struct {
-	CurrentStep int
-	Profile     *models.Profile
-}
-
-var registrationStates = make(map[int64]*ConversationState)
+// ConversationState – состояние диалога регистрации, хранится в базе (db.SaveRegistrationState).
+type ConversationState = models.RegistrationState
 
 // HandleStart – при команде /start запускается регистрация или выводится меню
-func HandleStart(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func HandleStart(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	// Ссылка для отметки на событии: t.me/<бот>?start=attend_<ID>_<код>.
+	if eventID, code, ok := parseCheckinLink(msg.CommandArguments()); ok {
+		attendEvent(ctx, bot, msg, eventID, code)
+		return
+	}
+
 	helpText := "Доступные команды:\n" +
 		"/start - начать регистрацию / показать меню\n" +
 		"/createprofile - создать новую анкету\n" +
@@ -44,15 +48,30 @@ func HandleStart(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		"/setage <возраст> - изменить возраст\n" +
 		"/setheight <рост> - изменить рост\n" +
 		"/setweight <вес> - изменить вес\n" +
-		"/setinventory <инвентарь> - изменить инвентарь\n" +
 		"/setphoto <file_id> - изменить фото\n" +
 		"/setrank <ранг> - изменить ранг\n" +
 		"/setteam <команда> - изменить команду\n" +
-		"/attend - отметиться на активном ивенте\n" +
+		"/events - открытые события\n" +
+		"/attend <ID> [код] - отметиться на активном ивенте\n" +
 		"/unattend - отменить отметку на активном ивенте\n" +
+		"/balance - текущий баланс\n" +
+		"/history - последние операции с валютой\n" +
+		"/inventory - инвентарь персонажа\n" +
+		"/pay <ID анкеты или @username> <сумма> <валюта> [комментарий] - перевести валюту персонажу\n" +
+		"/shop - магазин\n" +
+		"/buy <ID товара> - купить товар\n" +
+		"/trade <ID анкеты или @username> - предложить обмен персонажу\n" +
+		"/offer [количество] <предмет или валюта> - изменить своё предложение в обмене\n" +
+		"/tradecancel - отменить текущий обмен\n" +
+		"/auctions - открытые аукционы\n" +
+		"/auction <предмет> [количество]|<цена> <валюта>|<длительность> - выставить предмет на аукцион\n" +
+		"/bid <номер аукциона> <сумма> - сделать ставку\n" +
+		"/exchange <сумма> <из валюты> <в валюту> - обменять валюту по курсу\n" +
+		"/daily - ежедневная награда\n" +
+		"/cancel - отменить незавершённую регистрацию\n" +
 		"/help - вывести эту справку\n"
 
-	existingProfile, err := db.GetProfile(msg.From.ID)
+	existingProfile, err := Store.GetProfile(ctx, msg.From.ID)
 	if err == nil && existingProfile != nil && existingProfile.Name != "" {
 		SendMessage(bot, msg.Chat.ID, "Привет! Ваша анкета уже создана.\n\n"+helpText)
 		return
@@ -61,18 +80,22 @@ func HandleStart(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	newProfile := &models.Profile{
 		TelegramID: msg.From.ID,
 		Username:   msg.From.UserName,
-		Piastres:   0,
-		Oblomki:    0,
 	}
-	registrationStates[msg.From.ID] = &ConversationState{
+	err = Store.SaveRegistrationState(ctx, &ConversationState{
+		TelegramID:  msg.From.ID,
+		ChatID:      msg.Chat.ID,
 		CurrentStep: StepName,
 		Profile:     newProfile,
+	})
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка начала регистрации: "+err.Error())
+		return
 	}
 	SendMessage(bot, msg.Chat.ID, "Добро пожаловать! Начинаем регистрацию.\nВведите ваше имя.\n\n"+helpText)
 }
 
 // HandleUserHelp – выводит справку команд для пользователя.
-func HandleUserHelp(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func HandleUserHelp(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
 	helpText := "Доступные команды:\n" +
 		"/start - начать регистрацию / показать меню\n" +
 		"/createprofile - создать новую анкету\n" +
@@ -82,26 +105,53 @@ func HandleUserHelp(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		"/setage <возраст> - изменить возраст\n" +
 		"/setheight <рост> - изменить рост\n" +
 		"/setweight <вес> - изменить вес\n" +
-		"/setinventory <инвентарь> - изменить инвентарь\n" +
 		"/setphoto <file_id> - изменить фото\n" +
 		"/setrank <ранг> - изменить ранг\n" +
 		"/setteam <команда> - изменить команду\n" +
-		"/attend - отметиться на активном ивенте\n" +
+		"/events - открытые события\n" +
+		"/attend <ID> [код] - отметиться на активном ивенте\n" +
 		"/unattend - отменить отметку на активном ивенте\n" +
+		"/balance - текущий баланс\n" +
+		"/history - последние операции с валютой\n" +
+		"/inventory - инвентарь персонажа\n" +
+		"/pay <ID анкеты или @username> <сумма> <валюта> [комментарий] - перевести валюту персонажу\n" +
+		"/shop - магазин\n" +
+		"/buy <ID товара> - купить товар\n" +
+		"/trade <ID анкеты или @username> - предложить обмен персонажу\n" +
+		"/offer [количество] <предмет или валюта> - изменить своё предложение в обмене\n" +
+		"/tradecancel - отменить текущий обмен\n" +
+		"/auctions - открытые аукционы\n" +
+		"/auction <предмет> [количество]|<цена> <валюта>|<длительность> - выставить предмет на аукцион\n" +
+		"/bid <номер аукциона> <сумма> - сделать ставку\n" +
+		"/exchange <сумма> <из валюты> <в валюту> - обменять валюту по курсу\n" +
+		"/daily - ежедневная награда\n" +
+		"/cancel - отменить незавершённую регистрацию\n" +
 		"/help - вывести эту справку\n"
 	SendMessage(bot, msg.Chat.ID, helpText)
 }
 
 // HandleRegistrationConversation обрабатывает ввод данных при регистрации.
-func HandleRegistrationConversation(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, state *ConversationState) {
+// После каждого шага черновик сохраняется в базе.
+func HandleRegistrationConversation(ctx context.Context, bot Sender, msg *tgbotapi.Message, state *ConversationState) {
 	chatID := msg.Chat.ID
 	text := msg.Text
 
+	// Сохраняем черновик перед ответом, чтобы следующий шаг не потерялся при перезапуске.
+	saveAndReply := func(next int, reply string) {
+		state.CurrentStep = next
+		state.ChatID = chatID
+		if err := Store.SaveRegistrationState(ctx, state); err != nil {
+			log.Printf("Ошибка сохранения черновика регистрации %d: %v", state.TelegramID, err)
+			SendMessage(bot, chatID, "Ошибка сохранения ответа. Попробуйте ещё раз.")
+			return
+		}
+		SendMessage(bot, chatID, reply)
+	}
+
 	switch state.CurrentStep {
 	case StepName:
 		state.Profile.Name = text
-		state.CurrentStep = StepAge
-		SendMessage(bot, chatID, "Введите ваш возраст (целое число):")
+		saveAndReply(StepAge, "Введите ваш возраст (целое число):")
 	case StepAge:
 		age, err := strconv.Atoi(text)
 		if err != nil {
@@ -109,8 +159,7 @@ func HandleRegistrationConversation(bot *tgbotapi.BotAPI, msg *tgbotapi.Message,
 			return
 		}
 		state.Profile.Age = age
-		state.CurrentStep = StepHeight
-		SendMessage(bot, chatID, "Введите ваш рост (например, 175.5):")
+		saveAndReply(StepHeight, "Введите ваш рост (например, 175.5):")
 	case StepHeight:
 		height, err := strconv.ParseFloat(text, 64)
 		if err != nil {
@@ -118,8 +167,7 @@ func HandleRegistrationConversation(bot *tgbotapi.BotAPI, msg *tgbotapi.Message,
 			return
 		}
 		state.Profile.Height = height
-		state.CurrentStep = StepWeight
-		SendMessage(bot, chatID, "Введите ваш вес (например, 70.2):")
+		saveAndReply(StepWeight, "Введите ваш вес (например, 70.2):")
 	case StepWeight:
 		weight, err := strconv.ParseFloat(text, 64)
 		if err != nil {
@@ -127,29 +175,24 @@ func HandleRegistrationConversation(bot *tgbotapi.BotAPI, msg *tgbotapi.Message,
 			return
 		}
 		state.Profile.Weight = weight
-		state.CurrentStep = StepInventory
-		SendMessage(bot, chatID, "Опишите ваш инвентарь:")
+		saveAndReply(StepInventory, "Опишите ваш инвентарь:")
 	case StepInventory:
-		state.Profile.Inventory = text
-		state.CurrentStep = StepPhoto
-		SendMessage(bot, chatID, "Пришлите фотографию или введите file_id:")
+		state.Profile.StartingInventory = text
+		saveAndReply(StepPhoto, "Пришлите фотографию или введите file_id:")
 	case StepPhoto:
 		state.Profile.Photo = text
-		state.CurrentStep = StepRank
-		SendMessage(bot, chatID, "Введите ваш ранг:")
+		saveAndReply(StepRank, "Введите ваш ранг:")
 	case StepRank:
 		state.Profile.Rank = text
-		state.CurrentStep = StepTeam
-		SendMessage(bot, chatID, "Введите вашу команду:")
+		saveAndReply(StepTeam, "Введите вашу команду:")
 	case StepTeam:
 		state.Profile.Team = text
-		state.CurrentStep = StepRace
-		SendMessage(bot, chatID, "Введите вашу расу:")
+		saveAndReply(StepRace, "Введите вашу расу:")
 	case StepRace:
 		state.Profile.Race = text
 		state.CurrentStep = StepCompleted
 
-		err := db.SaveProfile(state.Profile)
+		err := Store.SaveProfile(ctx, state.Profile)
 		if err != nil {
 			SendMessage(bot, chatID, "Ошибка сохранения профиля. Попробуйте снова. (Ошибка: "+err.Error()+")")
 		} else {
@@ -159,13 +202,29 @@ func HandleRegistrationConversation(bot *tgbotapi.BotAPI, msg *tgbotapi.Message,
 			// Отправляем полную информацию админскому боту
 			SendProfileToAdminBot(state.Profile)
 		}
-		delete(registrationStates, msg.From.ID)
+		if _, err := Store.DeleteRegistrationState(ctx, msg.From.ID); err != nil {
+			log.Printf("Ошибка удаления черновика регистрации %d: %v", msg.From.ID, err)
+		}
+	}
+}
+
+// HandleCancelRegistration обрабатывает команду /cancel – удаляет черновик регистрации.
+func HandleCancelRegistration(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	deleted, err := Store.DeleteRegistrationState(ctx, msg.From.ID)
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка отмены регистрации: "+err.Error())
+		return
 	}
+	if !deleted {
+		SendMessage(bot, msg.Chat.ID, "Нет незавершённой регистрации.")
+		return
+	}
+	SendMessage(bot, msg.Chat.ID, "Регистрация отменена, введённые данные удалены. Начать заново: /start")
 }
 
 // HandleDeleteProfile удаляет профиль пользователя.
-func HandleDeleteProfile(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
-	err := db.DeleteProfile(msg.From.ID)
+func HandleDeleteProfile(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
+	err := Store.DeleteProfile(ctx, msg.From.ID)
 	if err != nil {
 		SendMessage(bot, msg.Chat.ID, "Ошибка при удалении профиля: "+err.Error())
 	} else {
@@ -174,7 +233,7 @@ func HandleDeleteProfile(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 }
 
 // HandleAttendCommand обрабатывает команду /attend – отметиться на активном ивенте.
-func HandleAttendCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func HandleAttendCommand(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
 	if currentEvent == nil {
 		SendMessage(bot, msg.Chat.ID, "На данный момент активных событий нет.")
 		return
@@ -185,31 +244,40 @@ func HandleAttendCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
-	profile, err := db.GetProfile(userID)
+	profile, err := Store.GetProfile(ctx, userID)
 	if err != nil || profile == nil {
 		SendMessage(bot, msg.Chat.ID, "Профиль не найден. Пожалуйста, зарегистрируйтесь через /start.")
 		return
 	}
 
-	switch currentEvent.CurrencyType {
-	case "piastres", "пиastres":
-		profile.Piastres += currentEvent.Amount
-	case "oblomki", "обломки":
-		profile.Oblomki += currentEvent.Amount
-	default:
+	currency, err := resolveCurrency(ctx, Store, currentEvent.CurrencyType, false)
+	if err != nil {
 		SendMessage(bot, msg.Chat.ID, "Неизвестный тип валюты в событии.")
 		return
 	}
+	eventID, _ := strconv.ParseInt(currentEvent.EventID, 10, 64)
+	credit := &models.LedgerEntry{
+		ProfileID:  profile.ID,
+		Currency:   currency.Code,
+		Delta:      currentEvent.Amount,
+		Reason:     "Участие в событии",
+		SourceType: models.LedgerSourceEvent,
+		SourceID:   eventID,
+	}
+	if err := Store.ChangeBalance(ctx, credit); err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка начисления валюты: "+err.Error())
+		return
+	}
 
 	currentEvent.Participants[userID] = true
-	db.SaveProfile(profile)
+	profile, _ = Store.GetProfile(ctx, userID)
 	SendMessage(bot, msg.Chat.ID, fmt.Sprintf(
-		"Вы успешно приняли участие в событии!\nВаш профиль:\nИмя: %s\nПиастры: %d\nОбломки: %d",
-		profile.Name, profile.Piastres, profile.Oblomki))
+		"Вы успешно приняли участие в событии!%s\nВаш профиль:\nИмя: %s\n%s",
+		debtRepaymentText(currency.Label(), credit), profile.Name, utils.FormatBalance(profile)))
 }
 
 // HandleUnattendCommand обрабатывает команду /unattend – отменить отметку на активном ивенте.
-func HandleUnattendCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func HandleUnattendCommand(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
 	if currentEvent == nil {
 		SendMessage(bot, msg.Chat.ID, "На данный момент активных событий нет.")
 		return
@@ -220,45 +288,48 @@ func HandleUnattendCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
-	profile, err := db.GetProfile(userID)
+	profile, err := Store.GetProfile(ctx, userID)
 	if err != nil || profile == nil {
 		SendMessage(bot, msg.Chat.ID, "Профиль не найден.")
 		return
 	}
 
-	delete(currentEvent.Participants, userID)
-	switch currentEvent.CurrencyType {
-	case "piastres", "пиastres":
-		if profile.Piastres >= currentEvent.Amount {
-			profile.Piastres -= currentEvent.Amount
-		} else {
-			profile.Piastres = 0
-		}
-	case "oblomki", "обломки":
-		if profile.Oblomki >= currentEvent.Amount {
-			profile.Oblomki -= currentEvent.Amount
-		} else {
-			profile.Oblomki = 0
-		}
-	default:
+	currency, err := resolveCurrency(ctx, Store, currentEvent.CurrencyType, false)
+	if err != nil {
 		SendMessage(bot, msg.Chat.ID, "Неизвестный тип валюты в событии.")
 		return
 	}
-	db.SaveProfile(profile)
-	SendMessage(bot, msg.Chat.ID, "Ваша отметка отменена, начисленная валюта списана.\nВаш профиль:\n"+utils.FormatProfile(profile))
+	// Если начисленное уже потрачено, баланс уходит в минус и становится долгом.
+	eventID, _ := strconv.ParseInt(currentEvent.EventID, 10, 64)
+	err = Store.ChangeBalance(ctx, &models.LedgerEntry{
+		ProfileID:  profile.ID,
+		Currency:   currency.Code,
+		Delta:      -currentEvent.Amount,
+		Reason:     "Отмена участия в событии",
+		SourceType: models.LedgerSourceEvent,
+		SourceID:   eventID,
+	})
+	if err != nil {
+		SendMessage(bot, msg.Chat.ID, "Ошибка списания валюты: "+err.Error())
+		return
+	}
+	delete(currentEvent.Participants, userID)
+	profile, _ = Store.GetProfile(ctx, userID)
+	SendMessage(bot, msg.Chat.ID, "Ваша отметка отменена, начисленная валюта списана."+debtText(profile)+
+		"\nВаш профиль:\n"+utils.FormatProfile(profile))
 }
 
 // ProcessUserCommand диспетчер пользовательских команд.
-func ProcessUserCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func ProcessUserCommand(ctx context.Context, bot Sender, msg *tgbotapi.Message) {
 	switch msg.Command() {
 	case "start":
-		HandleStart(bot, msg)
+		HandleStart(ctx, bot, msg)
 	case "help":
-		HandleUserHelp(bot, msg)
+		HandleUserHelp(ctx, bot, msg)
 	case "attend":
-		HandleAttendCommand(bot, msg)
+		HandleAttendCommand(ctx, bot, msg)
 	case "unattend":
-		HandleUnattendCommand(bot, msg)
+		HandleUnattendCommand(ctx, bot, msg)
 	// Дополнительные команды (например, profile, createprofile) можно добавить здесь.
 	default:
 		SendMessage(bot, msg.Chat.ID, "Неизвестная команда. Используйте /help для списка команд.")
@@ -266,36 +337,68 @@ func ProcessUserCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 }
 
 // SendMessage универсальная функция отправки сообщений.
-func SendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) {
+func SendMessage(bot Sender, chatID int64, text string) {
 	message := tgbotapi.NewMessage(chatID, text)
 	bot.Send(message)
 }
 
-// Declare AdminBot as a global variable
-var AdminBot *tgbotapi.BotAPI
-var PrimaryBot *tgbotapi.BotAPI
+// Store – хранилище данных, с которым работают обработчики (задаётся при старте).
+var Store db.Store
+
+// AdminBot и PrimaryBot – боты для уведомлений из обработчиков другого бота
+// (новая анкета уходит в админский, рассылка события – в пользовательский).
+var AdminBot Sender
+var PrimaryBot Sender
+
+// PrimaryBotUsername – имя пользовательского бота для ссылок t.me (задаётся при старте).
+var PrimaryBotUsername string
+
+// notifyProfile отправляет сообщение владельцу анкеты через пользовательского бота.
+func notifyProfile(ctx context.Context, profileID int, text string) {
+	if PrimaryBot == nil {
+		log.Println("PrimaryBot не инициализирован")
+		return
+	}
+	p, err := Store.GetProfileByID(ctx, profileID)
+	if err != nil {
+		log.Printf("Уведомление не отправлено: анкета %d не найдена: %v", profileID, err)
+		return
+	}
+	SendMessage(PrimaryBot, p.TelegramID, text)
+}
+
+// notifyAdmins отправляет сообщение админским ботом во все чаты из admin.chat_ids.
+func notifyAdmins(text string) {
+	if AdminBot == nil {
+		log.Println("Админский бот не инициализирован")
+		return
+	}
+	for _, chatID := range config.Current.Admin.ChatIDs {
+		SendMessage(AdminBot, chatID, text)
+	}
+}
 
-// SendProfileToAdminBot отправляет профиль админскому боту.
+// SendProfileToAdminBot отправляет профиль админскому боту во все чаты из admin.chat_ids.
 func SendProfileToAdminBot(profile *models.Profile) {
 	if AdminBot == nil {
 		log.Println("Админский бот не инициализирован")
 		return
 	}
 	profileText := utils.FormatProfileAdmin(profile)
-	// Укажите корректный chat_id для админских уведомлений.
-	adminChatID := int64(123456789) // замените на реальный chat_id
-	if profile.Photo != "" {
-		photoMsg := tgbotapi.NewPhoto(adminChatID, tgbotapi.FileID(profile.Photo))
-		photoMsg.Caption = profileText
-		_, err := AdminBot.Send(photoMsg)
-		if err != nil {
-			log.Printf("Ошибка отправки фото профиля админскому боту: %v", err)
-		}
-	} else {
-		message := tgbotapi.NewMessage(adminChatID, profileText)
-		_, err := AdminBot.Send(message)
-		if err != nil {
-			log.Printf("Ошибка отправки профиля админскому боту: %v", err)
+	for _, adminChatID := range config.Current.Admin.ChatIDs {
+		if profile.Photo != "" {
+			photoMsg := tgbotapi.NewPhoto(adminChatID, tgbotapi.FileID(profile.Photo))
+			photoMsg.Caption = profileText
+			_, err := AdminBot.Send(photoMsg)
+			if err != nil {
+				log.Printf("Ошибка отправки фото профиля админскому боту: %v", err)
+			}
+		} else {
+			message := tgbotapi.NewMessage(adminChatID, profileText)
+			_, err := AdminBot.Send(message)
+			if err != nil {
+				log.Printf("Ошибка отправки профиля админскому боту: %v", err)
+			}
 		}
 	}
 }
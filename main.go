@@ -1,63 +1,174 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	_ "time/tzdata" // база часовых поясов для параметра timezone в контейнерах без tzdata
+
 	"telegram-bot/config"
 	"telegram-bot/db"
+	"telegram-bot/dispatcher"
 	"telegram-bot/handlers"
+	"telegram-bot/scheduler"
+	"telegram-bot/transport"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 func main() {
-	// Инициализация пользовательского (основного) бота
-	botToken := config.LoadConfig()
-	if botToken == "" {
-		botToken = "7785304405:AAFWCYVT_s8axaV8P4exA54TrkvB_Q5rRco"
+	configPath := flag.String("config", "", "путь к файлу конфигурации (по умолчанию CONFIG_PATH или "+config.DefaultConfigPath+")")
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "вывести ожидающие миграции базы данных и выйти")
+	flag.Parse()
+
+	// Загрузка и проверка конфигурации
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
 	}
-	primaryBot, err := tgbotapi.NewBotAPI(botToken)
+
+	if *migrateDryRun {
+		printPendingMigrations(cfg.Database.DSN)
+		return
+	}
+
+	// Инициализация пользовательского (основного) бота
+	primaryBot, err := tgbotapi.NewBotAPI(cfg.PrimaryBot.Token)
 	if err != nil {
 		log.Panic(err)
 	}
-	primaryBot.Debug = true
+	primaryBot.Debug = cfg.PrimaryBot.Debug
 	log.Printf("Пользовательский бот авторизован как: %s", primaryBot.Self.UserName)
 	handlers.PrimaryBot = primaryBot
+	handlers.PrimaryBotUsername = primaryBot.Self.UserName
 
 	// Инициализация админского бота
-	adminBotToken := "8051322387:AAG4pnS8hch0JHBWgVS1qLt12JQCjd_JyB0"
-	adminBot, err := tgbotapi.NewBotAPI(adminBotToken)
+	adminBot, err := tgbotapi.NewBotAPI(cfg.AdminBot.Token)
 	if err != nil {
 		log.Panic(err)
 	}
-	adminBot.Debug = true
+	adminBot.Debug = cfg.AdminBot.Debug
 	log.Printf("Админский бот авторизован как: %s", adminBot.Self.UserName)
 	handlers.AdminBot = adminBot
 
 	// Инициализация базы данных
-	db.InitDB()
+	store := db.InitDB(cfg.Database.DSN)
+	handlers.Store = store
+
+	// Получение обновлений: long polling или webhook, в зависимости от конфигурации
+	receiver, err := transport.New(cfg.Transport)
+	if err != nil {
+		log.Fatalf("Ошибка настройки получения обновлений: %v", err)
+	}
+	updates, err := receiver.Listen(primaryBot, cfg.Transport.Webhook.PrimaryPath)
+	if err != nil {
+		log.Fatalf("Ошибка подписки на обновления пользовательского бота: %v", err)
+	}
+	adminUpdates, err := receiver.Listen(adminBot, cfg.Transport.Webhook.AdminPath)
+	if err != nil {
+		log.Fatalf("Ошибка подписки на обновления админского бота: %v", err)
+	}
+	if err := receiver.Start(); err != nil {
+		log.Fatalf("Ошибка запуска получения обновлений (%s): %v", cfg.Transport.Mode, err)
+	}
+	log.Printf("Получение обновлений запущено в режиме %s", cfg.Transport.Mode)
+
+	// Обновления обрабатываются ограниченным пулом воркеров;
+	// сообщения одного чата обрабатываются строго по порядку.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	d := dispatcher.New(cfg.Dispatcher.Workers, cfg.Dispatcher.QueueSize)
+	d.Consume(updates, func(ctx context.Context, update tgbotapi.Update) {
+		handlers.HandleUpdate(ctx, primaryBot, update)
+	})
+	d.Consume(adminUpdates, func(ctx context.Context, update tgbotapi.Update) {
+		handlers.HandleAdminUpdate(ctx, adminBot, update)
+	})
+
+	// Фоновые задачи работают с базой, поэтому перед её закрытием их нужно дождаться.
+	var background sync.WaitGroup
 
-	// Запускаем цикл обновлений для пользовательского бота
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := primaryBot.GetUpdatesChan(u)
+	// Напоминания и удаление заброшенных черновиков регистрации
+	background.Add(1)
 	go func() {
-		for update := range updates {
-			// Обработка обновлений для пользовательского бота
-			go handlers.HandleUpdate(primaryBot, update)
-		}
+		defer background.Done()
+		handlers.RunRegistrationJanitor(ctx, primaryBot, cfg.Registration.TTL, cfg.Registration.RemindBefore)
 	}()
 
-	// Запускаем цикл обновлений для админского бота
-	u2 := tgbotapi.NewUpdate(0)
-	u2.Timeout = 60
-	adminUpdates := adminBot.GetUpdatesChan(u2)
+	// Отложенные задачи (закрытие аукционов и т.д.) хранятся в базе и выполняются после перезапуска
+	jobs := scheduler.New(store, cfg.Scheduler.Interval)
+	handlers.RegisterJobs(jobs)
+	if err := handlers.ScheduleReconcile(ctx); err != nil {
+		log.Printf("Ошибка планирования сверки балансов: %v", err)
+	}
+	background.Add(1)
 	go func() {
-		for update := range adminUpdates {
-			// Обработка обновлений для админского бота
-			go handlers.HandleAdminUpdate(adminBot, update)
-		}
+		defer background.Done()
+		jobs.Run(ctx)
 	}()
 
-	// Блокировка главного потока
-	select {}
+	// Ожидаем сигнал завершения
+	<-ctx.Done()
+	stop()
+	log.Println("Получен сигнал завершения, останавливаем бота...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Dispatcher.ShutdownTimeout)
+	defer cancel()
+	// Сначала перестаём принимать новые обновления (снимаем webhook / останавливаем polling);
+	// диспетчер тем временем дочитывает уже принятые обновления, пока каналы не закроются,
+	// затем дожидаемся их обработчиков.
+	if err := receiver.Stop(shutdownCtx); err != nil {
+		log.Printf("Ошибка остановки получения обновлений: %v", err)
+	}
+	handlersDone := true
+	if err := d.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Не все обработчики завершились за %s: %v", cfg.Dispatcher.ShutdownTimeout, err)
+		handlersDone = false
+	}
+	// ctx уже отменён: фоновые задачи выходят, а выполняемая задача планировщика
+	// прерывается. Её транзакция откатывается, задача остаётся ожидающей и
+	// выполнится заново после перезапуска.
+	background.Wait()
+	if !handlersDone {
+		// Обработчики ещё работают с базой: не закрываем её под ними, SQLite
+		// закроется вместе с процессом.
+		log.Println("База данных не закрыта: остались незавершённые обработчики")
+		return
+	}
+	if err := store.Close(); err != nil {
+		log.Printf("Ошибка закрытия базы данных: %v", err)
+	}
+	log.Println("Бот остановлен")
+}
+
+// printPendingMigrations выводит миграции, которые будут применены при следующем запуске.
+// База открывается только для чтения и не изменяется.
+func printPendingMigrations(dsn string) {
+	conn, err := db.OpenReadOnly(dsn)
+	if err != nil {
+		log.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	store := db.NewStore(conn)
+	defer store.Close()
+	pending, err := store.PendingMigrations(context.Background())
+	if err != nil {
+		log.Fatalf("Ошибка проверки миграций: %v", err)
+	}
+	if len(pending) == 0 {
+		fmt.Println("Схема базы данных актуальна, ожидающих миграций нет.")
+		return
+	}
+	fmt.Printf("Ожидающие миграции (%d):\n", len(pending))
+	for _, m := range pending {
+		fmt.Printf("\n-- %04d_%s\n%s\n", m.Version, m.Name, strings.TrimSpace(m.SQL))
+		if m.Step != nil {
+			fmt.Println("-- + шаг миграции на Go")
+		}
+	}
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Статусы аукциона.
+const (
+	AuctionOpen      = "open"      // принимает ставки
+	AuctionSold      = "sold"      // лот передан победителю
+	AuctionUnsold    = "unsold"    // ставок не было, лот возвращён продавцу
+	AuctionCancelled = "cancelled" // отменён администратором
+)
+
+// Auction – аукцион на Quantity штук предмета ItemID.
+// Пока аукцион открыт, лот и лидирующая ставка хранятся у бота:
+// предмет уже изъят у продавца, CurrentBid уже списана с LeaderID.
+type Auction struct {
+	ID         int
+	SellerID   int // ID анкеты продавца; 0 – лот администрации
+	ItemID     int
+	ItemName   string // название предмета (заполняется при чтении)
+	Quantity   int
+	Currency   string
+	StartPrice int
+	CurrentBid int
+	LeaderID   int // ID анкеты лидера; 0 – ставок ещё нет
+	Status     string
+	EndsAt     time.Time
+	CreatedAt  time.Time
+	ClosedAt   time.Time // нулевое, пока аукцион открыт
+}
+
+// Bid – ставка на аукционе.
+type Bid struct {
+	ID        int
+	AuctionID int
+	ProfileID int
+	Amount    int
+	CreatedAt time.Time
+}
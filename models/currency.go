@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Currency – валюта из справочника currencies.
+// Code – неизменяемый код, по которому валюта хранится в балансах, журнале и событиях.
+type Currency struct {
+	Code      string
+	Name      string   // отображаемое название, например "Пиастры"
+	Aliases   []string // дополнительные названия для команд, в нижнем регистре
+	Icon      string
+	Retired   bool // выведена из оборота: не используется в новых начислениях и событиях
+	CreatedAt time.Time
+}
+
+// Label – название валюты с иконкой для сообщений.
+func (c *Currency) Label() string {
+	return currencyLabel(c.Icon, c.Name)
+}
+
+// Balance – баланс профиля в одной валюте.
+type Balance struct {
+	Currency string // код валюты
+	Name     string
+	Icon     string
+	Amount   int
+}
+
+// Label – название валюты баланса с иконкой.
+func (b Balance) Label() string {
+	return currencyLabel(b.Icon, b.Name)
+}
+
+func currencyLabel(icon, name string) string {
+	if icon == "" {
+		return name
+	}
+	return icon + " " + name
+}
@@ -0,0 +1,17 @@
+package models
+
+// DailyReward – ступень таблицы ежедневных наград: начиная с дня серии Day
+// выдаётся Amount валюты Currency.
+type DailyReward struct {
+	Day      int
+	Currency string
+	Amount   int
+}
+
+// DailyStreak – серия ежедневных наград персонажа.
+type DailyStreak struct {
+	ProfileID int
+	Streak    int    // текущая серия на момент LastDay
+	Best      int    // лучшая серия
+	LastDay   string // игровые сутки последней награды, "2006-01-02"; "" – наград не было
+}
@@ -4,10 +4,54 @@ import "time"
 
 // Event описывает событие начисления валюты.
 type Event struct {
-	ID           int       `json:"id"`
-	Name         string    `json:"name"`
-	CurrencyType string    `json:"currency_type"` // Например, "piastres" или "oblomki"
-	Amount       int       `json:"amount"`        // Сумма валюты, которую надо начислить
-	Active       bool      `json:"active"`        // Флаг активности события
-	CreatedAt    time.Time `json:"created_at"`    // Дата создания события
+	ID           int           `json:"id"`
+	Name         string        `json:"name"`
+	CurrencyType string        `json:"currency_type"` // Код валюты из справочника, например "piastres"
+	Amount       int           `json:"amount"`        // Сумма валюты, которую надо начислить
+	Active       bool          `json:"active"`        // Флаг активности события
+	CreatedAt    time.Time     `json:"created_at"`    // Дата создания события
+	StartsAt     time.Time     `json:"starts_at"`     // Время начала; нулевое – событие открыто сразу после создания
+	EndsAt       time.Time     `json:"ends_at"`       // Время автоматического закрытия; нулевое – без срока
+	ClosedAt     time.Time     `json:"closed_at"`     // Время закрытия; нулевое, пока событие открыто
+	RemindBefore time.Duration `json:"remind_before"` // За сколько до начала разослать напоминание; 0 – без напоминания
+	// Секрет кода отметки; пустой – отметиться можно без кода. Сам код видят только администраторы.
+	CheckinSecret string        `json:"-"`
+	CheckinRotate time.Duration `json:"checkin_rotate"` // Как часто меняется код; 0 – код постоянный
+	Capacity      int           `json:"capacity"`       // Число мест; 0 – без ограничения, сверх мест – лист ожидания
+	TemplateID    int           `json:"template_id"`    // Шаблон, по которому создано событие; 0 – создано вручную
+}
+
+// CheckinAttempts – неверные коды отметки пользователя на событии.
+type CheckinAttempts struct {
+	EventID     int
+	TelegramID  int64
+	Failures    int       // Неверных кодов подряд
+	LockedUntil time.Time // До какого времени отметка заблокирована; нулевое – не заблокирована
+}
+
+// EventMessage – объявление о событии, разосланное пользователю с кнопками участия.
+type EventMessage struct {
+	EventID   int
+	ChatID    int64
+	MessageID int
+	Title     string // Заголовок объявления, например "Новое событие"
+}
+
+// EventTemplate – шаблон повторяющегося события: по расписанию Schedule из него
+// создаётся событие, которое начинается в момент повтора и длится Duration.
+type EventTemplate struct {
+	ID            int
+	Name          string
+	CurrencyType  string
+	Amount        int
+	Schedule      string        // правило повторения, например "weekly mon 18:00"
+	Duration      time.Duration // сколько длится каждое событие
+	RemindBefore  time.Duration // за сколько до начала напомнить; событие создаётся в это же время
+	Checkin       bool          // отметка по коду; у каждого события свой код
+	CheckinRotate time.Duration
+	Capacity      int
+	Paused        bool
+	NextRunAt     time.Time // начало ближайшего ещё не созданного события
+	LastEventID   int       // последнее созданное событие; 0 – событий ещё не было
+	CreatedAt     time.Time
 }
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ExchangeRate – курс обмена: за FromAmount единиц FromCurrency выдаётся
+// ToAmount единиц ToCurrency. ToAmount = 0 – обмен по направлению закрыт.
+type ExchangeRate struct {
+	ID           int
+	FromCurrency string
+	ToCurrency   string
+	FromAmount   int
+	ToAmount     int
+	SetBy        int64 // Telegram ID администратора
+	CreatedAt    time.Time
+}
+
+// Статусы обмена валют.
+const (
+	ExchangePending   = "pending"   // ждёт подтверждения
+	ExchangeCompleted = "completed" // валюта обменяна
+	ExchangeCancelled = "cancelled" // отменён игроком, по таймауту или из-за смены курса
+)
+
+// Exchange – обмен валюты игроком по курсу RateID.
+type Exchange struct {
+	ID           int
+	ProfileID    int
+	RateID       int
+	FromCurrency string
+	ToCurrency   string
+	Amount       int // списывается в FromCurrency
+	Received     int // зачисляется в ToCurrency, уже за вычетом спреда
+	Fee          int // спред в ToCurrency
+	Status       string
+	CreatedAt    time.Time
+	CompletedAt  time.Time // нулевое, пока обмен не выполнен
+}
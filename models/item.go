@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Item – предмет из справочника items.
+type Item struct {
+	ID          int
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
+
+// InventoryItem – запись инвентаря персонажа.
+// ItemID равен 0 у записей без предмета: например, текст старого инвентаря,
+// перенесённый при переходе на структурированный инвентарь, хранится в Note.
+type InventoryItem struct {
+	ID       int
+	ItemID   int
+	Name     string // название предмета из справочника
+	Quantity int
+	Note     string
+}
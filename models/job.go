@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Статусы отложенной задачи.
+const (
+	JobPending = "pending" // ждёт RunAt
+	JobDone    = "done"    // выполнена
+	JobFailed  = "failed"  // не выполнилась после всех попыток
+)
+
+// Job – отложенная задача планировщика. Kind выбирает обработчик,
+// RefID – объект, к которому относится задача (например, ID аукциона).
+type Job struct {
+	ID        int
+	Kind      string
+	RefID     int64
+	RunAt     time.Time
+	Status    string
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+}
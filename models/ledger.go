@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Источники операций в журнале валюты.
+const (
+	LedgerSourceEvent    = "event"    // SourceID – ID события
+	LedgerSourceAdmin    = "admin"    // SourceID – ID начисления администратора
+	LedgerSourceSystem   = "system"   // начальные балансы и служебные операции
+	LedgerSourceTransfer = "transfer" // SourceID – ID перевода
+	LedgerSourceShop     = "shop"     // SourceID – ID товара
+	LedgerSourceTrade    = "trade"    // SourceID – ID обмена
+	LedgerSourceAuction  = "auction"  // SourceID – ID аукциона
+	LedgerSourceExchange = "exchange" // SourceID – ID обмена валют
+	LedgerSourceStipend  = "stipend"  // SourceID – ID стипендии
+	LedgerSourceDaily    = "daily"    // SourceID – день серии
+	LedgerSourceFine     = "fine"     // SourceID – Telegram ID администратора
+)
+
+// LedgerEntry – запись журнала изменений баланса.
+type LedgerEntry struct {
+	ID           int
+	ProfileID    int
+	Currency     string // код валюты, например "piastres"
+	CurrencyName string // отображаемое название валюты (заполняется при чтении журнала)
+	Delta        int    // изменение баланса (отрицательное – списание)
+	BalanceAfter int    // баланс после операции
+	Reason       string
+	SourceType   string
+	SourceID     int64
+	CreatedAt    time.Time
+}
+
+// AdminGrant – начисление или списание администратором. Операция журнала
+// ссылается на него через SourceID.
+type AdminGrant struct {
+	ID        int
+	ProfileID int
+	Currency  string
+	Amount    int
+	AdminID   int64
+	Reason    string
+	CreatedAt time.Time
+}
+
+// LedgerDrift – расхождение журнала с источником операций: Recorded – сумма
+// операций журнала по источнику, Expected – сколько должно быть по источнику
+// (награда за участие в событии или сумма начисления администратора).
+type LedgerDrift struct {
+	ProfileID  int
+	Currency   string
+	SourceType string
+	SourceID   int64
+	Recorded   int
+	Expected   int
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Profile описывает анкету персонажа.
 // Поле ID – уникальный номер анкеты (из базы),
 // Username – имя пользователя в Telegram (видно только администраторам).
@@ -11,11 +13,46 @@ type Profile struct {
 	Age        int
 	Height     float64
 	Weight     float64
-	Inventory  string
 	Photo      string // file_id или URL фотографии
 	Rank       string
 	Team       string
-	Race       string // Новое поле: раса
-	Piastres   int
-	Oblomki    int
+	Race       string          // Новое поле: раса
+	Balances   []Balance       // балансы по валютам (только чтение, изменяются через журнал)
+	Items      []InventoryItem // инвентарь (только чтение, изменяется через Store)
+
+	// StartingInventory – описание инвентаря, введённое при регистрации.
+	// При создании профиля сохраняется в инвентарь как заметка.
+	StartingInventory string `json:"Inventory"`
+}
+
+// Balance возвращает баланс профиля в валюте с кодом code.
+func (p *Profile) Balance(code string) int {
+	for _, b := range p.Balances {
+		if b.Currency == code {
+			return b.Amount
+		}
+	}
+	return 0
+}
+
+// Debts возвращает отрицательные балансы профиля – долги по валютам.
+func (p *Profile) Debts() []Balance {
+	var debts []Balance
+	for _, b := range p.Balances {
+		if b.Amount < 0 {
+			debts = append(debts, b)
+		}
+	}
+	return debts
+}
+
+// RegistrationState – черновик анкеты, заполняемой в диалоге регистрации.
+// Хранится в базе, чтобы переживать перезапуск бота.
+type RegistrationState struct {
+	TelegramID  int64
+	ChatID      int64
+	CurrentStep int
+	Profile     *Profile
+	UpdatedAt   time.Time // время последнего ответа пользователя
+	Reminded    bool      // отправлено ли напоминание о незавершённой регистрации
 }
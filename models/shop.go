@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// ShopStockUnlimited – запас товара без ограничения.
+const ShopStockUnlimited = -1
+
+// Price – сумма в одной валюте.
+type Price struct {
+	Currency string // код валюты
+	Amount   int
+}
+
+// ShopItem – товар магазина.
+// Rank, Team и Race ограничивают круг покупателей; пустое значение – без ограничения.
+type ShopItem struct {
+	ID          int
+	ItemID      int // предмет справочника, который получает покупатель
+	Name        string
+	Description string
+	Prices      []Price // при покупке списываются все суммы
+	Stock       int     // оставшееся количество или ShopStockUnlimited
+	Rank        string
+	Team        string
+	Race        string
+	CreatedAt   time.Time
+}
+
+// Статусы заказа в магазине.
+const (
+	ShopOrderPending   = "pending"   // ждёт подтверждения
+	ShopOrderCompleted = "completed" // товар куплен
+	ShopOrderCancelled = "cancelled" // отменён игроком, по таймауту или из-за смены цены
+)
+
+// ShopOrder – покупка товара ShopItemID, ожидающая подтверждения.
+type ShopOrder struct {
+	ID          int
+	ProfileID   int
+	ShopItemID  int
+	Price       string // показанная цена в виде "валюта:сумма,..." – см. handlers.priceKey
+	Status      string
+	CreatedAt   time.Time
+	CompletedAt time.Time // нулевое, пока покупка не выполнена
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Stipend – периодическая выплата Amount в валюте Currency каждому персонажу
+// с рангом Rank и командой Team (пустое значение – без ограничения).
+type Stipend struct {
+	ID        int
+	Rank      string
+	Team      string
+	Currency  string
+	Amount    int
+	Schedule  string // правило повторения, например "weekly mon 09:00"
+	Paused    bool
+	NextRunAt time.Time
+	LastRunAt time.Time // нулевое, если выплат ещё не было
+	CreatedAt time.Time
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Статусы обмена.
+const (
+	TradeOpen      = "open"      // участники добавляют предложения и подтверждают
+	TradeCompleted = "completed" // обмен выполнен
+	TradeCancelled = "cancelled" // отменён участником или по таймауту, залог возвращён
+)
+
+// Trade – обмен предметами и валютой между двумя персонажами.
+// Обмен выполняется, когда оба участника подтвердили текущие предложения;
+// любое изменение предложений сбрасывает подтверждения.
+type Trade struct {
+	ID                 int
+	InitiatorID        int // ID анкеты, открывшей обмен
+	PartnerID          int
+	Status             string
+	Revision           int // растёт при каждом изменении предложений
+	InitiatorConfirmed bool
+	PartnerConfirmed   bool
+	CancelReason       string
+	Lines              []TradeLine
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	ClosedAt           time.Time // нулевое, пока обмен открыт
+}
+
+// TradeLine – то, что участник ProfileID отдаёт в обмене:
+// Amount штук предмета ItemID или Amount единиц валюты Currency.
+type TradeLine struct {
+	ProfileID int
+	ItemID    int
+	ItemName  string // название предмета (заполняется при чтении)
+	Currency  string
+	Amount    int
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Статусы перевода.
+const (
+	TransferPending   = "pending"   // ждёт подтверждения отправителем
+	TransferCompleted = "completed" // валюта переведена
+	TransferCancelled = "cancelled" // отменён отправителем или не прошёл проверки
+)
+
+// Transfer – перевод валюты от одного персонажа другому.
+type Transfer struct {
+	ID            int
+	FromProfileID int
+	ToProfileID   int
+	Currency      string // код валюты
+	Amount        int    // сумма, которую получит получатель
+	Fee           int    // комиссия, списывается с отправителя сверх суммы
+	Comment       string
+	Status        string
+	CreatedAt     time.Time
+	CompletedAt   time.Time // нулевое, пока перевод не выполнен
+}
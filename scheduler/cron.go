@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMaxDays – на сколько дней вперёд Next ищет срабатывание cron-правила:
+// с запасом на правила вроде "29 февраля", которые срабатывают раз в четыре года.
+const cronMaxDays = 5*366 + 1
+
+// cronSpec – разобранное cron-выражение "минуты часы число месяц день-недели".
+// Каждое поле – набор подходящих значений в виде битовой маски.
+type cronSpec struct {
+	expr       string
+	minutes    uint64
+	hours      uint64
+	days       uint64
+	months     uint64
+	weekdays   uint64
+	anyDay     bool // число месяца "*"
+	anyWeekday bool // день недели "*"
+}
+
+// cronWeekdayNames – дни недели, которые понимает поле дня недели (0 и 7 – воскресенье).
+var cronWeekdayNames = func() map[string]int {
+	names := make(map[string]int, len(weekdayNames))
+	for name, d := range weekdayNames {
+		names[name] = int(d)
+	}
+	return names
+}()
+
+// parseCron разбирает cron-выражение из пяти полей. Поле – "*", число, диапазон
+// "a-b", шаг "*/n" или "a-b/n" и их списки через запятую, как в crontab.
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("неверное cron-выражение %q: нужно 5 полей – минуты, часы, число, месяц, день недели", expr)
+	}
+	c := &cronSpec{
+		expr:       strings.Join(fields, " "),
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	var err error
+	if c.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("минуты: %w", err)
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("часы: %w", err)
+	}
+	if c.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("число месяца: %w", err)
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12, nil); err != nil {
+		return nil, fmt.Errorf("месяц: %w", err)
+	}
+	if c.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return nil, fmt.Errorf("день недели: %w", err)
+	}
+	if c.weekdays&(1<<7) != 0 {
+		c.weekdays |= 1 // 7 – тоже воскресенье
+	}
+	return c, nil
+}
+
+// parseCronField разбирает одно поле cron-выражения со значениями от lo до hi.
+func parseCronField(s string, lo, hi int, names map[string]int) (uint64, error) {
+	value := func(v string) (int, error) {
+		if n, ok := names[v]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < lo || n > hi {
+			return 0, fmt.Errorf("неверное значение %q: ожидается от %d до %d", v, lo, hi)
+		}
+		return n, nil
+	}
+
+	var mask uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("неверный шаг %q", stepStr)
+			}
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = value(a); err != nil {
+				return 0, err
+			}
+			to = from
+			if isRange {
+				if to, err = value(b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				to = hi // "a/n" – от a до конца с шагом n
+			}
+			if from > to {
+				return 0, fmt.Errorf("неверный диапазон %q", rng)
+			}
+		}
+		for v := from; v <= to; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+// matchesDay проверяет, подходит ли день t под число месяца, месяц и день недели.
+// Как в crontab, если заданы и число, и день недели, подходит любое из них.
+func (c *cronSpec) matchesDay(t time.Time) bool {
+	if c.months&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	day := c.days&(1<<t.Day()) != 0
+	weekday := c.weekdays&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// next возвращает ближайший после after момент срабатывания в часовом поясе after
+// или нулевое время, если выражение не срабатывает (например, "0 0 31 2 *").
+func (c *cronSpec) next(after time.Time) time.Time {
+	y, m, d := after.Date()
+	for i := 0; i <= cronMaxDays; i++ {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, after.Location())
+		if !c.matchesDay(day) {
+			continue
+		}
+		for h := 0; h < 24; h++ {
+			if c.hours&(1<<h) == 0 {
+				continue
+			}
+			for mi := 0; mi < 60; mi++ {
+				if c.minutes&(1<<mi) == 0 {
+					continue
+				}
+				t := time.Date(day.Year(), day.Month(), day.Day(), h, mi, 0, 0, after.Location())
+				if t.After(after) {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
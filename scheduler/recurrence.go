@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weekdayNames – названия дней недели, которые понимает ParseRecurrence.
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+}
+
+// weekdayCodes – короткие названия дней недели для Recurrence.String.
+var weekdayCodes = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Recurrence – правило повторения: каждый день, по дням недели или по числу
+// месяца в заданное время либо cron-выражение. Время отсчитывается в часовом
+// поясе, переданном в Next.
+type Recurrence struct {
+	Weekdays []time.Weekday // еженедельное правило: дни недели
+	MonthDay int            // ежемесячное правило: число месяца (1–31)
+	Hour     int
+	Minute   int
+	cron     *cronSpec // правило cron; остальные поля тогда не используются
+}
+
+// ParseRecurrence разбирает правило вида "daily 09:00", "weekly mon,thu 18:30"
+// или "monthly 1 12:00". Вместо ключевых слов можно писать "ежедневно",
+// "еженедельно", "ежемесячно", дни недели – по-русски (пн, вт, ...).
+// Время необязательно, по умолчанию 00:00. Правило "cron 0 18 * * mon-fri"
+// задаётся cron-выражением из пяти полей, как в crontab.
+func ParseRecurrence(s string) (Recurrence, error) {
+	var r Recurrence
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return r, fmt.Errorf("пустое расписание")
+	}
+	if fields[0] == "cron" {
+		c, err := parseCron(strings.Join(fields[1:], " "))
+		if err != nil {
+			return r, err
+		}
+		r.cron = c
+		if r.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+			return r, fmt.Errorf("расписание %q никогда не срабатывает", s)
+		}
+		return r, nil
+	}
+	if last := fields[len(fields)-1]; strings.Contains(last, ":") {
+		t, err := time.Parse("15:04", last)
+		if err != nil {
+			return r, fmt.Errorf("неверное время %q: ожидается ЧЧ:ММ", last)
+		}
+		r.Hour, r.Minute = t.Hour(), t.Minute()
+		fields = fields[:len(fields)-1]
+	}
+
+	switch {
+	case len(fields) == 1 && (fields[0] == "daily" || fields[0] == "ежедневно"):
+	case len(fields) == 2 && (fields[0] == "weekly" || fields[0] == "еженедельно"):
+		seen := make(map[time.Weekday]bool)
+		for _, name := range strings.Split(fields[1], ",") {
+			d, ok := weekdayNames[strings.TrimSpace(name)]
+			if !ok {
+				return r, fmt.Errorf("неизвестный день недели %q: используйте mon–sun или пн–вс", name)
+			}
+			if !seen[d] {
+				seen[d] = true
+				r.Weekdays = append(r.Weekdays, d)
+			}
+		}
+	case len(fields) == 2 && (fields[0] == "monthly" || fields[0] == "ежемесячно"):
+		day, err := strconv.Atoi(fields[1])
+		if err != nil || day < 1 || day > 31 {
+			return r, fmt.Errorf("неверное число месяца %q: ожидается от 1 до 31", fields[1])
+		}
+		r.MonthDay = day
+	default:
+		return r, fmt.Errorf("неверное расписание %q: используйте daily [ЧЧ:ММ], weekly <дни> [ЧЧ:ММ], monthly <число> [ЧЧ:ММ] или cron <выражение>", s)
+	}
+	return r, nil
+}
+
+// String возвращает правило в виде, который понимает ParseRecurrence.
+func (r Recurrence) String() string {
+	if r.cron != nil {
+		return "cron " + r.cron.expr
+	}
+	at := fmt.Sprintf("%02d:%02d", r.Hour, r.Minute)
+	switch {
+	case len(r.Weekdays) > 0:
+		days := make([]string, len(r.Weekdays))
+		for i, d := range r.Weekdays {
+			days[i] = weekdayCodes[d]
+		}
+		return "weekly " + strings.Join(days, ",") + " " + at
+	case r.MonthDay > 0:
+		return fmt.Sprintf("monthly %d %s", r.MonthDay, at)
+	default:
+		return "daily " + at
+	}
+}
+
+// Next возвращает ближайший после after момент срабатывания правила
+// в часовом поясе after. Если в месяце меньше дней, чем MonthDay,
+// ежемесячное правило срабатывает в последний день месяца.
+func (r Recurrence) Next(after time.Time) time.Time {
+	if r.cron != nil {
+		return r.cron.next(after)
+	}
+	y, m, d := after.Date()
+	for i := 0; i <= 366; i++ {
+		t := time.Date(y, m, d+i, r.Hour, r.Minute, 0, 0, after.Location())
+		if t.After(after) && r.matches(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matches проверяет, подходит ли день t под правило.
+func (r Recurrence) matches(t time.Time) bool {
+	switch {
+	case len(r.Weekdays) > 0:
+		for _, d := range r.Weekdays {
+			if t.Weekday() == d {
+				return true
+			}
+		}
+		return false
+	case r.MonthDay > 0:
+		lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		return t.Day() == min(r.MonthDay, lastDay)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,179 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("нет часового пояса %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseRecurrenceString(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"daily 09:00", "daily 09:00"},
+		{"ежедневно", "daily 00:00"},
+		{"weekly пн,чт 18:30", "weekly mon,thu 18:30"},
+		{"Weekly MON,mon,fri 7:05", "weekly mon,fri 07:05"},
+		{"monthly 31 12:00", "monthly 31 12:00"},
+		{"ежемесячно 1", "monthly 1 00:00"},
+		{"cron  0 18 * * MON-FRI", "cron 0 18 * * mon-fri"},
+		{"cron */15 9-17 1,15 * *", "cron */15 9-17 1,15 * *"},
+	}
+	for _, tt := range tests {
+		r, err := ParseRecurrence(tt.in)
+		if err != nil {
+			t.Errorf("ParseRecurrence(%q): %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("ParseRecurrence(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		again, err := ParseRecurrence(r.String())
+		if err != nil || again.String() != tt.want {
+			t.Errorf("ParseRecurrence(%q) не разбирает собственный String(): %v", r.String(), err)
+		}
+	}
+}
+
+func TestParseRecurrenceInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"hourly",
+		"daily 25:00",
+		"daily 9",
+		"weekly",
+		"weekly xx 10:00",
+		"monthly 0",
+		"monthly 32",
+		"monthly 1 2 3",
+		"cron",
+		"cron 1 2 3",
+		"cron 0 0 * * * *",
+		"cron 60 * * * *",
+		"cron * 24 * * *",
+		"cron * * 0 * *",
+		"cron * * * 13 *",
+		"cron * * * * 8",
+		"cron */0 * * * *",
+		"cron 5-1 * * * *",
+		"cron a * * * *",
+		"cron 0 0 31 2 *", // никогда не срабатывает
+		"cron 0 0 30,31 2 *",
+	} {
+		if r, err := ParseRecurrence(in); err == nil {
+			t.Errorf("ParseRecurrence(%q) = %q, want error", in, r.String())
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+	const layout = "2006-01-02 15:04 -0700"
+	tests := []struct {
+		name  string
+		rule  string
+		after time.Time
+		want  []string
+	}{
+		{
+			name:  "daily через переход на летнее время",
+			rule:  "daily 09:00",
+			after: time.Date(2026, 3, 27, 10, 0, 0, 0, berlin),
+			want:  []string{"2026-03-28 09:00 +0100", "2026-03-29 09:00 +0200", "2026-03-30 09:00 +0200"},
+		},
+		{
+			name:  "cron через переход на зимнее время",
+			rule:  "cron 30 9 * * *",
+			after: time.Date(2026, 10, 24, 0, 0, 0, 0, berlin),
+			want:  []string{"2026-10-24 09:30 +0200", "2026-10-25 09:30 +0100", "2026-10-26 09:30 +0100"},
+		},
+		{
+			name:  "час, которого нет при переходе на летнее время",
+			rule:  "daily 02:30",
+			after: time.Date(2026, 3, 28, 12, 0, 0, 0, berlin),
+			want:  []string{"2026-03-29 03:30 +0200", "2026-03-30 02:30 +0200", "2026-03-31 02:30 +0200"},
+		},
+		{
+			name:  "weekly",
+			rule:  "weekly mon,thu 18:30",
+			after: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			want:  []string{"2026-10-19 18:30 +0000", "2026-10-22 18:30 +0000", "2026-10-26 18:30 +0000"},
+		},
+		{
+			name:  "weekly в день правила до и после времени",
+			rule:  "weekly mon 18:30",
+			after: time.Date(2026, 10, 19, 18, 30, 0, 0, time.UTC),
+			want:  []string{"2026-10-26 18:30 +0000", "2026-11-02 18:30 +0000"},
+		},
+		{
+			name:  "monthly 31 в коротких месяцах – последний день",
+			rule:  "monthly 31 12:00",
+			after: time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC),
+			want:  []string{"2026-02-28 12:00 +0000", "2026-03-31 12:00 +0000", "2026-04-30 12:00 +0000"},
+		},
+		{
+			name:  "monthly 31 в високосном феврале",
+			rule:  "monthly 31 12:00",
+			after: time.Date(2028, 1, 31, 13, 0, 0, 0, time.UTC),
+			want:  []string{"2028-02-29 12:00 +0000", "2028-03-31 12:00 +0000"},
+		},
+		{
+			name:  "cron: число и день недели – подходит любое",
+			rule:  "cron 0 12 13 * fri",
+			after: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2026-10-02 12:00 +0000", "2026-10-09 12:00 +0000", "2026-10-13 12:00 +0000", "2026-10-16 12:00 +0000"},
+		},
+		{
+			name:  "cron: только число месяца",
+			rule:  "cron 0 12 13 * *",
+			after: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2026-10-13 12:00 +0000", "2026-11-13 12:00 +0000", "2026-12-13 12:00 +0000"},
+		},
+		{
+			name:  "cron: только день недели",
+			rule:  "cron 0 12 * * 5",
+			after: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2026-10-02 12:00 +0000", "2026-10-09 12:00 +0000", "2026-10-16 12:00 +0000"},
+		},
+		{
+			name:  "cron: 7 – воскресенье",
+			rule:  "cron 0 8 * * 7",
+			after: time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC),
+			want:  []string{"2026-10-25 08:00 +0000", "2026-11-01 08:00 +0000"},
+		},
+		{
+			name:  "cron: шаги и диапазоны",
+			rule:  "cron */20 9-10 * * *",
+			after: time.Date(2026, 10, 1, 10, 30, 0, 0, time.UTC),
+			want:  []string{"2026-10-01 10:40 +0000", "2026-10-02 09:00 +0000", "2026-10-02 09:20 +0000"},
+		},
+		{
+			name:  "cron: 29 февраля",
+			rule:  "cron 0 0 29 2 *",
+			after: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2028-02-29 00:00 +0000", "2032-02-29 00:00 +0000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q): %v", tt.rule, err)
+			}
+			at := tt.after
+			for i, want := range tt.want {
+				next := r.Next(at)
+				if got := next.Format(layout); got != want {
+					t.Fatalf("повтор %d после %s = %s, want %s", i+1, at.Format(layout), got, want)
+				}
+				at = next
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package scheduler выполняет отложенные задачи, сохранённые в базе данных.
+// Задачи переживают перезапуск бота: после старта выполняются все задачи,
+// время которых уже наступило.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"telegram-bot/models"
+)
+
+// maxAttempts – сколько раз выполняется задача, обработчик которой возвращает ошибку.
+const maxAttempts = 5
+
+// LastAttempt сообщает, что это последняя попытка выполнить задачу: если
+// обработчик вернёт ошибку, задача будет отмечена проваленной и не повторится.
+func LastAttempt(job *models.Job) bool {
+	return job.Attempts+1 >= maxAttempts
+}
+
+// batchSize – сколько задач выбирается из базы за один проход.
+const batchSize = 100
+
+// Store – хранилище задач; реализуется db.SQLStore.
+type Store interface {
+	DueJobs(ctx context.Context, now time.Time, limit int) ([]*models.Job, error)
+	CompleteJob(ctx context.Context, job *models.Job) error
+	RetryJob(ctx context.Context, job *models.Job, nextRunAt time.Time, lastError string) error
+}
+
+// Handler выполняет задачу. Ошибка означает, что задачу нужно повторить позже.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// Scheduler раз в interval выполняет наступившие задачи по порядку.
+type Scheduler struct {
+	store    Store
+	interval time.Duration
+	handlers map[string]Handler
+}
+
+// New создаёт планировщик, который проверяет задачи раз в interval.
+func New(store Store, interval time.Duration) *Scheduler {
+	return &Scheduler{store: store, interval: interval, handlers: make(map[string]Handler)}
+}
+
+// Handle регистрирует обработчик задач вида kind. Обработчики регистрируются до Run.
+func (s *Scheduler) Handle(kind string, h Handler) {
+	s.handlers[kind] = h
+}
+
+// Run выполняет задачи, пока не отменён ctx.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.RunDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue выполняет все задачи, время которых наступило к now.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) {
+	jobs, err := s.store.DueJobs(ctx, now, batchSize)
+	if err != nil {
+		log.Printf("Ошибка получения отложенных задач: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		s.run(ctx, job)
+	}
+}
+
+// run выполняет одну задачу и сохраняет результат.
+func (s *Scheduler) run(ctx context.Context, job *models.Job) {
+	h, ok := s.handlers[job.Kind]
+	if !ok {
+		log.Printf("Нет обработчика для задачи %d вида %q", job.ID, job.Kind)
+		if err := s.store.RetryJob(ctx, job, time.Time{}, "нет обработчика"); err != nil {
+			log.Printf("Ошибка сохранения задачи %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	err := s.call(ctx, h, job)
+	if err == nil {
+		if err := s.store.CompleteJob(ctx, job); err != nil {
+			log.Printf("Ошибка сохранения задачи %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	var next time.Time
+	if !LastAttempt(job) {
+		next = time.Now().Add(time.Duration(job.Attempts+1) * time.Minute)
+		log.Printf("Задача %d (%s %d) не выполнена, повтор в %s: %v", job.ID, job.Kind, job.RefID, next.Format(time.TimeOnly), err)
+	} else {
+		log.Printf("Задача %d (%s %d) не выполнена после %d попыток: %v", job.ID, job.Kind, job.RefID, maxAttempts, err)
+	}
+	if err := s.store.RetryJob(ctx, job, next, err.Error()); err != nil {
+		log.Printf("Ошибка сохранения задачи %d: %v", job.ID, err)
+	}
+}
+
+// call вызывает обработчик, превращая панику в ошибку.
+func (s *Scheduler) call(ctx context.Context, h Handler, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Паника в задаче %d: %v\n%s", job.ID, r, debug.Stack())
+			err = fmt.Errorf("паника: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
@@ -0,0 +1,314 @@
+// Package telegramtest – поддельный сервер Telegram Bot API для тестов без сети.
+//
+// Сервер записывает вызовы методов (sendMessage, sendPhoto и т.д.) и отдаёт
+// заранее подготовленные обновления через getUpdates. Бот, созданный через
+// Server.Bot, – обычный *tgbotapi.BotAPI, поэтому его можно передать в
+// handlers.HandleUpdate / handlers.HandleAdminUpdate или в transport.NewPolling.
+//
+//	srv := telegramtest.NewServer()
+//	defer srv.Close()
+//	bot, _ := srv.Bot("1:primary")
+//	handlers.HandleUpdate(ctx, bot, telegramtest.Command(42, "/start"))
+//	texts := srv.Texts(42)
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Call – один записанный вызов метода Bot API.
+type Call struct {
+	Token  string
+	Method string
+	Params map[string]string
+}
+
+// ChatID возвращает chat_id вызова (0, если не задан).
+func (c Call) ChatID() int64 {
+	id, _ := strconv.ParseInt(c.Params["chat_id"], 10, 64)
+	return id
+}
+
+// Server – поддельный Bot API.
+type Server struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	calls     []Call
+	updates   map[string][]tgbotapi.Update // очередь getUpdates по токену
+	nextMsgID int
+	nextUpdID int
+	notify    chan struct{} // сигнал о новом вызове или обновлении
+}
+
+// NewServer запускает поддельный Bot API на локальном порту.
+func NewServer() *Server {
+	s := &Server{
+		updates: make(map[string][]tgbotapi.Update),
+		notify:  make(chan struct{}),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Close останавливает сервер.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Endpoint – формат адреса API для tgbotapi.NewBotAPIWithAPIEndpoint.
+func (s *Server) Endpoint() string {
+	return s.srv.URL + "/bot%s/%s"
+}
+
+// Bot создаёт клиента Bot API, направленного на этот сервер.
+// Токен должен иметь вид "<id>:<secret>"; id становится ID бота.
+func (s *Server) Bot(token string) (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithAPIEndpoint(token, s.Endpoint())
+}
+
+// PushUpdate ставит обновление в очередь getUpdates бота с токеном token.
+// UpdateID назначается автоматически.
+func (s *Server) PushUpdate(token string, update tgbotapi.Update) {
+	s.mu.Lock()
+	s.nextUpdID++
+	update.UpdateID = s.nextUpdID
+	s.updates[token] = append(s.updates[token], update)
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// Calls возвращает копию всех записанных вызовов (кроме getMe и getUpdates).
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsTo возвращает вызовы метода method.
+func (s *Server) CallsTo(method string) []Call {
+	var out []Call
+	for _, c := range s.Calls() {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Texts возвращает тексты sendMessage и подписи sendPhoto, отправленные в чат chatID.
+func (s *Server) Texts(chatID int64) []string {
+	var out []string
+	for _, c := range s.Calls() {
+		if c.ChatID() != chatID {
+			continue
+		}
+		switch c.Method {
+		case "sendMessage":
+			out = append(out, c.Params["text"])
+		case "sendPhoto":
+			out = append(out, c.Params["caption"])
+		}
+	}
+	return out
+}
+
+// Reset очищает записанные вызовы.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.calls = nil
+	s.mu.Unlock()
+}
+
+// WaitCalls ждёт, пока будет записано не меньше n вызовов, или истечёт timeout.
+// Нужен, когда обновления обрабатываются асинхронно (через getUpdates).
+func (s *Server) WaitCalls(n int, timeout time.Duration) []Call {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		if len(s.calls) >= n {
+			calls := append([]Call(nil), s.calls...)
+			s.mu.Unlock()
+			return calls
+		}
+		notify := s.notify
+		s.mu.Unlock()
+		select {
+		case <-notify:
+		case <-deadline:
+			return s.Calls()
+		}
+	}
+}
+
+// broadcast будит всех, кто ждёт в WaitCalls или getUpdates.
+func (s *Server) broadcast() {
+	s.mu.Lock()
+	close(s.notify)
+	s.notify = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// serve разбирает запрос вида /bot<token>/<method>.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/bot")
+	token, method, ok := strings.Cut(path, "/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if err := r.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params := make(map[string]string, len(r.Form))
+	for k, v := range r.Form {
+		params[k] = v[0]
+	}
+
+	switch method {
+	case "getMe":
+		id, _, _ := strings.Cut(token, ":")
+		botID, _ := strconv.ParseInt(id, 10, 64)
+		writeResult(w, tgbotapi.User{ID: botID, IsBot: true, FirstName: "Test", UserName: "test_" + id + "_bot"})
+	case "getUpdates":
+		writeResult(w, s.getUpdates(r, token, params))
+	default:
+		s.record(Call{Token: token, Method: method, Params: params})
+		switch method {
+		case "sendMessage", "sendPhoto", "editMessageText", "editMessageCaption", "editMessageReplyMarkup":
+			writeResult(w, s.message(method, params))
+		default:
+			// answerCallbackQuery, setWebhook, deleteWebhook и прочие возвращают true.
+			writeResult(w, true)
+		}
+	}
+}
+
+// record сохраняет вызов и будит ожидающих.
+func (s *Server) record(c Call) {
+	s.mu.Lock()
+	s.calls = append(s.calls, c)
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// getUpdates отдаёт обновления с update_id >= offset. Если их нет, ждёт
+// до timeout секунд (но не больше секунды, чтобы тесты не зависали).
+func (s *Server) getUpdates(r *http.Request, token string, params map[string]string) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(params["offset"])
+	wait := time.Second
+	if t, err := strconv.Atoi(params["timeout"]); err == nil && time.Duration(t)*time.Second < wait {
+		wait = time.Duration(t) * time.Second
+	}
+	deadline := time.After(wait)
+	for {
+		s.mu.Lock()
+		queue := s.updates[token]
+		// Подтверждённые обновления (update_id < offset) удаляются, как в Telegram.
+		for len(queue) > 0 && queue[0].UpdateID < offset {
+			queue = queue[1:]
+		}
+		s.updates[token] = queue
+		notify := s.notify
+		s.mu.Unlock()
+		if len(queue) > 0 {
+			return append([]tgbotapi.Update(nil), queue...)
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return []tgbotapi.Update{}
+		case <-r.Context().Done():
+			return []tgbotapi.Update{}
+		}
+	}
+}
+
+// message собирает ответ на отправку/редактирование сообщения.
+func (s *Server) message(method string, params map[string]string) tgbotapi.Message {
+	chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+	msg := tgbotapi.Message{
+		Chat:    &tgbotapi.Chat{ID: chatID},
+		Date:    int(time.Now().Unix()),
+		Text:    params["text"],
+		Caption: params["caption"],
+	}
+	if id, err := strconv.Atoi(params["message_id"]); err == nil {
+		msg.MessageID = id
+	} else {
+		s.mu.Lock()
+		s.nextMsgID++
+		msg.MessageID = s.nextMsgID
+		s.mu.Unlock()
+	}
+	if method == "sendPhoto" {
+		msg.Photo = []tgbotapi.PhotoSize{{FileID: params["photo"]}}
+	}
+	return msg
+}
+
+// writeResult отвечает в формате Bot API {"ok": true, "result": ...}.
+func writeResult(w http.ResponseWriter, result any) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+// writeError отвечает ошибкой в формате Bot API.
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: code, Description: description})
+}
+
+// Command собирает обновление с командой (например, "/attend 3") от пользователя
+// userID в его личном чате.
+func Command(userID int64, text string) tgbotapi.Update {
+	update := Text(userID, text)
+	cmd, _, _ := strings.Cut(text, " ")
+	update.Message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len([]rune(cmd))}}
+	return update
+}
+
+// Callback собирает обновление с нажатием inline-кнопки с данными data
+// под сообщением messageID в личном чате пользователя userID.
+func Callback(userID int64, messageID int, data string) tgbotapi.Update {
+	return tgbotapi.Update{
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:   fmt.Sprintf("cb%d", time.Now().UnixNano()),
+			From: &tgbotapi.User{ID: userID, UserName: fmt.Sprintf("user%d", userID)},
+			Message: &tgbotapi.Message{
+				MessageID: messageID,
+				Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+			},
+			Data: data,
+		},
+	}
+}
+
+// Text собирает обновление с обычным текстовым сообщением от пользователя userID.
+func Text(userID int64, text string) tgbotapi.Update {
+	return tgbotapi.Update{
+		Message: &tgbotapi.Message{
+			MessageID: 1,
+			From:      &tgbotapi.User{ID: userID, UserName: fmt.Sprintf("user%d", userID)},
+			Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+			Date:      int(time.Now().Unix()),
+			Text:      text,
+		},
+	}
+}
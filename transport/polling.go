@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Polling получает обновления через getUpdates (long polling).
+type Polling struct {
+	bots []*tgbotapi.BotAPI
+	chs  []chan tgbotapi.Update
+
+	stop       chan struct{}
+	forwarders sync.WaitGroup
+}
+
+// NewPolling создаёт Receiver для режима long polling.
+func NewPolling() *Polling {
+	return &Polling{stop: make(chan struct{})}
+}
+
+// Listen регистрирует бота; канал начнёт получать обновления после Start.
+func (p *Polling) Listen(bot *tgbotapi.BotAPI, _ string) (tgbotapi.UpdatesChannel, error) {
+	ch := make(chan tgbotapi.Update, bot.Buffer)
+	p.bots = append(p.bots, bot)
+	p.chs = append(p.chs, ch)
+	return ch, nil
+}
+
+// Start снимает webhook, оставшийся с прошлого запуска (иначе getUpdates
+// вернёт ошибку), и запускает long polling для каждого бота.
+func (p *Polling) Start() error {
+	for i, bot := range p.bots {
+		if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			return err
+		}
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		p.forwarders.Add(1)
+		go p.forward(bot.GetUpdatesChan(u), p.chs[i])
+	}
+	return nil
+}
+
+// Stop останавливает long polling и закрывает каналы, не дожидаясь текущего
+// запроса getUpdates: обновления из него ещё не подтверждены Telegram и
+// придут снова после перезапуска.
+func (p *Polling) Stop(ctx context.Context) error {
+	for _, bot := range p.bots {
+		bot.StopReceivingUpdates()
+	}
+	close(p.stop)
+	done := make(chan struct{})
+	go func() {
+		p.forwarders.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// forward перекладывает обновления из канала библиотеки в канал Listen.
+// После Stop дочитывает то, что уже лежит в канале библиотеки: следующий
+// запрос getUpdates подтвердил эти обновления, и больше они не придут.
+func (p *Polling) forward(in tgbotapi.UpdatesChannel, out chan<- tgbotapi.Update) {
+	defer p.forwarders.Done()
+	defer log.Println("Long polling остановлен")
+	defer close(out)
+	for {
+		select {
+		case update, ok := <-in:
+			if !ok {
+				return
+			}
+			out <- update
+		case <-p.stop:
+			for {
+				select {
+				case update, ok := <-in:
+					if !ok {
+						return
+					}
+					out <- update
+				default:
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Package transport доставляет обновления Telegram ботам:
+// через long polling или через встроенный webhook-сервер.
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"telegram-bot/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Receiver – источник обновлений для одного или нескольких ботов.
+type Receiver interface {
+	// Listen регистрирует бота и возвращает канал его обновлений.
+	// path используется только в режиме webhook.
+	Listen(bot *tgbotapi.BotAPI, path string) (tgbotapi.UpdatesChannel, error)
+	// Start начинает получение обновлений для всех зарегистрированных ботов.
+	Start() error
+	// Stop прекращает получение обновлений и закрывает каналы.
+	Stop(ctx context.Context) error
+}
+
+// New создаёт Receiver для режима, выбранного в конфигурации.
+func New(cfg config.TransportConfig) (Receiver, error) {
+	switch cfg.Mode {
+	case config.TransportPolling:
+		return NewPolling(), nil
+	case config.TransportWebhook:
+		return NewWebhook(cfg.Webhook), nil
+	default:
+		return nil, fmt.Errorf("неизвестный режим получения обновлений: %q", cfg.Mode)
+	}
+}
@@ -0,0 +1,162 @@
+package transport
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-bot/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretHeader – заголовок, в котором Telegram передаёт secret_token из setWebhook.
+const secretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Webhook принимает обновления всех ботов одним HTTP-сервером,
+// по отдельному пути на каждого бота.
+type Webhook struct {
+	cfg    config.WebhookConfig
+	mux    *http.ServeMux
+	server *http.Server
+	hooks  []*hook
+	// stopping закрывается в начале Stop: новые обновления больше не принимаются.
+	stopping chan struct{}
+	// mu защищает закрытие stopping и добавление в senders: обработчик,
+	// увидевший открытый stopping, успевает попасть в senders до Wait в Stop.
+	mu sync.Mutex
+	// senders – обработчики, которые сейчас передают обновление в канал бота.
+	// Stop закрывает каналы только после того, как все они вышли.
+	senders sync.WaitGroup
+}
+
+// hook – зарегистрированный бот и канал его обновлений.
+type hook struct {
+	bot  *tgbotapi.BotAPI
+	path string
+	ch   chan tgbotapi.Update
+}
+
+// NewWebhook создаёт Receiver для режима webhook.
+func NewWebhook(cfg config.WebhookConfig) *Webhook {
+	mux := http.NewServeMux()
+	return &Webhook{
+		cfg:      cfg,
+		mux:      mux,
+		stopping: make(chan struct{}),
+		server: &http.Server{
+			Addr:              cfg.Listen,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Listen регистрирует обработчик пути path для бота.
+func (w *Webhook) Listen(bot *tgbotapi.BotAPI, path string) (tgbotapi.UpdatesChannel, error) {
+	h := &hook{bot: bot, path: path, ch: make(chan tgbotapi.Update, bot.Buffer)}
+	w.mux.Handle(path, w.handler(h))
+	w.hooks = append(w.hooks, h)
+	return h.ch, nil
+}
+
+// handler проверяет секрет и передаёт обновление в канал бота.
+func (w *Webhook) handler(h *hook) http.Handler {
+	secret := []byte(w.cfg.SecretToken)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get(secretHeader))
+		if subtle.ConstantTimeCompare(got, secret) != 1 {
+			http.Error(rw, "forbidden", http.StatusForbidden)
+			return
+		}
+		update, err := h.bot.HandleUpdate(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !w.enter() {
+			http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer w.senders.Done()
+		select {
+		case h.ch <- *update:
+			rw.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			// Telegram повторит доставку, если не получит ответ 200.
+			http.Error(rw, "busy", http.StatusServiceUnavailable)
+		case <-w.stopping:
+			http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// enter регистрирует обработчик в senders. Возвращает false, если Stop уже начался.
+func (w *Webhook) enter() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case <-w.stopping:
+		return false
+	default:
+		w.senders.Add(1)
+		return true
+	}
+}
+
+// Start запускает HTTP-сервер и регистрирует webhook каждого бота в Telegram.
+func (w *Webhook) Start() error {
+	errCh := make(chan error, 1)
+	go func() {
+		if err := w.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+	// Даём серверу шанс сообщить об ошибке привязки к адресу до setWebhook.
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	base := strings.TrimRight(w.cfg.PublicURL, "/")
+	for _, h := range w.hooks {
+		params := tgbotapi.Params{}
+		params["url"] = base + h.path
+		params["secret_token"] = w.cfg.SecretToken
+		if _, err := h.bot.MakeRequest("setWebhook", params); err != nil {
+			return err
+		}
+		log.Printf("Webhook для @%s установлен: %s", h.bot.Self.UserName, base+h.path)
+	}
+	return nil
+}
+
+// Stop снимает webhook в Telegram, останавливает HTTP-сервер
+// (дожидаясь текущих запросов) и закрывает каналы обновлений. Каналы
+// закрываются, даже если Shutdown не дождался запросов: обработчики,
+// передающие обновление, выходят по stopping, и Stop ждёт их перед закрытием.
+func (w *Webhook) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	close(w.stopping)
+	w.mu.Unlock()
+	var errs []error
+	for _, h := range w.hooks {
+		if _, err := h.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := w.server.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	w.senders.Wait()
+	for _, h := range w.hooks {
+		close(h.ch)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-bot/config"
+	"telegram-bot/telegramtest"
+)
+
+// post отправляет обновление в обработчик webhook и возвращает код ответа.
+func post(w *Webhook, path string) int {
+	req := httptest.NewRequest(http.MethodPost, path,
+		strings.NewReader(`{"update_id":1,"message":{"message_id":1,"text":"hi","chat":{"id":42}}}`))
+	req.Header.Set(secretHeader, "secret")
+	rec := httptest.NewRecorder()
+	w.mux.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookStopWithBlockedHandler(t *testing.T) {
+	srv := telegramtest.NewServer()
+	defer srv.Close()
+	bot, err := srv.Bot("1:primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Buffer = 0 // обновление некому прочитать – обработчик ждёт в select
+
+	w := NewWebhook(config.WebhookConfig{SecretToken: "secret"})
+	updates, err := w.Listen(bot, "/primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := make(chan int, 1)
+	go func() { codes <- post(w, "/primary") }()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := w.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case code := <-codes:
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("ответ обработчика при остановке = %d, want 503", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("обработчик не вышел после Stop")
+	}
+	if _, ok := <-updates; ok {
+		t.Error("канал обновлений не закрыт")
+	}
+	if code := post(w, "/primary"); code != http.StatusServiceUnavailable {
+		t.Errorf("ответ после Stop = %d, want 503", code)
+	}
+}
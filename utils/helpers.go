@@ -2,6 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"strings"
+
+	"telegram-bot/config"
 	"telegram-bot/models"
 )
 
@@ -12,14 +15,12 @@ func FormatProfile(p *models.Profile) string {
 		"Возраст: %d\n"+
 		"Рост: %.2f\n"+
 		"Вес: %.2f\n"+
-		"Инвентарь: %s\n"+
+		"Инвентарь:%s\n"+
 		"Ранг: %s\n"+
 		"Команда: %s\n"+
-		"Раса: %s\n"+
-		"Пиастры: %d\n"+
-		"Обломки: %d",
+		"Раса: %s%s",
 		p.Name, p.Age, p.Height, p.Weight,
-		p.Inventory, p.Rank, p.Team, p.Race, p.Piastres, p.Oblomki)
+		formatInventoryBlock(p.Items, false), p.Rank, p.Team, p.Race, formatBalances(p))
 }
 
 // FormatProfileAdmin – форматирует анкету для администратора,
@@ -30,12 +31,84 @@ func FormatProfileAdmin(p *models.Profile) string {
 		"Возраст: %d\n"+
 		"Рост: %.2f\n"+
 		"Вес: %.2f\n"+
-		"Инвентарь: %s\n"+
+		"Инвентарь:%s\n"+
 		"Ранг: %s\n"+
 		"Команда: %s\n"+
-		"Раса: %s\n"+
-		"Пиастры: %d\n"+
-		"Обломки: %d",
+		"Раса: %s%s",
 		p.ID, p.Username, p.Name, p.Age, p.Height, p.Weight,
-		p.Inventory, p.Rank, p.Team, p.Race, p.Piastres, p.Oblomki)
+		formatInventoryBlock(p.Items, true), p.Rank, p.Team, p.Race, formatBalances(p))
+}
+
+// FormatInventory – инвентарь персонажа, по предмету на строку.
+// Если withIDs, для администратора добавляется номер записи (для /revokeitem #N).
+func FormatInventory(items []models.InventoryItem, withIDs bool) string {
+	if len(items) == 0 {
+		return "пусто"
+	}
+	lines := make([]string, 0, len(items))
+	for _, it := range items {
+		line := "- "
+		if withIDs {
+			line += fmt.Sprintf("#%d ", it.ID)
+		}
+		if it.ItemID == 0 {
+			line += it.Note
+		} else {
+			line += it.Name
+			if it.Quantity != 1 {
+				line += fmt.Sprintf(" ×%d", it.Quantity)
+			}
+			if it.Note != "" {
+				line += " (" + it.Note + ")"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatInventoryBlock – инвентарь для анкеты: "пусто" в той же строке, список – с новой.
+func formatInventoryBlock(items []models.InventoryItem, withIDs bool) string {
+	if len(items) == 0 {
+		return " пусто"
+	}
+	return "\n" + FormatInventory(items, withIDs)
+}
+
+// formatBalances – строки "Валюта: сумма" для каждого баланса профиля,
+// каждая с новой строки. Отрицательный баланс помечается как долг.
+func formatBalances(p *models.Profile) string {
+	var b strings.Builder
+	for _, bal := range p.Balances {
+		fmt.Fprintf(&b, "\n%s: %d", bal.Label(), bal.Amount)
+		if bal.Amount < 0 {
+			b.WriteString(" (долг)")
+		}
+	}
+	return b.String()
+}
+
+// FormatBalance – баланс профиля по всем валютам.
+func FormatBalance(p *models.Profile) string {
+	return "Баланс:" + formatBalances(p)
+}
+
+// FormatLedger – список операций журнала, по одной на строку.
+// Время показывается в часовом поясе игры. Если withSource, для администратора
+// добавляется источник операции.
+func FormatLedger(entries []*models.LedgerEntry, withSource bool) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s  %+d %s (= %d) – %s",
+			e.CreatedAt.In(config.Current.Location()).Format("02.01.2006 15:04"), e.Delta, e.CurrencyName, e.BalanceAfter, e.Reason)
+		if withSource {
+			fmt.Fprintf(&b, " [#%d %s", e.ID, e.SourceType)
+			if e.SourceID != 0 {
+				fmt.Fprintf(&b, " %d", e.SourceID)
+			}
+			b.WriteString("]")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
 }